@@ -0,0 +1,1005 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	obcacheImport = "github.com/vnykmshr/obcache-go/pkg/obcache"
+
+	// Annotations recognised in doc comments
+	annotationCache       = "//obcache:cache"
+	annotationNoCache     = "//obcache:nocache"
+	annotationTTL         = "//obcache:ttl"
+	annotationInvalidates = "//obcache:invalidates"
+)
+
+// reserved holds identifiers used by generated code that parameters are
+// renamed away from
+var reserved = map[string]bool{
+	"c": true, "cache": true, "options": true, "key": true, "value": true,
+	"err": true, "ok": true, "r": true, "ctx": true, "context": true,
+	"obcache": true, "strconv": true,
+}
+
+// isResultVar reports whether name clashes with the generated r0..rn names
+func isResultVar(name string) bool {
+	if len(name) < 2 || name[0] != 'r' {
+		return false
+	}
+	_, err := strconv.Atoi(name[1:])
+	return err == nil
+}
+
+// param is a parameter of a cached function
+type param struct {
+	Name     string
+	Type     string
+	Variadic bool
+
+	// Declared is the name in the source, before renaming
+	Declared string
+}
+
+// function describes a method or function to generate a cached version of
+type function struct {
+	Name    string
+	Params  []param
+	Results []string
+
+	// HasError is set when the last result is an error
+	HasError bool
+
+	// TakesContext is set when the first parameter is a context.Context
+	TakesContext bool
+
+	// NoCache passes calls straight through (//obcache:nocache)
+	NoCache bool
+
+	// TTL overrides the cache TTL for this function (//obcache:ttl)
+	TTL time.Duration
+
+	// Invalidates lists the raw //obcache:invalidates targets
+	Invalidates []string
+
+	// invalidations are the resolved invalidation rules
+	invalidations []*invalidation
+
+	// Tagged is set when all results of the method may be invalidated at
+	// once, which requires tagging them
+	Tagged bool
+}
+
+// invalidation removes cached results of Target after a successful call
+type invalidation struct {
+	Target *function
+
+	// Args are expressions over the caller's parameters matching the
+	// target's key parameters; nil removes every result of Target
+	Args []string
+}
+
+// keyParams returns the parameters that make up the cache key
+func (f *function) keyParams() []param {
+	if f.TakesContext {
+		return f.Params[1:]
+	}
+	return f.Params
+}
+
+// hasOptions reports whether the method needs options beyond the shared ones
+func (f *function) hasOptions() bool {
+	return f.cacheable() && (f.TTL > 0 || f.Tagged)
+}
+
+// values returns the non-error results
+func (f *function) values() []string {
+	if f.HasError {
+		return f.Results[:len(f.Results)-1]
+	}
+	return f.Results
+}
+
+// cacheable reports whether calls can be cached
+func (f *function) cacheable() bool {
+	return !f.NoCache && len(f.values()) > 0
+}
+
+// callArgs returns the argument list used to forward a call
+func (f *function) callArgs() string {
+	args := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		args[i] = p.Name
+		if p.Variadic {
+			args[i] += "..."
+		}
+	}
+	return strings.Join(args, ", ")
+}
+
+// signature returns the parameter and result lists as Go source
+func (f *function) signature() string {
+	params := make([]string, len(f.Params))
+	for i, p := range f.Params {
+		params[i] = p.Name + " " + p.Type
+	}
+
+	results := strings.Join(f.Results, ", ")
+	if len(f.Results) > 1 {
+		results = "(" + results + ")"
+	}
+	return "(" + strings.Join(params, ", ") + ") " + results
+}
+
+// iface is an interface to generate a caching decorator for
+type iface struct {
+	Name    string
+	Methods []*function
+}
+
+// source is a parsed package directory
+type source struct {
+	fset    *token.FileSet
+	pkgName string
+	files   []*ast.File
+
+	// imports maps package names used in the package to import paths
+	imports map[string]string
+}
+
+// parseDir parses the non-test Go files of a package directory, skipping
+// files previously generated by this tool
+func parseDir(dir string) (*source, error) {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	src := &source{fset: fset, imports: make(map[string]string)}
+	for _, path := range matches {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if isGenerated(file) {
+			continue
+		}
+
+		if src.pkgName == "" {
+			src.pkgName = file.Name.Name
+		}
+		src.files = append(src.files, file)
+
+		for _, imp := range file.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			name := filepath.Base(path)
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			src.imports[name] = path
+		}
+	}
+
+	if len(src.files) == 0 {
+		return nil, fmt.Errorf("no Go files found in %s", dir)
+	}
+	return src, nil
+}
+
+// isGenerated reports whether file was generated by obcache-gen
+func isGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "// Code generated by obcache-gen") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findInterface returns the named interface, resolving embedded interfaces
+// declared in the same package
+func (s *source) findInterface(name string) (*iface, error) {
+	spec := s.findType(name)
+	if spec == nil {
+		return nil, fmt.Errorf("type %s not found", name)
+	}
+	if spec.TypeParams != nil {
+		return nil, fmt.Errorf("generic interface %s is not supported", name)
+	}
+
+	it, ok := spec.Type.(*ast.InterfaceType)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not an interface", name)
+	}
+
+	methods, err := s.methods(name, it)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &iface{Name: name, Methods: methods}
+	if err := result.resolveInvalidations(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// methods returns the methods of an interface, including those of embedded
+// interfaces
+func (s *source) methods(name string, it *ast.InterfaceType) ([]*function, error) {
+	var methods []*function
+	for _, field := range it.Methods.List {
+		ft, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			embedded, ok := field.Type.(*ast.Ident)
+			if !ok {
+				return nil, fmt.Errorf("%s: embedded interface %s must be declared in the same package", name, s.expr(field.Type))
+			}
+			spec := s.findType(embedded.Name)
+			if spec == nil {
+				return nil, fmt.Errorf("%s: embedded type %s not found", name, embedded.Name)
+			}
+			inner, ok := spec.Type.(*ast.InterfaceType)
+			if !ok || spec.TypeParams != nil {
+				return nil, fmt.Errorf("%s: embedded type %s is not a non-generic interface", name, embedded.Name)
+			}
+			innerMethods, err := s.methods(embedded.Name, inner)
+			if err != nil {
+				return nil, err
+			}
+			methods = append(methods, innerMethods...)
+			continue
+		}
+
+		fn, err := s.function(field.Names[0].Name, ft, field.Doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%w", name, err)
+		}
+		methods = append(methods, fn)
+	}
+
+	return methods, nil
+}
+
+// resolveInvalidations links //obcache:invalidates targets to the methods
+// they name and checks their arguments
+func (it *iface) resolveInvalidations() error {
+	byName := make(map[string]*function, len(it.Methods))
+	for _, m := range it.Methods {
+		byName[m.Name] = m
+	}
+
+	for _, m := range it.Methods {
+		if len(m.Invalidates) > 0 && m.cacheable() {
+			return fmt.Errorf("%s.%s: cached methods cannot invalidate others, mark it %s", it.Name, m.Name, annotationNoCache)
+		}
+
+		for _, target := range m.Invalidates {
+			inv, err := m.invalidation(target, byName)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %s %s: %w", it.Name, m.Name, annotationInvalidates, target, err)
+			}
+			if inv.Args == nil {
+				inv.Target.Tagged = true
+			}
+			m.invalidations = append(m.invalidations, inv)
+		}
+	}
+	return nil
+}
+
+// invalidation parses a target of the form Method or Method(expr, ...),
+// where the expressions refer to the parameters of f
+func (f *function) invalidation(target string, methods map[string]*function) (*invalidation, error) {
+	expr, err := parser.ParseExpr(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target: %w", err)
+	}
+
+	var name string
+	var args []ast.Expr
+	switch e := expr.(type) {
+	case *ast.Ident:
+		name = e.Name
+	case *ast.CallExpr:
+		ident, ok := e.Fun.(*ast.Ident)
+		if !ok || e.Ellipsis.IsValid() {
+			return nil, fmt.Errorf("target must be a method name or call")
+		}
+		name = ident.Name
+		args = e.Args
+	default:
+		return nil, fmt.Errorf("target must be a method name or call")
+	}
+
+	method, ok := methods[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown method %s", name)
+	}
+	if !method.cacheable() {
+		return nil, fmt.Errorf("method %s is not cached", name)
+	}
+
+	inv := &invalidation{Target: method}
+	if _, isIdent := expr.(*ast.Ident); isIdent {
+		return inv, nil
+	}
+
+	if len(args) != len(method.keyParams()) {
+		return nil, fmt.Errorf("%s takes %d key arguments, got %d", name, len(method.keyParams()), len(args))
+	}
+
+	renames := make(map[string]string, len(f.Params))
+	for _, p := range f.Params {
+		renames[p.Declared] = p.Name
+	}
+
+	inv.Args = make([]string, len(args))
+	for i, arg := range args {
+		renameIdents(arg, renames)
+
+		var buf bytes.Buffer
+		if err := format.Node(&buf, token.NewFileSet(), arg); err != nil {
+			return nil, err
+		}
+		inv.Args[i] = buf.String()
+	}
+	return inv, nil
+}
+
+// renameIdents rewrites references to renamed parameters in an expression
+func renameIdents(expr ast.Expr, renames map[string]string) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			// Only the operand can refer to a parameter
+			renameIdents(n.X, renames)
+			return false
+		case *ast.Ident:
+			if name, ok := renames[n.Name]; ok {
+				n.Name = name
+			}
+		}
+		return true
+	})
+}
+
+// findType returns the declaration of a named type
+func (s *source) findType(name string) *ast.TypeSpec {
+	for _, file := range s.files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if ts := spec.(*ast.TypeSpec); ts.Name.Name == name {
+					return ts
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// annotatedFunctions returns the top-level functions marked //obcache:cache
+func (s *source) annotatedFunctions() ([]*function, error) {
+	var functions []*function
+	for _, file := range s.files {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || !hasAnnotation(fd.Doc, annotationCache) {
+				continue
+			}
+			if fd.Recv != nil {
+				return nil, fmt.Errorf("%s: methods cannot be annotated, generate a decorator for their interface instead", fd.Name.Name)
+			}
+			if fd.Type.TypeParams != nil {
+				return nil, fmt.Errorf("%s: generic functions are not supported", fd.Name.Name)
+			}
+
+			fn, err := s.function(fd.Name.Name, fd.Type, fd.Doc)
+			if err != nil {
+				return nil, err
+			}
+			if len(fn.Invalidates) > 0 {
+				return nil, fmt.Errorf("%s: %s is only supported on interface methods", fd.Name.Name, annotationInvalidates)
+			}
+			functions = append(functions, fn)
+		}
+	}
+	return functions, nil
+}
+
+// function builds the model of a function from its signature and doc comment
+func (s *source) function(name string, ft *ast.FuncType, doc *ast.CommentGroup) (*function, error) {
+	fn := &function{Name: name, NoCache: hasAnnotation(doc, annotationNoCache)}
+
+	for _, value := range annotationValues(doc, annotationInvalidates) {
+		targets, err := splitTargets(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid %s value: %w", name, annotationInvalidates, err)
+		}
+		fn.Invalidates = append(fn.Invalidates, targets...)
+	}
+
+	if value, ok := annotationValue(doc, annotationTTL); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid %s value: %w", name, annotationTTL, err)
+		}
+		fn.TTL = ttl
+	}
+
+	for i, field := range ft.Params.List {
+		typ := field.Type
+		variadic := false
+		if ellipsis, ok := typ.(*ast.Ellipsis); ok {
+			typ = ellipsis.Elt
+			variadic = true
+		}
+
+		typeStr := s.expr(typ)
+		if variadic {
+			typeStr = "..." + typeStr
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{{Name: "_"}}
+		}
+		for _, n := range names {
+			paramName := n.Name
+			if paramName == "_" || (reserved[paramName] && !(paramName == "ctx" && len(fn.Params) == 0)) || isResultVar(paramName) {
+				paramName = fmt.Sprintf("p%d", len(fn.Params))
+			}
+			fn.Params = append(fn.Params, param{Name: paramName, Type: typeStr, Variadic: variadic, Declared: n.Name})
+		}
+
+		if i == 0 && typeStr == "context.Context" {
+			fn.TakesContext = true
+		}
+	}
+
+	if ft.Results != nil {
+		for _, field := range ft.Results.List {
+			count := len(field.Names)
+			if count == 0 {
+				count = 1
+			}
+			for range count {
+				fn.Results = append(fn.Results, s.expr(field.Type))
+			}
+		}
+	}
+	fn.HasError = len(fn.Results) > 0 && fn.Results[len(fn.Results)-1] == "error"
+
+	return fn, nil
+}
+
+// expr renders a type expression as Go source
+func (s *source) expr(e ast.Expr) string {
+	var buf bytes.Buffer
+	_ = format.Node(&buf, s.fset, e) //nolint:errcheck // Expressions from a parsed file always format
+	return buf.String()
+}
+
+// hasAnnotation reports whether a doc comment contains the annotation
+func hasAnnotation(doc *ast.CommentGroup, annotation string) bool {
+	_, ok := annotationValue(doc, annotation)
+	return ok
+}
+
+// annotationValue returns the text following an annotation in a doc comment
+func annotationValue(doc *ast.CommentGroup, annotation string) (string, bool) {
+	values := annotationValues(doc, annotation)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// annotationValues returns the text following each occurrence of an
+// annotation in a doc comment
+func annotationValues(doc *ast.CommentGroup, annotation string) []string {
+	if doc == nil {
+		return nil
+	}
+
+	var values []string
+	for _, c := range doc.List {
+		if rest, ok := strings.CutPrefix(c.Text, annotation); ok && (rest == "" || rest[0] == ' ') {
+			values = append(values, strings.TrimSpace(rest))
+		}
+	}
+	return values
+}
+
+// splitTargets splits a comma-separated list of invalidation targets,
+// ignoring commas inside argument lists
+func splitTargets(value string) ([]string, error) {
+	var targets []string
+	depth, start := 0, 0
+	for i, r := range value {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				targets = append(targets, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	targets = append(targets, strings.TrimSpace(value[start:]))
+
+	for _, target := range targets {
+		if target == "" {
+			return nil, fmt.Errorf("empty target in %q", value)
+		}
+	}
+	return targets, nil
+}
+
+// generator accumulates generated code and the imports it needs
+type generator struct {
+	src     *source
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+// generate returns formatted source for the given interfaces and functions
+func generate(src *source, ifaces []*iface, functions []*function) ([]byte, error) {
+	g := &generator{src: src, imports: map[string]bool{obcacheImport: true}}
+
+	for _, it := range ifaces {
+		g.decorator(it)
+	}
+	for _, fn := range functions {
+		g.cachedFunction(fn)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by obcache-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", src.pkgName)
+
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if isStdlib(paths[i]) != isStdlib(paths[j]) {
+			return isStdlib(paths[i])
+		}
+		return paths[i] < paths[j]
+	})
+
+	out.WriteString("import (\n")
+	for i, path := range paths {
+		// Separate standard library imports from the rest
+		if i > 0 && isStdlib(paths[i-1]) && !isStdlib(path) {
+			out.WriteString("\n")
+		}
+		if name := g.importName(path); name != filepath.Base(path) {
+			fmt.Fprintf(&out, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+	out.WriteString(")\n\n")
+	out.Write(g.buf.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, out.String())
+	}
+	return formatted, nil
+}
+
+// isStdlib reports whether an import path belongs to the standard library
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// importName returns the name under which the package declares path
+func (g *generator) importName(path string) string {
+	for name, p := range g.src.imports {
+		if p == path {
+			return name
+		}
+	}
+	return filepath.Base(path)
+}
+
+// use records the imports needed by the package qualifiers in a type
+func (g *generator) use(typ string) {
+	for name, path := range g.src.imports {
+		if containsQualifier(typ, name) {
+			g.imports[path] = true
+		}
+	}
+}
+
+// containsQualifier reports whether typ references the package name as a qualifier
+func containsQualifier(typ, name string) bool {
+	qualifier := name + "."
+	for offset := 0; ; {
+		i := strings.Index(typ[offset:], qualifier)
+		if i < 0 {
+			return false
+		}
+		i += offset
+		if i == 0 || !isIdentRune(rune(typ[i-1])) {
+			return true
+		}
+		offset = i + len(qualifier)
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// printf appends formatted code
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// decorator emits a caching decorator struct for an interface
+func (g *generator) decorator(it *iface) {
+	name := "Cached" + it.Name
+
+	g.printf("// %s decorates %s with caching through an obcache.Cache.\n", name, it.Name)
+	g.printf("type %s struct {\n", name)
+	g.printf("next %s\n", it.Name)
+	g.printf("cache *obcache.Cache\n")
+	g.printf("options []obcache.WrapOption\n")
+	for _, m := range it.Methods {
+		if m.hasOptions() {
+			g.printf("%s []obcache.WrapOption\n", optionsField(m))
+		}
+	}
+	g.printf("}\n\n")
+
+	g.printf("// New%s returns a %s that caches the results of next in cache.\n", name, it.Name)
+	g.printf("// Options apply to every cached method, as for obcache.Wrap.\n")
+	g.printf("func New%s(next %s, cache *obcache.Cache, options ...obcache.WrapOption) *%s {\n", name, it.Name, name)
+	g.printf("c := &%s{next: next, cache: cache, options: options}\n", name)
+	for _, m := range it.Methods {
+		if !m.hasOptions() {
+			continue
+		}
+
+		// Method options come last so they override the shared ones
+		g.printf("c.%s = append(options[:len(options):len(options)]", optionsField(m))
+		if m.TTL > 0 {
+			g.printf(", obcache.WithTTL(%d /* %s */)", int64(m.TTL), m.TTL)
+		}
+		if m.Tagged {
+			g.printf(", obcache.WithResultTags(%q)", g.namespace(it, m))
+		}
+		g.printf(")\n")
+	}
+	g.printf("return c\n}\n\n")
+
+	g.printf("var _ %s = (*%s)(nil)\n\n", it.Name, name)
+
+	for _, m := range it.Methods {
+		g.useSignature(m)
+		g.printf("// %s implements %s.\n", m.Name, it.Name)
+		g.printf("func (c *%s) %s%s {\n", name, m.Name, m.signature())
+
+		if !m.cacheable() {
+			if len(m.invalidations) > 0 {
+				g.invalidatingCall(it, m)
+			} else {
+				g.passThrough(m, "c.next."+m.Name)
+			}
+			g.printf("}\n\n")
+			continue
+		}
+
+		options := "c.options"
+		if m.hasOptions() {
+			options = "c." + optionsField(m)
+		}
+		resultType := lowerFirst(it.Name) + m.Name + "Result"
+		g.cachedBody(m, g.namespace(it, m), resultType, "c.next."+m.Name, "c.cache", options)
+		g.printf("}\n\n")
+
+		g.resultType(m, resultType)
+	}
+}
+
+// namespace returns the key prefix of a method, qualified by package and
+// interface so decorators sharing a cache do not collide
+func (g *generator) namespace(it *iface, m *function) string {
+	return g.src.pkgName + "." + it.Name + "." + m.Name
+}
+
+// invalidatingCall emits a call that removes the cached results named by
+// //obcache:invalidates once it succeeds
+func (g *generator) invalidatingCall(it *iface, m *function) {
+	call := fmt.Sprintf("c.next.%s(%s)", m.Name, m.callArgs())
+
+	vars := resultVars(len(m.values()))
+	if m.HasError {
+		vars = append(vars, "err")
+	}
+	if len(vars) > 0 {
+		g.printf("%s := %s\n", strings.Join(vars, ", "), call)
+	} else {
+		g.printf("%s\n", call)
+	}
+
+	if m.HasError {
+		g.printf("if err == nil {\n")
+	}
+	g.printf("// Invalidation is best-effort: the call itself succeeded\n")
+	for _, inv := range m.invalidations {
+		if inv.Args == nil {
+			g.printf("_, _ = c.cache.DeleteByTag(%q)\n", g.namespace(it, inv.Target))
+			continue
+		}
+
+		params := make([]param, len(inv.Args))
+		for i, p := range inv.Target.keyParams() {
+			params[i] = param{Name: inv.Args[i], Type: p.Type, Variadic: p.Variadic}
+		}
+		g.printf("_ = c.cache.Invalidate(%s)\n", g.key(g.namespace(it, inv.Target), params))
+	}
+	if m.HasError {
+		g.printf("}\n")
+	}
+
+	if len(vars) > 0 {
+		g.printf("return %s\n", strings.Join(vars, ", "))
+	}
+}
+
+// cachedFunction emits a constructor for a cached version of an annotated function
+func (g *generator) cachedFunction(fn *function) {
+	g.useSignature(fn)
+
+	name := "Cached" + upperFirst(fn.Name)
+	g.printf("// %s returns %s with its results cached in cache.\n", name, fn.Name)
+	g.printf("// Options are applied as for obcache.Wrap.\n")
+	g.printf("func %s(cache *obcache.Cache, options ...obcache.WrapOption) func%s {\n", name, fn.signature())
+
+	if !fn.cacheable() {
+		g.printf("return %s\n}\n\n", fn.Name)
+		return
+	}
+
+	if fn.TTL > 0 {
+		g.printf("options = append(options[:len(options):len(options)], obcache.WithTTL(%d /* %s */))\n", int64(fn.TTL), fn.TTL)
+	}
+	resultType := lowerFirst(fn.Name) + "Result"
+	g.printf("return func%s {\n", fn.signature())
+	g.cachedBody(fn, g.src.pkgName+"."+fn.Name, resultType, fn.Name, "cache", "options")
+	g.printf("}\n}\n\n")
+
+	g.resultType(fn, resultType)
+}
+
+// useSignature records the imports referenced by a function's types
+func (g *generator) useSignature(fn *function) {
+	for _, p := range fn.Params {
+		g.use(p.Type)
+	}
+	for _, r := range fn.Results {
+		g.use(r)
+	}
+}
+
+// passThrough emits a call forwarded without caching
+func (g *generator) passThrough(fn *function, callee string) {
+	if len(fn.Results) == 0 {
+		g.printf("%s(%s)\n", callee, fn.callArgs())
+		return
+	}
+	g.printf("return %s(%s)\n", callee, fn.callArgs())
+}
+
+// cachedBody emits a function body that serves calls through GetOrLoad
+func (g *generator) cachedBody(fn *function, keyPrefix, resultType, callee, cache, options string) {
+	g.imports["context"] = true
+
+	ctx := "context.Background()"
+	if fn.TakesContext {
+		ctx = fn.Params[0].Name
+	}
+
+	g.printf("key := %s\n", g.key(keyPrefix, fn.keyParams()))
+
+	// The loader receives the load context (which may carry a timeout or be
+	// detached), so forward it in place of the caller's context
+	loaderArgs := fn.callArgs()
+	loaderCtx := "_"
+	if fn.TakesContext {
+		loaderCtx = "ctx"
+		rest := fn.Params[1:]
+		args := []string{"ctx"}
+		for _, p := range rest {
+			arg := p.Name
+			if p.Variadic {
+				arg += "..."
+			}
+			args = append(args, arg)
+		}
+		loaderArgs = strings.Join(args, ", ")
+	}
+
+	values := fn.values()
+
+	g.printf("value, err := %s.GetOrLoad(%s, key, func(%s context.Context) (any, error) {\n", cache, ctx, loaderCtx)
+	switch {
+	case len(values) == 1 && fn.HasError:
+		g.printf("return %s(%s)\n", callee, loaderArgs)
+	case len(values) == 1:
+		g.printf("return %s(%s), nil\n", callee, loaderArgs)
+	default:
+		vars := resultVars(len(values))
+		if fn.HasError {
+			g.printf("%s, err := %s(%s)\n", strings.Join(vars, ", "), callee, loaderArgs)
+			g.printf("return %s{%s}, err\n", resultType, strings.Join(vars, ", "))
+		} else {
+			g.printf("%s := %s(%s)\n", strings.Join(vars, ", "), callee, loaderArgs)
+			g.printf("return %s{%s}, nil\n", resultType, strings.Join(vars, ", "))
+		}
+	}
+	g.printf("}, %s...)\n", options)
+
+	if fn.HasError {
+		g.printf("if err != nil {\n")
+		vars := resultVars(len(values))
+		for i, v := range vars {
+			g.printf("var %s %s\n", v, values[i])
+		}
+		g.printf("return %s\n", strings.Join(append(vars, "err"), ", "))
+		g.printf("}\n")
+	} else {
+		// Without an error result, load failures such as cancellation fall
+		// through to a direct call
+		g.printf("if err == nil {\n")
+	}
+
+	if len(values) == 1 {
+		g.printf("if r0, ok := value.(%s); ok {\n", values[0])
+		g.printf("return r0%s\n", errSuffix(fn))
+	} else {
+		g.printf("if r, ok := value.(%s); ok {\n", resultType)
+		fields := make([]string, len(values))
+		for i, v := range resultVars(len(values)) {
+			fields[i] = "r." + v
+		}
+		g.printf("return %s%s\n", strings.Join(fields, ", "), errSuffix(fn))
+	}
+	g.printf("}\n")
+	if !fn.HasError {
+		g.printf("}\n")
+	}
+
+	if fn.HasError {
+		g.printf("// The cached value has an unexpected type (e.g. it was decoded from\n")
+		g.printf("// a remote store), so call through\n")
+	} else {
+		g.printf("// Loading failed or the cached value has an unexpected type (e.g. it\n")
+		g.printf("// was decoded from a remote store), so call through\n")
+	}
+	g.printf("return %s(%s)\n", callee, fn.callArgs())
+}
+
+// resultType emits the struct used to cache multiple results
+func (g *generator) resultType(fn *function, name string) {
+	values := fn.values()
+	if len(values) < 2 {
+		return
+	}
+
+	g.printf("// %s holds the cached results of %s.\n", name, fn.Name)
+	g.printf("type %s struct {\n", name)
+	for i, v := range resultVars(len(values)) {
+		g.printf("%s %s\n", v, values[i])
+	}
+	g.printf("}\n\n")
+}
+
+// key returns an expression building a cache key from prefix and the key
+// parameters. Basic types are formatted inline; other types use
+// obcache.DefaultKeyFunc.
+func (g *generator) key(prefix string, params []param) string {
+	parts := []string{strconv.Quote(prefix)}
+	for _, p := range params {
+		parts = append(parts, `"|"`, g.argKey(p))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// argKey returns an expression formatting one argument for the cache key
+func (g *generator) argKey(p param) string {
+	if !p.Variadic {
+		switch p.Type {
+		case "string":
+			g.imports["strconv"] = true
+			return "strconv.Quote(" + p.Name + ")"
+		case "int", "int8", "int16", "int32", "int64":
+			g.imports["strconv"] = true
+			return "strconv.FormatInt(int64(" + p.Name + "), 10)"
+		case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+			g.imports["strconv"] = true
+			return "strconv.FormatUint(uint64(" + p.Name + "), 10)"
+		case "bool":
+			g.imports["strconv"] = true
+			return "strconv.FormatBool(" + p.Name + ")"
+		case "float32", "float64":
+			g.imports["strconv"] = true
+			return "strconv.FormatFloat(float64(" + p.Name + "), 'g', -1, 64)"
+		}
+	}
+	g.imports["strconv"] = true
+	return "strconv.Quote(obcache.DefaultKeyFunc([]any{" + p.Name + "}))"
+}
+
+// optionsField returns the name of the per-method options field
+func optionsField(fn *function) string {
+	return lowerFirst(fn.Name) + "Options"
+}
+
+// resultVars returns r0..rn-1
+func resultVars(n int) []string {
+	vars := make([]string, n)
+	for i := range vars {
+		vars[i] = fmt.Sprintf("r%d", i)
+	}
+	return vars
+}
+
+// errSuffix returns ", nil" for functions with an error result
+func errSuffix(fn *function) string {
+	if fn.HasError {
+		return ", nil"
+	}
+	return ""
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// writeFile writes generated code to path
+func writeFile(path string, code []byte) error {
+	return os.WriteFile(path, code, 0o644) //nolint:gosec // Generated source is not sensitive
+}
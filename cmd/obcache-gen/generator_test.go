@@ -0,0 +1,218 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package users
+
+import (
+	"context"
+	"net/url"
+	"os"
+)
+
+type User struct{ Name string }
+
+type Reader interface {
+	Get(ctx context.Context, id int64) (*User, error)
+}
+
+type Store interface {
+	Reader
+
+	//obcache:ttl 90s
+	Find(ctx context.Context, q *url.URL, tags ...string) ([]User, int, error)
+
+	Count(active bool) int
+
+	//obcache:nocache
+	Delete(ctx context.Context, id int64) error
+
+	Touch(key string)
+}
+
+// Score is expensive
+//
+//obcache:cache
+func Score(_ string, weight float64) (float64, error) { return weight, nil }
+
+var _ = os.Getenv
+`
+
+func generateFromSource(t *testing.T, code string, types ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.go"), []byte(code), 0o600); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	if err := run(dir, strings.Join(types, ","), "users_cache.go"); err != nil {
+		t.Fatalf("Generation failed: %v", err)
+	}
+
+	out, err := os.ReadFile(filepath.Join(dir, "users_cache.go"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "users_cache.go", out, 0); err != nil {
+		t.Fatalf("Generated code does not parse: %v\n%s", err, out)
+	}
+	return string(out)
+}
+
+func TestGenerateInterfaceDecorator(t *testing.T) {
+	out := generateFromSource(t, testSource, "Store")
+
+	expected := []string{
+		"type CachedStore struct",
+		"func NewCachedStore(next Store, cache *obcache.Cache, options ...obcache.WrapOption) *CachedStore",
+		"var _ Store = (*CachedStore)(nil)",
+
+		// Embedded interface methods are included
+		`key := "users.Store.Get" + "|" + strconv.FormatInt(int64(id), 10)`,
+		"return c.next.Get(ctx, id)",
+
+		// TTL annotation, variadic and non-basic arguments
+		"obcache.WithTTL(90000000000 /* 1m30s */)",
+		"}, c.findOptions...)",
+		"strconv.Quote(obcache.DefaultKeyFunc([]any{q}))",
+		"strconv.Quote(obcache.DefaultKeyFunc([]any{tags}))",
+		"return c.next.Find(ctx, q, tags...)",
+		"type storeFindResult struct",
+		"return storeFindResult{r0, r1}, err",
+
+		// Methods without a context use a background context
+		"c.cache.GetOrLoad(context.Background(), key, func(_ context.Context) (any, error)",
+		"strconv.FormatBool(active)",
+
+		// Pass-through methods
+		"return c.next.Delete(ctx, id)",
+		"c.next.Touch(p0)",
+
+		// Annotated functions, with unnamed parameters renamed
+		"func CachedScore(cache *obcache.Cache, options ...obcache.WrapOption) func(p0 string, weight float64) (float64, error)",
+		"strconv.FormatFloat(float64(weight), 'g', -1, 64)",
+
+		`"net/url"`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected generated code to contain %q\n%s", want, out)
+		}
+	}
+
+	// Imports not referenced by any signature are dropped
+	if strings.Contains(out, `"os"`) {
+		t.Errorf("Expected unused import to be dropped\n%s", out)
+	}
+}
+
+func TestGenerateRenamesClashingParameters(t *testing.T) {
+	out := generateFromSource(t, `package users
+
+type Lookup interface {
+	Value(key string, err error) string
+}
+`, "Lookup")
+
+	if !strings.Contains(out, "Value(p0 string, p1 error) string") {
+		t.Fatalf("Expected parameters clashing with generated names to be renamed\n%s", out)
+	}
+}
+
+func TestGenerateInvalidations(t *testing.T) {
+	out := generateFromSource(t, `package users
+
+import "context"
+
+type User struct{ ID int64 }
+
+type Store interface {
+	Get(ctx context.Context, id int64) (*User, error)
+	List(ctx context.Context, offset, limit int) ([]*User, error)
+
+	//obcache:invalidates Get(u.ID), List
+	Save(ctx context.Context, u *User) error
+
+	//obcache:invalidates Get(key)
+	Remove(key int64)
+}
+`, "Store")
+
+	expected := []string{
+		// Targets of whole-method invalidation are tagged
+		`c.listOptions = append(options[:len(options):len(options)], obcache.WithResultTags("users.Store.List"))`,
+		"}, c.listOptions...)",
+
+		"err := c.next.Save(ctx, u)",
+		`_ = c.cache.Invalidate("users.Store.Get" + "|" + strconv.FormatInt(int64(u.ID), 10))`,
+		`_, _ = c.cache.DeleteByTag("users.Store.List")`,
+
+		// Renamed parameters are followed in invalidation arguments
+		`_ = c.cache.Invalidate("users.Store.Get" + "|" + strconv.FormatInt(int64(p0), 10))`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected generated code to contain %q\n%s", want, out)
+		}
+	}
+
+	// Results of methods that are only invalidated by argument are not tagged
+	if strings.Contains(out, `WithResultTags("users.Store.Get")`) {
+		t.Errorf("Expected Get results not to be tagged\n%s", out)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		types  string
+	}{
+		{"missing type", "package users\n", "Missing"},
+		{"not an interface", "package users\ntype Store struct{}\n", "Store"},
+		{"generic interface", "package users\ntype Store[T any] interface{ Get() T }\n", "Store"},
+		{"external embedded interface", "package users\nimport \"io\"\ntype Store interface{ io.Reader }\n", "Store"},
+		{"invalid ttl", "package users\ntype Store interface{\n//obcache:ttl soon\nGet() int\n}\n", "Store"},
+		{"nothing to generate", "package users\n", ""},
+		{"unknown invalidation target", "package users\ntype Store interface{\nGet(id int) int\n//obcache:invalidates Missing\nPut(id int)\n}\n", "Store"},
+		{"invalidation argument count", "package users\ntype Store interface{\nGet(id int) int\n//obcache:invalidates Get(id, id)\nPut(id int)\n}\n", "Store"},
+		{"cached method invalidates", "package users\ntype Store interface{\nGet(id int) int\n//obcache:invalidates Get(id)\nLoad(id int) int\n}\n", "Store"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "users.go"), []byte(tt.source), 0o600); err != nil {
+				t.Fatalf("Failed to write source: %v", err)
+			}
+			if err := run(dir, tt.types, ""); err == nil {
+				t.Fatal("Expected an error")
+			}
+		})
+	}
+}
+
+func TestGenerateSkipsGeneratedFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "users.go"), []byte(testSource), 0o600); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	// Regenerating must not pick up the previous output
+	for i := 0; i < 2; i++ {
+		if err := run(dir, "Store", ""); err != nil {
+			t.Fatalf("Generation %d failed: %v", i, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "store_cache.go")); err != nil {
+		t.Fatalf("Expected default output file: %v", err)
+	}
+}
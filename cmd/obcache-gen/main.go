@@ -0,0 +1,108 @@
+// Command obcache-gen generates typed caching decorators that use the
+// obcache Cache API without reflection.
+//
+// It is meant to be run with go generate:
+//
+//	//go:generate go run github.com/vnykmshr/obcache-go/cmd/obcache-gen -type=UserStore
+//
+// For each interface named with -type it emits a CachedX struct that
+// implements X by serving results through Cache.GetOrLoad, with cache keys
+// built from the method arguments at compile time. Top-level functions whose
+// doc comment contains //obcache:cache get a CachedF constructor returning a
+// cached function of the same signature.
+//
+// Interface methods and annotated functions accept these annotations:
+//
+//	//obcache:nocache      call through without caching
+//	//obcache:ttl 5m       cache results for the given duration
+//
+// Uncached interface methods can also drop the cached results of other
+// methods once they return without error:
+//
+//	//obcache:invalidates GetUser(u.ID), Search
+//
+// A call target names the method and gives one expression per key parameter
+// (excluding a leading context), written in terms of the annotated method's
+// parameters; a bare method name removes all of its results. Keys are
+// namespaced by package, interface and method name.
+//
+// Methods without results are always called through. Methods whose first
+// parameter is a context.Context pass it to GetOrLoad, so cancellation and
+// WrapOptions such as WithLoadTimeout apply as they do for Wrap.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma-separated list of interface names to decorate")
+	output := flag.String("output", "", "output file name (default <first type>_cache.go in lower case)")
+	dir := flag.String("dir", ".", "directory of the package to read")
+	flag.Parse()
+
+	if err := run(*dir, *typeNames, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "obcache-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run generates decorators for the named interfaces and annotated functions
+// in dir and writes them to output
+func run(dir, typeNames, output string) error {
+	src, err := parseDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var ifaces []*iface
+	for _, name := range strings.Split(typeNames, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		it, err := src.findInterface(name)
+		if err != nil {
+			return err
+		}
+		ifaces = append(ifaces, it)
+	}
+
+	functions, err := src.annotatedFunctions()
+	if err != nil {
+		return err
+	}
+
+	if len(ifaces) == 0 && len(functions) == 0 {
+		return fmt.Errorf("nothing to generate: use -type or annotate functions with %s", annotationCache)
+	}
+
+	code, err := generate(src, ifaces, functions)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = defaultOutput(ifaces)
+	}
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(dir, output)
+	}
+	return writeFile(output, code)
+}
+
+// defaultOutput names the output file after the first interface, or after the
+// file being processed by go generate
+func defaultOutput(ifaces []*iface) string {
+	if len(ifaces) > 0 {
+		return strings.ToLower(ifaces[0].Name) + "_cache.go"
+	}
+	if file := os.Getenv("GOFILE"); file != "" {
+		return strings.TrimSuffix(file, ".go") + "_cache.go"
+	}
+	return "obcache_gen.go"
+}
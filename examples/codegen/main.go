@@ -0,0 +1,114 @@
+package main
+
+//go:generate go run ../../cmd/obcache-gen -type=UserStore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+// User is a record loaded from the backing store
+type User struct {
+	ID   int
+	Name string
+}
+
+// UserStore is decorated with caching by obcache-gen (see userstore_cache.go)
+type UserStore interface {
+	GetUser(ctx context.Context, id int) (*User, error)
+
+	//obcache:ttl 30s
+	Search(ctx context.Context, query string, limit int) ([]*User, error)
+
+	// Saving a user drops its cached record and every cached search
+	//
+	//obcache:nocache
+	//obcache:invalidates GetUser(u.ID), Search
+	SaveUser(ctx context.Context, u *User) error
+}
+
+// slowStore simulates an expensive backing store
+type slowStore struct {
+	users map[int]*User
+}
+
+func (s *slowStore) GetUser(_ context.Context, id int) (*User, error) {
+	fmt.Printf("Loading user %d (this is expensive)...\n", id)
+	time.Sleep(100 * time.Millisecond)
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %d not found", id)
+	}
+	return u, nil
+}
+
+func (s *slowStore) Search(_ context.Context, query string, limit int) ([]*User, error) {
+	fmt.Printf("Searching for %q (this is expensive)...\n", query)
+	time.Sleep(100 * time.Millisecond)
+
+	var found []*User
+	for _, u := range s.users {
+		if len(found) < limit && u.Name == query {
+			found = append(found, u)
+		}
+	}
+	return found, nil
+}
+
+func (s *slowStore) SaveUser(_ context.Context, u *User) error {
+	s.users[u.ID] = u
+	return nil
+}
+
+// Square returns n squared
+//
+//obcache:cache
+func Square(n int) int {
+	fmt.Printf("Squaring %d...\n", n)
+	return n * n
+}
+
+func main() {
+	cache, err := obcache.New(obcache.NewDefaultConfig())
+	if err != nil {
+		panic(err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	store := NewCachedUserStore(&slowStore{users: map[int]*User{
+		1: {ID: 1, Name: "alice"},
+		2: {ID: 2, Name: "bob"},
+	}}, cache)
+
+	fmt.Println("=== Generated Decorator ===")
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		u, err := store.GetUser(ctx, 1)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Got %s in %v\n", u.Name, time.Since(start))
+	}
+
+	users, _ := store.Search(ctx, "bob", 10)
+	fmt.Printf("Search found %d user(s)\n", len(users))
+
+	fmt.Println("\n=== Invalidation ===")
+	if err := store.SaveUser(ctx, &User{ID: 1, Name: "alice smith"}); err != nil {
+		panic(err)
+	}
+	u, _ := store.GetUser(ctx, 1)
+	fmt.Printf("Got %s after update\n", u.Name)
+
+	fmt.Println("\n=== Generated Function ===")
+	square := CachedSquare(cache)
+	fmt.Println(square(12), square(12))
+
+	stats := cache.Stats()
+	fmt.Printf("\nHits: %d, Misses: %d\n", stats.Hits(), stats.Misses())
+}
@@ -0,0 +1,94 @@
+// Code generated by obcache-gen. DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+// CachedUserStore decorates UserStore with caching through an obcache.Cache.
+type CachedUserStore struct {
+	next          UserStore
+	cache         *obcache.Cache
+	options       []obcache.WrapOption
+	searchOptions []obcache.WrapOption
+}
+
+// NewCachedUserStore returns a UserStore that caches the results of next in cache.
+// Options apply to every cached method, as for obcache.Wrap.
+func NewCachedUserStore(next UserStore, cache *obcache.Cache, options ...obcache.WrapOption) *CachedUserStore {
+	c := &CachedUserStore{next: next, cache: cache, options: options}
+	c.searchOptions = append(options[:len(options):len(options)], obcache.WithTTL(30000000000 /* 30s */), obcache.WithResultTags("main.UserStore.Search"))
+	return c
+}
+
+var _ UserStore = (*CachedUserStore)(nil)
+
+// GetUser implements UserStore.
+func (c *CachedUserStore) GetUser(ctx context.Context, id int) (*User, error) {
+	key := "main.UserStore.GetUser" + "|" + strconv.FormatInt(int64(id), 10)
+	value, err := c.cache.GetOrLoad(ctx, key, func(ctx context.Context) (any, error) {
+		return c.next.GetUser(ctx, id)
+	}, c.options...)
+	if err != nil {
+		var r0 *User
+		return r0, err
+	}
+	if r0, ok := value.(*User); ok {
+		return r0, nil
+	}
+	// The cached value has an unexpected type (e.g. it was decoded from
+	// a remote store), so call through
+	return c.next.GetUser(ctx, id)
+}
+
+// Search implements UserStore.
+func (c *CachedUserStore) Search(ctx context.Context, query string, limit int) ([]*User, error) {
+	key := "main.UserStore.Search" + "|" + strconv.Quote(query) + "|" + strconv.FormatInt(int64(limit), 10)
+	value, err := c.cache.GetOrLoad(ctx, key, func(ctx context.Context) (any, error) {
+		return c.next.Search(ctx, query, limit)
+	}, c.searchOptions...)
+	if err != nil {
+		var r0 []*User
+		return r0, err
+	}
+	if r0, ok := value.([]*User); ok {
+		return r0, nil
+	}
+	// The cached value has an unexpected type (e.g. it was decoded from
+	// a remote store), so call through
+	return c.next.Search(ctx, query, limit)
+}
+
+// SaveUser implements UserStore.
+func (c *CachedUserStore) SaveUser(ctx context.Context, u *User) error {
+	err := c.next.SaveUser(ctx, u)
+	if err == nil {
+		// Invalidation is best-effort: the call itself succeeded
+		_ = c.cache.Invalidate("main.UserStore.GetUser" + "|" + strconv.FormatInt(int64(u.ID), 10))
+		_, _ = c.cache.DeleteByTag("main.UserStore.Search")
+	}
+	return err
+}
+
+// CachedSquare returns Square with its results cached in cache.
+// Options are applied as for obcache.Wrap.
+func CachedSquare(cache *obcache.Cache, options ...obcache.WrapOption) func(n int) int {
+	return func(n int) int {
+		key := "main.Square" + "|" + strconv.FormatInt(int64(n), 10)
+		value, err := cache.GetOrLoad(context.Background(), key, func(_ context.Context) (any, error) {
+			return Square(n), nil
+		}, options...)
+		if err == nil {
+			if r0, ok := value.(int); ok {
+				return r0
+			}
+		}
+		// Loading failed or the cached value has an unexpected type (e.g. it
+		// was decoded from a remote store), so call through
+		return Square(n)
+	}
+}
@@ -1,8 +1,12 @@
 package entry
 
 import (
+	"math"
+	"math/rand/v2"
 	"sync"
 	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/clock"
 )
 
 // Entry represents a cache entry with value and TTL information
@@ -11,6 +15,7 @@ type Entry struct {
 	Value any
 
 	// ExpiresAt indicates when this entry expires (nil means no expiration)
+	// Sliding entries update it on access under mu; use Expiry for concurrent reads
 	ExpiresAt *time.Time
 
 	// CreatedAt is when this entry was created
@@ -21,21 +26,68 @@ type Entry struct {
 	AccessedAt time.Time
 	mu         sync.RWMutex
 
+	// ComputeTime is how long the value took to compute; it scales the
+	// probability of early expiration (XFetch). Zero disables early expiration.
+	ComputeTime time.Duration
+
+	// IdleTTL enables sliding expiration: each access pushes ExpiresAt to
+	// IdleTTL from now. Zero means the entry has a fixed expiry.
+	IdleTTL time.Duration
+
+	// MaxLifetime caps sliding expiration at CreatedAt+MaxLifetime
+	// (zero means no cap)
+	MaxLifetime time.Duration
+
+	// Version identifies the write that produced this entry. Stores assign a
+	// new version on every write, so comparing versions detects concurrent
+	// modification (see store.AtomicStore).
+	Version uint64
+
+	// Priority orders eviction: lower-priority entries are evicted first
+	Priority int
+
+	// NoEvict pins the entry so capacity and cost limits never evict it
+	// (it still expires according to its TTL)
+	NoEvict bool
+
+	// Cost is the entry's weight against a store's cost budget
+	// (zero counts as 1, see Weight)
+	Cost int64
+
+	// Tags label the entry for group operations such as invalidation by tag
+	Tags []string
+
 	// Compression metadata
 	IsCompressed   bool   // Whether the value is compressed
 	CompressorName string // Name of the compressor used (for debugging/metrics)
 	OriginalSize   int    // Original size before compression (0 if not compressed)
 	CompressedSize int    // Size after compression (0 if not compressed)
+
+	// clock tells the entry the time for expiry and access tracking
+	// (nil means the system clock)
+	clock clock.Clock
 }
 
 // New creates a new cache entry with the given value and TTL
 func New(value any, ttl time.Duration) *Entry {
-	now := time.Now()
+	return NewWithClock(value, ttl, nil)
+}
+
+// NewWithoutTTL creates a new cache entry without expiration
+func NewWithoutTTL(value any) *Entry {
+	return NewWithClock(value, 0, nil)
+}
+
+// NewWithClock creates a new cache entry whose expiry is measured by clk
+// (nil means the system clock). A ttl of zero or less means no expiration.
+func NewWithClock(value any, ttl time.Duration, clk clock.Clock) *Entry {
 	entry := &Entry{
-		Value:      value,
-		CreatedAt:  now,
-		AccessedAt: now,
+		Value: value,
+		clock: clk,
 	}
+	now := entry.now()
+	entry.CreatedAt = now
+	entry.AccessedAt = now
 
 	if ttl > 0 {
 		expiry := now.Add(ttl)
@@ -45,33 +97,45 @@ func New(value any, ttl time.Duration) *Entry {
 	return entry
 }
 
-// NewWithoutTTL creates a new cache entry without expiration
-func NewWithoutTTL(value any) *Entry {
-	now := time.Now()
-	return &Entry{
-		Value:      value,
-		ExpiresAt:  nil,
-		CreatedAt:  now,
-		AccessedAt: now,
+// SetClock sets the clock the entry measures its expiry by, for entries
+// that were not created by NewWithClock such as deserialized ones
+func (e *Entry) SetClock(clk clock.Clock) {
+	e.clock = clk
+}
+
+// now returns the current time according to the entry's clock
+func (e *Entry) now() time.Time {
+	if e.clock == nil {
+		return time.Now()
 	}
+	return e.clock.Now()
 }
 
 // IsExpired returns true if the entry has expired
 func (e *Entry) IsExpired() bool {
-	if e.ExpiresAt == nil {
+	expiresAt := e.Expiry()
+	if expiresAt == nil {
 		return false
 	}
-	return time.Now().After(*e.ExpiresAt)
+	return e.now().After(*expiresAt)
+}
+
+// Expiry returns the current expiration time (nil means no expiration)
+func (e *Entry) Expiry() *time.Time {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.ExpiresAt
 }
 
 // TTL returns the time remaining until expiration
 // Returns 0 if the entry has no expiration or has already expired
 func (e *Entry) TTL() time.Duration {
-	if e.ExpiresAt == nil {
+	expiresAt := e.Expiry()
+	if expiresAt == nil {
 		return 0 // No expiration
 	}
 
-	remaining := time.Until(*e.ExpiresAt)
+	remaining := expiresAt.Sub(e.now())
 	if remaining < 0 {
 		return 0 // Already expired
 	}
@@ -81,7 +145,7 @@ func (e *Entry) TTL() time.Duration {
 
 // Age returns how long ago this entry was created
 func (e *Entry) Age() time.Duration {
-	return time.Since(e.CreatedAt)
+	return e.now().Sub(e.CreatedAt)
 }
 
 // TimeSinceLastAccess returns how long ago this entry was last accessed
@@ -89,29 +153,114 @@ func (e *Entry) TimeSinceLastAccess() time.Duration {
 	e.mu.RLock()
 	accessedAt := e.AccessedAt
 	e.mu.RUnlock()
-	return time.Since(accessedAt)
+	return e.now().Sub(accessedAt)
 }
 
 // Touch updates the last accessed time to now
 func (e *Entry) Touch() {
 	e.mu.Lock()
-	e.AccessedAt = time.Now()
+	e.AccessedAt = e.now()
 	e.mu.Unlock()
 }
 
 // UpdateExpiry updates the expiration time with a new TTL from now
 func (e *Entry) UpdateExpiry(ttl time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
 	if ttl > 0 {
-		expiry := time.Now().Add(ttl)
+		expiry := e.now().Add(ttl)
 		e.ExpiresAt = &expiry
 	} else {
 		e.ExpiresAt = nil
 	}
 }
 
+// ExpiresEarly reports whether the entry should be treated as expired ahead of
+// its ExpiresAt using XFetch probabilistic early expiration:
+//
+//	now - ComputeTime * beta * ln(rand()) >= ExpiresAt
+//
+// Larger beta values favour earlier recomputation. Returns false for entries
+// without expiry or without a recorded ComputeTime.
+func (e *Entry) ExpiresEarly(beta float64) bool {
+	expiresAt := e.Expiry()
+	if expiresAt == nil || e.ComputeTime <= 0 || beta <= 0 {
+		return false
+	}
+
+	// 1-rand.Float64() is in (0, 1], so the logarithm is finite and <= 0
+	gap := -float64(e.ComputeTime) * beta * math.Log(1-rand.Float64())
+	return !e.now().Add(time.Duration(gap)).Before(*expiresAt)
+}
+
 // HasExpiry returns true if the entry has an expiration time set
 func (e *Entry) HasExpiry() bool {
-	return e.ExpiresAt != nil
+	return e.Expiry() != nil
+}
+
+// IsSliding returns true if the entry uses sliding expiration
+func (e *Entry) IsSliding() bool {
+	return e.IdleTTL > 0
+}
+
+// Slide pushes the expiration time to IdleTTL from now, capped at
+// CreatedAt+MaxLifetime when MaxLifetime is set. It is a no-op for entries
+// without sliding expiration.
+func (e *Entry) Slide() {
+	if !e.IsSliding() {
+		return
+	}
+
+	expiry := e.now().Add(e.IdleTTL)
+	if e.MaxLifetime > 0 {
+		if limit := e.CreatedAt.Add(e.MaxLifetime); expiry.After(limit) {
+			expiry = limit
+		}
+	}
+
+	e.mu.Lock()
+	e.ExpiresAt = &expiry
+	e.mu.Unlock()
+}
+
+// Inherit copies src's expiry, the clock it is measured by, creation time
+// and per-entry options onto e, so e can replace src without changing how
+// long it lives or how it is evicted. Value, version and compression
+// metadata are left untouched.
+func (e *Entry) Inherit(src *Entry) {
+	e.clock = src.clock
+	e.ExpiresAt = nil
+	if expiry := src.Expiry(); expiry != nil {
+		expiresAt := *expiry
+		e.ExpiresAt = &expiresAt
+	}
+	e.CreatedAt = src.CreatedAt
+	e.ComputeTime = src.ComputeTime
+	e.IdleTTL = src.IdleTTL
+	e.MaxLifetime = src.MaxLifetime
+	e.Priority = src.Priority
+	e.NoEvict = src.NoEvict
+	e.Cost = src.Cost
+	e.Tags = src.Tags
+}
+
+// Weight returns the entry's cost, treating an unset cost as 1
+func (e *Entry) Weight() int64 {
+	if e.Cost > 0 {
+		return e.Cost
+	}
+	return 1
+}
+
+// HasTag returns true if the entry carries the given tag
+func (e *Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // String returns a string representation of the entry (for debugging)
@@ -120,10 +269,10 @@ func (e *Entry) String() string {
 	if e.IsCompressed {
 		status += "compressed, "
 	}
-	if e.ExpiresAt == nil {
+	if expiresAt := e.Expiry(); expiresAt == nil {
 		status += "no-expiry}"
 	} else {
-		status += "expires: " + e.ExpiresAt.Format(time.RFC3339) + "}"
+		status += "expires: " + expiresAt.Format(time.RFC3339) + "}"
 	}
 	return status
 }
@@ -3,6 +3,8 @@ package entry
 import (
 	"testing"
 	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/clock"
 )
 
 func TestNew(t *testing.T) {
@@ -56,6 +58,44 @@ func TestNewWithoutTTL(t *testing.T) {
 	}
 }
 
+// stoppedClock is a clock.Clock that reports a fixed time
+type stoppedClock struct {
+	now time.Time
+}
+
+func (c *stoppedClock) Now() time.Time { return c.now }
+
+func (c *stoppedClock) NewTicker(time.Duration) clock.Ticker { return nil }
+
+func TestNewWithClock(t *testing.T) {
+	clk := &stoppedClock{now: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	entry := NewWithClock("value", time.Minute, clk)
+
+	if !entry.CreatedAt.Equal(clk.now) {
+		t.Fatalf("Expected CreatedAt from the clock, got %v", entry.CreatedAt)
+	}
+	if entry.IsExpired() {
+		t.Fatal("Expected entry not to be expired")
+	}
+
+	clk.now = clk.now.Add(45 * time.Second)
+	if ttl := entry.TTL(); ttl != 15*time.Second {
+		t.Fatalf("Expected 15s remaining, got %v", ttl)
+	}
+	if age := entry.Age(); age != 45*time.Second {
+		t.Fatalf("Expected age 45s, got %v", age)
+	}
+
+	clk.now = clk.now.Add(time.Minute)
+	if !entry.IsExpired() {
+		t.Fatal("Expected entry to expire by its clock")
+	}
+
+	if entry := NewWithClock("value", 0, clk); entry.HasExpiry() {
+		t.Fatal("Expected no expiry for a zero TTL")
+	}
+}
+
 func TestIsExpired(t *testing.T) {
 	// Test non-expired entry
 	entry := New("value", time.Hour)
@@ -168,6 +208,57 @@ func TestUpdateExpiry(t *testing.T) {
 	}
 }
 
+func TestExpiresEarly(t *testing.T) {
+	// Without compute time or expiry, entries never expire early
+	if New("value", time.Second).ExpiresEarly(1) {
+		t.Fatal("Expected entry without compute time not to expire early")
+	}
+	noExpiry := NewWithoutTTL("value")
+	noExpiry.ComputeTime = time.Hour
+	if noExpiry.ExpiresEarly(1) {
+		t.Fatal("Expected entry without expiry not to expire early")
+	}
+
+	// A compute time far longer than the remaining TTL makes early expiry near-certain
+	e := New("value", time.Second)
+	e.ComputeTime = 24 * time.Hour
+	if !e.ExpiresEarly(1) {
+		t.Fatal("Expected slow-to-compute entry near expiry to expire early")
+	}
+	if e.ExpiresEarly(0) {
+		t.Fatal("Expected beta 0 to disable early expiry")
+	}
+}
+
+func TestSlide(t *testing.T) {
+	fixed := New("value", time.Minute)
+	before := *fixed.Expiry()
+	fixed.Slide()
+	if !fixed.Expiry().Equal(before) {
+		t.Error("Expected Slide to be a no-op for non-sliding entries")
+	}
+
+	e := New("value", 50*time.Millisecond)
+	e.IdleTTL = 50 * time.Millisecond
+	time.Sleep(30 * time.Millisecond)
+	e.Slide()
+	if ttl := e.TTL(); ttl < 40*time.Millisecond {
+		t.Errorf("Expected Slide to restore the idle TTL, got %v", ttl)
+	}
+
+	capped := New("value", time.Minute)
+	capped.IdleTTL = time.Minute
+	capped.MaxLifetime = 10 * time.Millisecond
+	capped.Slide()
+	if ttl := capped.TTL(); ttl > 10*time.Millisecond {
+		t.Errorf("Expected MaxLifetime to cap expiry, got TTL %v", ttl)
+	}
+	time.Sleep(15 * time.Millisecond)
+	if !capped.IsExpired() {
+		t.Error("Expected entry to expire at its max lifetime")
+	}
+}
+
 func TestHasExpiry(t *testing.T) {
 	// Test entry with TTL
 	entry := New("value", time.Hour)
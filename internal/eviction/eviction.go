@@ -33,6 +33,9 @@ type Strategy interface {
 
 	// Peek retrieves an entry without updating its position in the eviction order
 	Peek(key string) (*entry.Entry, bool)
+
+	// Victim returns the key that the strategy would evict next, without evicting it
+	Victim() (string, bool)
 }
 
 // EvictionType represents the type of eviction strategy
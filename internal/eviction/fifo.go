@@ -130,3 +130,14 @@ func (f *FIFOStrategy) Peek(key string) (*entry.Entry, bool) {
 	entry, found := f.data[key]
 	return entry, found
 }
+
+// Victim returns the oldest inserted key
+func (f *FIFOStrategy) Victim() (string, bool) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	if len(f.order) == 0 {
+		return "", false
+	}
+	return f.order[0], true
+}
@@ -130,6 +130,15 @@ func (l *LFUStrategy) Peek(key string) (*entry.Entry, bool) {
 	return entry, found
 }
 
+// Victim returns the least frequently used key
+func (l *LFUStrategy) Victim() (string, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	key := l.findLFU()
+	return key, key != ""
+}
+
 // findLFU finds the key with the lowest frequency (internal method, assumes lock is held)
 func (l *LFUStrategy) findLFU() string {
 	if len(l.data) == 0 {
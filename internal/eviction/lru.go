@@ -97,3 +97,12 @@ func (l *LRUStrategy) Peek(key string) (*entry.Entry, bool) {
 
 	return l.cache.Peek(key)
 }
+
+// Victim returns the least recently used key
+func (l *LRUStrategy) Victim() (string, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	key, _, ok := l.cache.GetOldest()
+	return key, ok
+}
@@ -2,9 +2,34 @@ package singleflight
 
 import (
 	"context"
+	"fmt"
+	"runtime/debug"
 	"sync"
 )
 
+// PanicError wraps a value recovered from a panicking function, together
+// with the stack trace of the goroutine that panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error implements error
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: function panicked: %v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap returns the panic value if it is an error
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// newPanicError captures the current stack for a recovered value
+func newPanicError(v any) *PanicError {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
+
 // Group represents a class of work and forms a namespace in which
 // units of work can be executed with duplicate suppression.
 type Group[K comparable, V any] struct {
@@ -49,6 +74,7 @@ func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bo
 		g.mu.Unlock()
 		c.wg.Wait()
 
+		repanic(c.err)
 		return c.val, c.err, true
 	}
 	c := new(call[V])
@@ -57,11 +83,13 @@ func (g *Group[K, V]) Do(key K, fn func() (V, error)) (v V, err error, shared bo
 	g.mu.Unlock()
 
 	g.doCall(c, key, fn)
+	repanic(c.err)
 	return c.val, c.err, c.dups > 0
 }
 
 // DoChan is like Do but returns a channel that will receive the
-// results when they are ready.
+// results when they are ready. If fn panics, the result's Err is a
+// *PanicError rather than the panic being re-raised.
 //
 // The returned channel will not be closed.
 func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
@@ -86,9 +114,18 @@ func (g *Group[K, V]) DoChan(key K, fn func() (V, error)) <-chan Result[V] {
 	return ch
 }
 
-// doCall handles the single call for a key.
+// doCall handles the single call for a key. A panic in fn is recovered and
+// recorded as a *PanicError so that every waiter observes it instead of
+// hanging; Do and DoContext re-raise it in each caller.
 func (g *Group[K, V]) doCall(c *call[V], key K, fn func() (V, error)) {
-	c.val, c.err = fn()
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				c.err = newPanicError(r)
+			}
+		}()
+		c.val, c.err = fn()
+	}()
 	c.wg.Done()
 
 	g.mu.Lock()
@@ -121,10 +158,18 @@ func (g *Group[K, V]) DoContext(ctx context.Context, key K, fn func() (V, error)
 	case <-ctx.Done():
 		return v, ctx.Err(), false
 	case result := <-ch:
+		repanic(result.Err)
 		return result.Val, result.Err, result.Shared
 	}
 }
 
+// repanic re-raises a panic recovered by doCall in the calling goroutine
+func repanic(err error) {
+	if pe, ok := err.(*PanicError); ok {
+		panic(pe)
+	}
+}
+
 // InFlight returns the number of keys currently being processed.
 func (g *Group[K, V]) InFlight() int {
 	g.mu.Lock()
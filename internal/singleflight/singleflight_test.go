@@ -297,3 +297,70 @@ func TestSingleflightConcurrentDifferentTypes(t *testing.T) {
 		t.Fatalf("String key group failed: %v, %d", err2, v2)
 	}
 }
+
+func TestSingleflightPanicReraisedInAllWaiters(t *testing.T) {
+	g := &Group[string, int]{}
+
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		panic("boom")
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	panics := make(chan any, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { panics <- recover() }()
+			_, _, _ = g.Do("key", fn)
+		}()
+	}
+
+	// Let every caller join before the function panics
+	for g.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(panics)
+
+	count := 0
+	for r := range panics {
+		pe, ok := r.(*PanicError)
+		if !ok {
+			t.Fatalf("Expected *PanicError, got %T (%v)", r, r)
+		}
+		if pe.Value != "boom" || len(pe.Stack) == 0 {
+			t.Fatalf("Expected panic value and stack, got %v", pe.Value)
+		}
+		count++
+	}
+	if count != callers {
+		t.Fatalf("Expected all %d callers to panic, got %d", callers, count)
+	}
+	if g.InFlight() != 0 {
+		t.Fatal("Expected key to be released after panic")
+	}
+}
+
+func TestSingleflightPanicDoChanAndDoContext(t *testing.T) {
+	g := &Group[string, int]{}
+	errBoom := errors.New("boom")
+
+	result := <-g.DoChan("key", func() (int, error) { panic(errBoom) })
+	var pe *PanicError
+	if !errors.As(result.Err, &pe) || !errors.Is(result.Err, errBoom) {
+		t.Fatalf("Expected DoChan to deliver a *PanicError wrapping the value, got %v", result.Err)
+	}
+
+	defer func() {
+		if _, ok := recover().(*PanicError); !ok {
+			t.Fatal("Expected DoContext to re-raise the panic")
+		}
+	}()
+	_, _, _ = g.DoContext(context.Background(), "key", func() (int, error) { panic("boom") })
+}
@@ -0,0 +1,466 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/clock"
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// Default breaker settings, used for unset Config fields
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenTimeout      = 5 * time.Second
+	DefaultHalfOpenProbes   = 1
+)
+
+// Config holds circuit breaker configuration
+type Config struct {
+	// Inner is the store guarded by the breaker
+	Inner store.Store
+
+	// Fallback, if set, serves reads and writes while the breaker is open.
+	// It is cleared when the breaker closes again, since the inner store may
+	// have changed in the meantime.
+	Fallback store.Store
+
+	// FailureThreshold is the number of consecutive failed operations that
+	// opens the breaker
+	FailureThreshold int
+
+	// LatencyThreshold makes operations slower than it count as failures
+	// (0 disables the latency check)
+	LatencyThreshold time.Duration
+
+	// OpenTimeout is how long the breaker stays open before letting probe
+	// operations through
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of probe operations that must succeed
+	// for the breaker to close; one failed probe opens it again
+	HalfOpenProbes int
+}
+
+// Store wraps a store with a circuit breaker. Operations that fail with
+// store.ErrBackendUnavailable or exceed the latency threshold count as
+// failures; after enough consecutive failures the breaker opens and stops
+// calling the inner store. While open, reads miss (or are served from the
+// fallback store), writes go to the fallback store or are dropped, and
+// operations that cannot be skipped safely, such as Delete, fail with
+// store.ErrCircuitOpen. After OpenTimeout the breaker lets a few probe
+// operations through and closes once they succeed.
+type Store struct {
+	inner    store.Store
+	fallback store.Store
+
+	failureThreshold int
+	latencyThreshold time.Duration
+	openTimeout      time.Duration
+	halfOpenProbes   int
+
+	mu         sync.Mutex
+	clock      clock.Clock
+	state      store.CircuitState
+	generation uint64
+	failures   int
+	successes  int
+	probes     int
+	openedAt   time.Time
+	onChange   store.CircuitCallback
+	onReject   func()
+}
+
+// call is an operation the breaker let through to the inner store
+type call struct {
+	generation uint64
+	start      time.Time
+	probe      bool
+}
+
+// New creates a circuit breaker around config.Inner. Unset thresholds take
+// their default values.
+func New(config *Config) (*Store, error) {
+	if config.Inner == nil {
+		return nil, fmt.Errorf("inner store is required")
+	}
+
+	s := &Store{
+		inner:            config.Inner,
+		fallback:         config.Fallback,
+		failureThreshold: config.FailureThreshold,
+		latencyThreshold: max(config.LatencyThreshold, 0),
+		openTimeout:      config.OpenTimeout,
+		halfOpenProbes:   config.HalfOpenProbes,
+		clock:            clock.Real(),
+	}
+	if s.failureThreshold <= 0 {
+		s.failureThreshold = DefaultFailureThreshold
+	}
+	if s.openTimeout <= 0 {
+		s.openTimeout = DefaultOpenTimeout
+	}
+	if s.halfOpenProbes <= 0 {
+		s.halfOpenProbes = DefaultHalfOpenProbes
+	}
+
+	return s, nil
+}
+
+// allow reports whether an operation may call the inner store, moving an
+// open breaker to half-open once OpenTimeout has passed
+func (s *Store) allow() (call, bool) {
+	s.mu.Lock()
+	now := s.clock.Now()
+
+	var notify func()
+	if s.state == store.CircuitOpen && now.Sub(s.openedAt) >= s.openTimeout {
+		notify = s.transition(store.CircuitHalfOpen)
+	}
+
+	c := call{generation: s.generation, start: now}
+	allowed := true
+	switch s.state {
+	case store.CircuitOpen:
+		allowed = false
+	case store.CircuitHalfOpen:
+		if s.probes < s.halfOpenProbes {
+			s.probes++
+			c.probe = true
+		} else {
+			allowed = false
+		}
+	}
+	onReject := s.onReject
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+	if !allowed && onReject != nil {
+		onReject()
+	}
+	return c, allowed
+}
+
+// done records the outcome of an operation let through by allow. Outcomes
+// of operations started before the last state change are ignored.
+func (s *Store) done(c call, err error) {
+	failed := errors.Is(err, store.ErrBackendUnavailable)
+
+	s.mu.Lock()
+	if s.latencyThreshold > 0 && s.clock.Now().Sub(c.start) > s.latencyThreshold {
+		failed = true
+	}
+	if c.generation != s.generation {
+		s.mu.Unlock()
+		return
+	}
+
+	var notify func()
+	switch s.state {
+	case store.CircuitClosed:
+		if !failed {
+			s.failures = 0
+			break
+		}
+		s.failures++
+		if s.failures >= s.failureThreshold {
+			notify = s.transition(store.CircuitOpen)
+		}
+	case store.CircuitHalfOpen:
+		if c.probe {
+			s.probes--
+		}
+		if failed {
+			notify = s.transition(store.CircuitOpen)
+			break
+		}
+		s.successes++
+		if s.successes >= s.halfOpenProbes {
+			notify = s.transition(store.CircuitClosed)
+		}
+	}
+	s.mu.Unlock()
+
+	if notify != nil {
+		notify()
+	}
+}
+
+// transition changes the state with mu held. It returns a function that
+// reports the change, to be called once mu is released.
+func (s *Store) transition(to store.CircuitState) func() {
+	from := s.state
+	s.state = to
+	s.generation++
+	s.failures = 0
+	s.successes = 0
+	s.probes = 0
+	if to == store.CircuitOpen {
+		s.openedAt = s.clock.Now()
+	}
+
+	onChange := s.onChange
+	return func() {
+		if to == store.CircuitClosed && s.fallback != nil {
+			_ = s.fallback.Clear() //nolint:errcheck // Best-effort reset of the fallback tier
+		}
+		if onChange != nil {
+			onChange(from, to)
+		}
+	}
+}
+
+// rejected returns the error for an operation the open breaker refused
+func rejected() error {
+	return fmt.Errorf("%w: %w", store.ErrBackendUnavailable, store.ErrCircuitOpen)
+}
+
+// closed reports whether the breaker currently passes operations through
+func (s *Store) closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state == store.CircuitClosed
+}
+
+// Get retrieves an entry by key
+func (s *Store) Get(key string) (*entry.Entry, bool) {
+	e, err := s.GetE(key)
+	return e, err == nil
+}
+
+// GetE retrieves an entry by key. While the breaker is open it reads the
+// fallback store, or returns store.ErrNotFound without one.
+func (s *Store) GetE(key string) (*entry.Entry, error) {
+	c, ok := s.allow()
+	if !ok {
+		if s.fallback == nil {
+			return nil, store.ErrNotFound
+		}
+		return store.GetE(s.fallback, key)
+	}
+
+	e, err := store.GetE(s.inner, key)
+	s.done(c, err)
+	return e, err
+}
+
+// Set stores an entry with the given key. While the breaker is open the
+// entry is written to the fallback store, or dropped without one.
+func (s *Store) Set(key string, e *entry.Entry, opts ...store.SetOption) error {
+	c, ok := s.allow()
+	if !ok {
+		if s.fallback == nil {
+			return nil
+		}
+		return s.fallback.Set(key, e, opts...)
+	}
+
+	err := s.inner.Set(key, e, opts...)
+	s.done(c, err)
+	return err
+}
+
+// Delete removes an entry by key. While the breaker is open the entry is
+// only removed from the fallback store and store.ErrCircuitOpen is returned,
+// since the inner store still holds it.
+func (s *Store) Delete(key string) error {
+	c, ok := s.allow()
+	if !ok {
+		if s.fallback != nil {
+			_ = s.fallback.Delete(key) //nolint:errcheck // The inner store still holds the entry
+		}
+		return rejected()
+	}
+
+	err := s.inner.Delete(key)
+	s.done(c, err)
+	return err
+}
+
+// Keys returns the keys of the inner store, or of the fallback store while
+// the breaker is not closed
+func (s *Store) Keys() []string {
+	if s.closed() {
+		return s.inner.Keys()
+	}
+	if s.fallback == nil {
+		return []string{}
+	}
+	return s.fallback.Keys()
+}
+
+// Len returns the number of entries in the inner store, or in the fallback
+// store while the breaker is not closed
+func (s *Store) Len() int {
+	if s.closed() {
+		return s.inner.Len()
+	}
+	if s.fallback == nil {
+		return 0
+	}
+	return s.fallback.Len()
+}
+
+// Clear removes all entries. While the breaker is open only the fallback
+// store is cleared and store.ErrCircuitOpen is returned.
+func (s *Store) Clear() error {
+	c, ok := s.allow()
+	if !ok {
+		if s.fallback != nil {
+			_ = s.fallback.Clear() //nolint:errcheck // The inner store still holds the entries
+		}
+		return rejected()
+	}
+
+	err := s.inner.Clear()
+	s.done(c, err)
+	return err
+}
+
+// Close closes the inner and fallback stores
+func (s *Store) Close() error {
+	err := s.inner.Close()
+	if s.fallback != nil {
+		err = errors.Join(err, s.fallback.Close())
+	}
+	return err
+}
+
+// Update atomically updates an entry in the inner store
+func (s *Store) Update(key string, fn store.UpdateFunc) error {
+	atomicStore, ok := s.inner.(store.AtomicStore)
+	if !ok {
+		return fmt.Errorf("store %T does not support atomic updates", s.inner)
+	}
+
+	c, ok := s.allow()
+	if !ok {
+		return rejected()
+	}
+	err := atomicStore.Update(key, fn)
+	s.done(c, err)
+	return err
+}
+
+// IncrBy increments a counter in the inner store
+func (s *Store) IncrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	counterStore, ok := s.inner.(store.CounterStore)
+	if !ok {
+		return 0, fmt.Errorf("store %T does not support counters", s.inner)
+	}
+
+	c, ok := s.allow()
+	if !ok {
+		return 0, rejected()
+	}
+	n, err := counterStore.IncrBy(key, delta, ttl)
+	s.done(c, err)
+	return n, err
+}
+
+// AcquireLease takes a lease in the inner store
+func (s *Store) AcquireLease(key string, ttl time.Duration) (int64, bool, error) {
+	leaseStore, ok := s.inner.(store.LeaseStore)
+	if !ok {
+		return 0, false, fmt.Errorf("store %T does not support leases", s.inner)
+	}
+
+	c, ok := s.allow()
+	if !ok {
+		return 0, false, rejected()
+	}
+	token, acquired, err := leaseStore.AcquireLease(key, ttl)
+	s.done(c, err)
+	return token, acquired, err
+}
+
+// ReleaseLease gives up a lease in the inner store
+func (s *Store) ReleaseLease(key string, token int64) error {
+	leaseStore, ok := s.inner.(store.LeaseStore)
+	if !ok {
+		return fmt.Errorf("store %T does not support leases", s.inner)
+	}
+
+	c, ok := s.allow()
+	if !ok {
+		return rejected()
+	}
+	err := leaseStore.ReleaseLease(key, token)
+	s.done(c, err)
+	return err
+}
+
+// Cleanup removes expired entries from the inner store
+func (s *Store) Cleanup() int {
+	if ttlStore, ok := s.inner.(store.TTLStore); ok {
+		return ttlStore.Cleanup()
+	}
+	return 0
+}
+
+// SetCleanupCallback forwards to the inner store
+func (s *Store) SetCleanupCallback(callback store.EvictCallback) {
+	if ttlStore, ok := s.inner.(store.TTLStore); ok {
+		ttlStore.SetCleanupCallback(callback)
+	}
+}
+
+// SetRemovalCallback forwards to the inner store
+func (s *Store) SetRemovalCallback(callback store.RemovalCallback) {
+	if removalStore, ok := s.inner.(store.RemovalStore); ok {
+		removalStore.SetRemovalCallback(callback)
+	}
+}
+
+// SetClock sets the clock used for the open timeout and latency checks (the
+// system clock if c is nil) and forwards it to the inner and fallback stores
+func (s *Store) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	s.clock = clock.Or(c)
+	s.mu.Unlock()
+
+	for _, inner := range []store.Store{s.inner, s.fallback} {
+		if clockStore, ok := inner.(store.ClockStore); ok {
+			clockStore.SetClock(c)
+		}
+	}
+}
+
+// CircuitState returns the current state of the breaker
+func (s *Store) CircuitState() store.CircuitState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// SetCircuitCallback sets the callback for state changes
+func (s *Store) SetCircuitCallback(callback store.CircuitCallback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = callback
+}
+
+// SetRejectCallback sets the callback for rejected operations
+func (s *Store) SetRejectCallback(callback func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReject = callback
+}
+
+// Ensure Store implements the required interfaces
+var (
+	_ store.Store        = (*Store)(nil)
+	_ store.TTLStore     = (*Store)(nil)
+	_ store.RemovalStore = (*Store)(nil)
+	_ store.ClockStore   = (*Store)(nil)
+	_ store.ErrorStore   = (*Store)(nil)
+	_ store.AtomicStore  = (*Store)(nil)
+	_ store.CounterStore = (*Store)(nil)
+	_ store.LeaseStore   = (*Store)(nil)
+	_ store.CircuitStore = (*Store)(nil)
+)
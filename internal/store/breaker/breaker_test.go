@@ -0,0 +1,220 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/clock"
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+	"github.com/vnykmshr/obcache-go/internal/store/memory"
+)
+
+// manualClock is a clock.Clock that only moves when told to
+type manualClock struct {
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time { return c.now }
+
+func (c *manualClock) NewTicker(d time.Duration) clock.Ticker { return clock.Real().NewTicker(d) }
+
+func (c *manualClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// flakyStore is a memory store whose reads and writes fail while down is set
+type flakyStore struct {
+	*memory.Store
+	down  bool
+	calls int
+
+	// during runs inside every GetE call
+	during func()
+}
+
+func newFlakyStore(t *testing.T) *flakyStore {
+	t.Helper()
+	inner, err := memory.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	return &flakyStore{Store: inner}
+}
+
+func (s *flakyStore) GetE(key string) (*entry.Entry, error) {
+	s.calls++
+	if s.during != nil {
+		s.during()
+	}
+	if s.down {
+		return nil, fmt.Errorf("%w: connection refused", store.ErrBackendUnavailable)
+	}
+	return store.GetE(s.Store, key)
+}
+
+func (s *flakyStore) Set(key string, e *entry.Entry, opts ...store.SetOption) error {
+	s.calls++
+	if s.down {
+		return fmt.Errorf("%w: connection refused", store.ErrBackendUnavailable)
+	}
+	return s.Store.Set(key, e, opts...)
+}
+
+// newBreaker wraps inner in a breaker driven by a manual clock, recording
+// its state changes into transitions
+func newBreaker(t *testing.T, config *Config, transitions *[]string) (*Store, *manualClock) {
+	t.Helper()
+	s, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create breaker: %v", err)
+	}
+	clk := &manualClock{now: time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	s.SetClock(clk)
+	s.SetCircuitCallback(func(from, to store.CircuitState) {
+		*transitions = append(*transitions, from.String()+"->"+to.String())
+	})
+	return s, clk
+}
+
+func TestBreakerOpensAfterFailureThreshold(t *testing.T) {
+	inner := newFlakyStore(t)
+	var transitions []string
+	s, _ := newBreaker(t, &Config{Inner: inner, FailureThreshold: 3}, &transitions)
+
+	rejects := 0
+	s.SetRejectCallback(func() { rejects++ })
+
+	inner.down = true
+	for i := 0; i < 3; i++ {
+		if _, err := s.GetE("key"); !errors.Is(err, store.ErrBackendUnavailable) {
+			t.Fatalf("Expected backend error from call %d, got %v", i, err)
+		}
+	}
+	if s.CircuitState() != store.CircuitOpen {
+		t.Fatalf("Expected breaker to be open, got %s", s.CircuitState())
+	}
+
+	calls := inner.calls
+	if _, err := s.GetE("key"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Expected open breaker to report a miss, got %v", err)
+	}
+	if err := s.Delete("key"); !errors.Is(err, store.ErrCircuitOpen) {
+		t.Fatalf("Expected open breaker to reject Delete, got %v", err)
+	}
+	if inner.calls != calls {
+		t.Fatal("Expected open breaker not to call the inner store")
+	}
+	if rejects != 2 {
+		t.Fatalf("Expected 2 rejections, got %d", rejects)
+	}
+	if len(transitions) != 1 || transitions[0] != "closed->open" {
+		t.Fatalf("Expected a single closed->open transition, got %v", transitions)
+	}
+}
+
+func TestBreakerIgnoresMisses(t *testing.T) {
+	inner := newFlakyStore(t)
+	var transitions []string
+	s, _ := newBreaker(t, &Config{Inner: inner, FailureThreshold: 2}, &transitions)
+
+	for i := 0; i < 5; i++ {
+		if _, err := s.GetE("missing"); !errors.Is(err, store.ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if s.CircuitState() != store.CircuitClosed {
+		t.Fatalf("Expected misses not to open the breaker, got %s", s.CircuitState())
+	}
+}
+
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	inner := newFlakyStore(t)
+	var transitions []string
+	s, clk := newBreaker(t, &Config{Inner: inner, FailureThreshold: 1, OpenTimeout: time.Second}, &transitions)
+
+	if err := s.Set("key", entry.New("value", 0)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	inner.down = true
+	_, _ = s.GetE("key")
+
+	// A failed probe opens the breaker again
+	clk.advance(time.Second)
+	if _, err := s.GetE("key"); !errors.Is(err, store.ErrBackendUnavailable) {
+		t.Fatalf("Expected the probe to reach the inner store, got %v", err)
+	}
+	if s.CircuitState() != store.CircuitOpen {
+		t.Fatalf("Expected failed probe to reopen the breaker, got %s", s.CircuitState())
+	}
+
+	// A successful probe closes it
+	inner.down = false
+	clk.advance(time.Second)
+	e, err := s.GetE("key")
+	if err != nil || e.Value != "value" {
+		t.Fatalf("Expected the probe to read the entry, got %v, %v", e, err)
+	}
+	if s.CircuitState() != store.CircuitClosed {
+		t.Fatalf("Expected successful probe to close the breaker, got %s", s.CircuitState())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if fmt.Sprint(transitions) != fmt.Sprint(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, transitions)
+	}
+}
+
+func TestBreakerLatencyThreshold(t *testing.T) {
+	inner := newFlakyStore(t)
+	var transitions []string
+	s, clk := newBreaker(t, &Config{Inner: inner, FailureThreshold: 2, LatencyThreshold: 100 * time.Millisecond}, &transitions)
+
+	inner.during = func() { clk.advance(50 * time.Millisecond) }
+	_, _ = s.GetE("key")
+	_, _ = s.GetE("key")
+	if s.CircuitState() != store.CircuitClosed {
+		t.Fatalf("Expected fast calls to keep the breaker closed, got %s", s.CircuitState())
+	}
+
+	inner.during = func() { clk.advance(200 * time.Millisecond) }
+	_, _ = s.GetE("key")
+	_, _ = s.GetE("key")
+	if s.CircuitState() != store.CircuitOpen {
+		t.Fatalf("Expected slow calls to open the breaker, got %s", s.CircuitState())
+	}
+}
+
+func TestBreakerFallback(t *testing.T) {
+	inner := newFlakyStore(t)
+	fallback, err := memory.New(10)
+	if err != nil {
+		t.Fatalf("Failed to create fallback store: %v", err)
+	}
+	var transitions []string
+	s, clk := newBreaker(t, &Config{Inner: inner, Fallback: fallback, FailureThreshold: 1, OpenTimeout: time.Second}, &transitions)
+
+	inner.down = true
+	_, _ = s.GetE("key")
+
+	if err := s.Set("key", entry.New("local", 0)); err != nil {
+		t.Fatalf("Expected open breaker to write to the fallback, got %v", err)
+	}
+	e, err := s.GetE("key")
+	if err != nil || e.Value != "local" {
+		t.Fatalf("Expected open breaker to read from the fallback, got %v, %v", e, err)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("Expected Len to count fallback entries, got %d", s.Len())
+	}
+
+	inner.down = false
+	clk.advance(time.Second)
+	if _, err := s.GetE("key"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Expected the probe to miss in the inner store, got %v", err)
+	}
+	if fallback.Len() != 0 {
+		t.Fatal("Expected the fallback to be cleared when the breaker closes")
+	}
+}
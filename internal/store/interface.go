@@ -1,9 +1,107 @@
 package store
 
 import (
+	"errors"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/clock"
 	"github.com/vnykmshr/obcache-go/internal/entry"
 )
 
+// ErrNotStored is returned by Set when its write condition is not met
+var ErrNotStored = errors.New("store: write condition not met")
+
+// ErrNotInteger is returned by IncrBy when the stored value is not an integer
+var ErrNotInteger = errors.New("store: value is not an integer")
+
+// ErrOverflow is returned by IncrBy when the result would overflow int64
+var ErrOverflow = errors.New("store: increment would overflow")
+
+// ErrNotFound is returned by GetE when the key is missing or expired
+var ErrNotFound = errors.New("store: key not found")
+
+// ErrBackendUnavailable wraps errors from a remote backend that could not be
+// reached or did not answer
+var ErrBackendUnavailable = errors.New("store: backend unavailable")
+
+// ErrCorruptEntry wraps errors decoding a stored entry
+var ErrCorruptEntry = errors.New("store: corrupt entry")
+
+// ErrCircuitOpen is returned, wrapped in ErrBackendUnavailable, by operations
+// a circuit breaker rejected without contacting the backend
+var ErrCircuitOpen = errors.New("store: circuit breaker is open")
+
+// SetCondition restricts when Set writes an entry
+type SetCondition int
+
+const (
+	// SetAlways writes the entry unconditionally (default)
+	SetAlways SetCondition = iota
+
+	// SetIfAbsent writes the entry only if the key is missing or expired
+	SetIfAbsent
+
+	// SetIfPresent writes the entry only if the key exists and is not expired
+	SetIfPresent
+)
+
+// SetOptions holds per-call options for Store.Set
+type SetOptions struct {
+	// Condition restricts when the entry is written
+	Condition SetCondition
+
+	// LeaseToken, when non-zero, fences the write: it only succeeds while
+	// the lease for the key is still held with this token (see LeaseStore)
+	LeaseToken int64
+}
+
+// SetOption is a function that configures SetOptions
+type SetOption func(*SetOptions)
+
+// IfAbsent makes Set write only if the key is missing or expired
+func IfAbsent() SetOption {
+	return func(opts *SetOptions) {
+		opts.Condition = SetIfAbsent
+	}
+}
+
+// IfPresent makes Set write only if the key exists and is not expired
+func IfPresent() SetOption {
+	return func(opts *SetOptions) {
+		opts.Condition = SetIfPresent
+	}
+}
+
+// WithLease fences Set on the lease for the key being held with token.
+// Stores that do not implement LeaseStore ignore it.
+func WithLease(token int64) SetOption {
+	return func(opts *SetOptions) {
+		opts.LeaseToken = token
+	}
+}
+
+// ApplySetOptions builds SetOptions from a list of options
+func ApplySetOptions(options []SetOption) SetOptions {
+	var opts SetOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return opts
+}
+
+// Allows reports whether a write with this condition may proceed given
+// whether a live entry currently exists for the key
+func (o SetOptions) Allows(exists bool) bool {
+	switch o.Condition {
+	case SetIfAbsent:
+		return !exists
+	case SetIfPresent:
+		return exists
+	default:
+		return true
+	}
+}
+
 // Store defines the interface for cache storage backends
 // This abstraction allows for different implementations (memory, Redis, etc.)
 type Store interface {
@@ -12,8 +110,10 @@ type Store interface {
 	Get(key string) (*entry.Entry, bool)
 
 	// Set stores an entry with the given key
-	// Returns an error if the operation fails
-	Set(key string, entry *entry.Entry) error
+	// Per-entry metadata (priority, cost, tags, pinning) travels on the entry;
+	// options carry write conditions. Returns ErrNotStored if a condition is
+	// not met, or another error if the operation fails
+	Set(key string, entry *entry.Entry, opts ...SetOption) error
 
 	// Delete removes an entry by key
 	// Returns an error if the operation fails
@@ -34,10 +134,76 @@ type Store interface {
 	Close() error
 }
 
+// ErrorStore extends Store with a Get that reports why a lookup failed
+type ErrorStore interface {
+	Store
+
+	// GetE retrieves an entry by key. It returns ErrNotFound if the key is
+	// missing or expired, and an error wrapping ErrBackendUnavailable or
+	// ErrCorruptEntry if the entry could not be read.
+	GetE(key string) (*entry.Entry, error)
+}
+
+// GetE retrieves an entry from s, reporting lookup failures for stores that
+// implement ErrorStore. Other stores only fail with ErrNotFound.
+func GetE(s Store, key string) (*entry.Entry, error) {
+	if es, ok := s.(ErrorStore); ok {
+		return es.GetE(key)
+	}
+	if e, found := s.Get(key); found {
+		return e, nil
+	}
+	return nil, ErrNotFound
+}
+
 // EvictCallback is called when an entry is evicted from the store
 // This allows the cache to track evictions and invoke hooks
 type EvictCallback func(key string, value any)
 
+// RemovalReason explains why an entry left a store
+type RemovalReason int
+
+const (
+	// RemovedCapacity means the entry was evicted to stay within capacity
+	RemovedCapacity RemovalReason = iota
+
+	// RemovedCost means the entry was evicted to stay within the cost budget
+	RemovedCost
+
+	// RemovedExpiredOnAccess means the entry was found expired when read
+	RemovedExpiredOnAccess
+
+	// RemovedExpiredSweep means the entry was removed by a cleanup sweep
+	RemovedExpiredSweep
+
+	// RemovedReplaced means the entry was overwritten by a newer one
+	RemovedReplaced
+
+	// RemovedDeleted means the entry was removed with Delete
+	RemovedDeleted
+
+	// RemovedCleared means the entry was removed with Clear
+	RemovedCleared
+
+	// RemovedDeletedExternally means the entry was deleted by another client
+	// of a shared backend
+	RemovedDeletedExternally
+)
+
+// RemovalCallback is called when an entry leaves a store, with the reason
+type RemovalCallback func(key string, value any, reason RemovalReason)
+
+// RemovalStore extends Store with notifications for every removed entry.
+// Stores implementing it report each removal to the removal callback in
+// addition to the eviction and cleanup callbacks.
+type RemovalStore interface {
+	Store
+
+	// SetRemovalCallback sets a callback function that will be called
+	// whenever an entry leaves the store
+	SetRemovalCallback(callback RemovalCallback)
+}
+
 // LRUStore extends Store with LRU-specific functionality
 type LRUStore interface {
 	Store
@@ -62,3 +228,123 @@ type TTLStore interface {
 	// when entries are removed during cleanup
 	SetCleanupCallback(callback EvictCallback)
 }
+
+// BatchCleanupStore extends TTLStore with cleanup in bounded batches
+type BatchCleanupStore interface {
+	TTLStore
+
+	// SetCleanupBatchSize limits how many expired entries Cleanup removes
+	// before letting other operations through
+	SetCleanupBatchSize(size int)
+}
+
+// ClockStore extends Store with an injectable clock, so expiry can be
+// tested without waiting for real time to pass
+type ClockStore interface {
+	Store
+
+	// SetClock sets the clock used to expire entries created by the store
+	// and to schedule background work. It must be called before the store
+	// is used.
+	SetClock(c clock.Clock)
+}
+
+// CircuitState is the state of a circuit breaker guarding a store
+type CircuitState int
+
+const (
+	// CircuitClosed passes operations through to the backend
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen rejects operations without contacting the backend
+	CircuitOpen
+
+	// CircuitHalfOpen lets a few probe operations through to find out
+	// whether the backend has recovered
+	CircuitHalfOpen
+)
+
+// String returns the string representation of CircuitState
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitCallback is called when a circuit breaker changes state
+type CircuitCallback func(from, to CircuitState)
+
+// CircuitStore extends Store with a circuit breaker that stops calling an
+// unhealthy backend
+type CircuitStore interface {
+	Store
+
+	// CircuitState returns the current state of the breaker
+	CircuitState() CircuitState
+
+	// SetCircuitCallback sets a callback function that will be called
+	// whenever the breaker changes state
+	SetCircuitCallback(callback CircuitCallback)
+
+	// SetRejectCallback sets a callback function that will be called
+	// whenever the breaker rejects an operation
+	SetRejectCallback(callback func())
+}
+
+// CostStore extends Store with a cost budget measured in entry weights
+type CostStore interface {
+	Store
+
+	// SetMaxCost sets the total cost budget; entries are evicted to stay
+	// within it (0 disables the budget)
+	SetMaxCost(maxCost int64)
+
+	// TotalCost returns the summed cost of all stored entries
+	TotalCost() int64
+}
+
+// UpdateFunc computes a replacement for an entry. existing is the current
+// live entry or nil if the key is missing or expired. Returning a nil entry
+// leaves the store unchanged; returning an error aborts the update.
+type UpdateFunc func(existing *entry.Entry) (*entry.Entry, error)
+
+// AtomicStore extends Store with atomic read-modify-write support
+type AtomicStore interface {
+	Store
+
+	// Update atomically replaces the entry for key with the result of fn,
+	// assigning the new entry a fresh version. fn may be called more than
+	// once if the store retries after a conflicting write.
+	Update(key string, fn UpdateFunc) error
+}
+
+// CounterStore extends Store with native integer counters
+type CounterStore interface {
+	Store
+
+	// IncrBy atomically adds delta to the integer stored at key and returns
+	// the new value. A missing or expired key starts from zero and is created
+	// with ttl (zero means no expiry); an existing counter keeps its TTL.
+	IncrBy(key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// LeaseStore extends Store with leases that let processes sharing the store
+// agree on which of them loads a missing key
+type LeaseStore interface {
+	Store
+
+	// AcquireLease takes the lease for key for ttl if nobody holds it. The
+	// returned fencing token grows with each acquisition and identifies the
+	// holder in ReleaseLease and WithLease.
+	AcquireLease(key string, ttl time.Duration) (token int64, acquired bool, err error)
+
+	// ReleaseLease gives up the lease if it is still held with token
+	ReleaseLease(key string, token int64) error
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// callbacks holds the removal notifications registered with a store
+type callbacks struct {
+	evict   store.EvictCallback
+	cleanup store.EvictCallback
+	removal store.RemovalCallback
+}
+
+// notify reports a removed entry: every removal goes to the removal
+// callback, evictions to the evict callback and expirations to the cleanup
+// callback
+func (c *callbacks) notify(key string, e *entry.Entry, reason store.RemovalReason) {
+	if c.removal != nil {
+		c.removal(key, e.Value, reason)
+	}
+
+	switch reason {
+	case store.RemovedCapacity, store.RemovedCost:
+		if c.evict != nil {
+			c.evict(key, e.Value)
+		}
+	case store.RemovedExpiredOnAccess, store.RemovedExpiredSweep:
+		if c.cleanup != nil {
+			c.cleanup(key, e.Value)
+		}
+	}
+}
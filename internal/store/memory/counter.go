@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"math"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/clock"
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// incrBy implements CounterStore.IncrBy on top of an atomic update; new
+// entries measure their expiry by clk
+func incrBy(update func(string, store.UpdateFunc) error, key string, delta int64, ttl time.Duration, clk clock.Clock) (int64, error) {
+	var result int64
+	err := update(key, func(existing *entry.Entry) (*entry.Entry, error) {
+		if existing == nil {
+			result = delta
+			return entry.NewWithClock(result, ttl, clk), nil
+		}
+
+		current, ok := toInt64(existing.Value)
+		if !ok || existing.IsCompressed {
+			return nil, store.ErrNotInteger
+		}
+		if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+			return nil, store.ErrOverflow
+		}
+
+		result = current + delta
+		e := entry.NewWithClock(result, 0, clk)
+		e.Inherit(existing)
+		return e, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+// toInt64 converts any Go integer type that fits in an int64
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), uint64(v) <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	default:
+		return 0, false
+	}
+}
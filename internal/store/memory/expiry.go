@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/entry"
+)
+
+// DefaultCleanupBatchSize is the number of expired entries Cleanup removes
+// per acquisition of the store lock unless SetCleanupBatchSize says otherwise
+const DefaultCleanupBatchSize = 1024
+
+// expiryItem is an entry with an expiry, positioned in an expiryIndex
+type expiryItem struct {
+	key   string
+	entry *entry.Entry
+	at    time.Time
+	index int
+}
+
+// expiryHeap is a min-heap of items ordered by expiration time
+type expiryHeap []*expiryItem
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	item := x.(*expiryItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// expiryIndex orders the entries of a store by expiration time, so cleanup
+// only visits entries that have expired. Entries without an expiry are not
+// indexed.
+//
+// Sliding entries push their expiry back on access without telling the
+// store, so an indexed time is a lower bound: entries found not to have
+// expired yet are re-indexed at their current expiry instead of removed.
+type expiryIndex struct {
+	heap  expiryHeap
+	items map[string]*expiryItem
+}
+
+// newExpiryIndex creates an empty index
+func newExpiryIndex() *expiryIndex {
+	return &expiryIndex{items: make(map[string]*expiryItem)}
+}
+
+// add indexes e under key, replacing any entry indexed for key
+func (x *expiryIndex) add(key string, e *entry.Entry) {
+	expiry := e.Expiry()
+	if expiry == nil {
+		x.drop(key)
+		return
+	}
+
+	if item, found := x.items[key]; found {
+		item.entry = e
+		item.at = *expiry
+		heap.Fix(&x.heap, item.index)
+		return
+	}
+
+	item := &expiryItem{key: key, entry: e, at: *expiry}
+	heap.Push(&x.heap, item)
+	x.items[key] = item
+}
+
+// remove drops key from the index if it is indexed for e
+func (x *expiryIndex) remove(key string, e *entry.Entry) {
+	if item, found := x.items[key]; found && item.entry == e {
+		x.drop(key)
+	}
+}
+
+// drop removes key from the index
+func (x *expiryIndex) drop(key string) {
+	item, found := x.items[key]
+	if !found {
+		return
+	}
+	heap.Remove(&x.heap, item.index)
+	delete(x.items, key)
+}
+
+// next returns the key of the indexed entry that expires first if it had
+// expired by now
+func (x *expiryIndex) next(now time.Time) (string, bool) {
+	for len(x.heap) > 0 {
+		item := x.heap[0]
+		if !now.After(item.at) {
+			return "", false
+		}
+
+		expiry := item.entry.Expiry()
+		switch {
+		case expiry == nil:
+			x.drop(item.key)
+		case !now.After(*expiry):
+			// Slid since it was indexed
+			item.at = *expiry
+			heap.Fix(&x.heap, 0)
+		default:
+			return item.key, true
+		}
+	}
+	return "", false
+}
+
+// reset empties the index
+func (x *expiryIndex) reset() {
+	x.heap = nil
+	x.items = make(map[string]*expiryItem)
+}
+
+// cleanupBatch removes up to limit entries that had expired by now from the
+// store with remove and from x. It returns the number of entries removed and
+// whether more may remain.
+func (x *expiryIndex) cleanupBatch(now time.Time, limit int, remove func(key string)) (int, bool) {
+	for removed := 0; removed < limit; removed++ {
+		key, found := x.next(now)
+		if !found {
+			return removed, false
+		}
+
+		remove(key)
+		x.drop(key)
+	}
+	return limit, true
+}
@@ -5,33 +5,56 @@ import (
 	"time"
 
 	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/vnykmshr/obcache-go/internal/clock"
 	"github.com/vnykmshr/obcache-go/internal/entry"
 	"github.com/vnykmshr/obcache-go/internal/store"
 )
 
 // Store implements an in-memory LRU cache with TTL support
 type Store struct {
-	cache           *lru.Cache[string, *entry.Entry]
-	mutex           sync.RWMutex
-	evictCallback   store.EvictCallback
-	cleanupCallback store.EvictCallback
-	cleanupTicker   *time.Ticker
-	stopCleanup     chan struct{}
-	capacity        int
+	cache         *lru.Cache[string, *entry.Entry]
+	pinned        map[string]*entry.Entry
+	mutex         sync.RWMutex
+	callbacks     callbacks
+	cleanupTicker clock.Ticker
+	stopCleanup   chan struct{}
+	closeOnce     sync.Once
+	clock         clock.Clock
+	capacity      int
+
+	// Cost accounting, protected by mutex
+	maxCost     int64
+	totalCost   int64
+	prioritized int
+
+	// expiry indexes entries by expiration time for Cleanup, protected by mutex
+	expiry           *expiryIndex
+	cleanupBatchSize int
+
+	// removing is the reason reported for entries the LRU drops; it is
+	// RemovedCapacity unless an entry is being removed explicitly
+	removing store.RemovalReason
+
+	// version is the last version assigned to a written entry
+	version uint64
 }
 
 // New creates a new memory store with the specified capacity
 func New(capacity int) (*Store, error) {
 	s := &Store{
-		capacity:    capacity,
-		stopCleanup: make(chan struct{}),
+		capacity:         capacity,
+		pinned:           make(map[string]*entry.Entry),
+		stopCleanup:      make(chan struct{}),
+		clock:            clock.Real(),
+		expiry:           newExpiryIndex(),
+		cleanupBatchSize: DefaultCleanupBatchSize,
 	}
 
-	// Create cache with eviction callback
+	// Create cache with eviction callback. The LRU invokes it for every
+	// removal, so it also keeps the cost accounting in sync.
 	cache, err := lru.NewWithEvict[string, *entry.Entry](capacity, func(key string, entry *entry.Entry) {
-		if s.evictCallback != nil {
-			s.evictCallback(key, entry.Value)
-		}
+		s.untrack(key, entry)
+		s.callbacks.notify(key, entry, s.removing)
 	})
 	if err != nil {
 		return nil, err
@@ -49,7 +72,7 @@ func NewWithCleanup(capacity int, cleanupInterval time.Duration) (*Store, error)
 	}
 
 	if cleanupInterval > 0 {
-		s.startCleanup(cleanupInterval)
+		s.StartCleanup(cleanupInterval)
 	}
 
 	return s, nil
@@ -58,48 +81,99 @@ func NewWithCleanup(capacity int, cleanupInterval time.Duration) (*Store, error)
 // Get retrieves an entry by key
 func (s *Store) Get(key string) (*entry.Entry, bool) {
 	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
-	entry, found := s.cache.Get(key)
+	entry, found := s.pinned[key]
 	if !found {
-		return nil, false
+		entry, found = s.cache.Get(key)
+	}
+	if found && !entry.IsExpired() {
+		// Touch the entry for LRU
+		entry.Touch()
+		entry.Slide()
+		s.mutex.RUnlock()
+		return entry, true
+	}
+	s.mutex.RUnlock()
+
+	// Remove an expired entry before reporting the miss, so the removal is
+	// visible to the caller. Only remove the entry we saw; it may have been
+	// replaced while the lock was released.
+	if found {
+		s.mutex.Lock()
+		if current, stillThere := s.peekLocked(key); stillThere && current == entry {
+			s.removeLocked(key, store.RemovedExpiredOnAccess)
+		}
+		s.mutex.Unlock()
 	}
+	return nil, false
+}
 
-	// Check if entry has expired
-	if entry.IsExpired() {
-		// Remove expired entry (do this in a separate goroutine to avoid deadlock)
-		go func() {
-			s.mutex.Lock()
-			s.cache.Remove(key)
-			s.mutex.Unlock()
+// Set stores an entry with the given key
+func (s *Store) Set(key string, e *entry.Entry, opts ...store.SetOption) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-			if s.cleanupCallback != nil {
-				s.cleanupCallback(key, entry.Value)
-			}
-		}()
-		return nil, false
+	existing, exists := s.peekLocked(key)
+	if !store.ApplySetOptions(opts).Allows(liveEntry(existing, exists)) {
+		return store.ErrNotStored
 	}
 
-	// Touch the entry for LRU
-	entry.Touch()
-	return entry, true
+	s.setLocked(key, e, exists)
+	return nil
 }
 
-// Set stores an entry with the given key
-func (s *Store) Set(key string, entry *entry.Entry) error {
+// Update atomically replaces the entry for key with the result of fn
+func (s *Store) Update(key string, fn store.UpdateFunc) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.cache.Add(key, entry)
+	existing, exists := s.peekLocked(key)
+	current := existing
+	if !liveEntry(existing, exists) {
+		current = nil
+	}
+
+	e, err := fn(current)
+	if err != nil || e == nil {
+		return err
+	}
+
+	s.setLocked(key, e, exists)
 	return nil
 }
 
+// IncrBy atomically adds delta to the integer stored at key
+func (s *Store) IncrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	return incrBy(s.Update, key, delta, ttl, s.clock)
+}
+
+// setLocked stores e under key, replacing any previous entry, and assigns it
+// the next version
+func (s *Store) setLocked(key string, e *entry.Entry, exists bool) {
+	s.version++
+	e.Version = s.version
+
+	if exists {
+		s.removeLocked(key, store.RemovedReplaced)
+	}
+
+	if e.NoEvict {
+		s.pinned[key] = e
+		s.track(key, e)
+		s.makeRoomLocked(0, false)
+		return
+	}
+
+	s.makeRoomLocked(e.Weight(), true)
+	s.cache.Add(key, e)
+	s.track(key, e)
+}
+
 // Delete removes an entry by key
 func (s *Store) Delete(key string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.cache.Remove(key)
+	s.removeLocked(key, store.RemovedDeleted)
 	return nil
 }
 
@@ -110,12 +184,17 @@ func (s *Store) Keys() []string {
 
 	keys := s.cache.Keys()
 	// Filter out expired keys
-	validKeys := make([]string, 0, len(keys))
+	validKeys := make([]string, 0, len(keys)+len(s.pinned))
 	for _, key := range keys {
 		if entry, found := s.cache.Peek(key); found && !entry.IsExpired() {
 			validKeys = append(validKeys, key)
 		}
 	}
+	for key, entry := range s.pinned {
+		if !entry.IsExpired() {
+			validKeys = append(validKeys, key)
+		}
+	}
 
 	return validKeys
 }
@@ -133,6 +212,11 @@ func (s *Store) Len() int {
 			count++
 		}
 	}
+	for _, entry := range s.pinned {
+		if !entry.IsExpired() {
+			count++
+		}
+	}
 
 	return count
 }
@@ -142,32 +226,50 @@ func (s *Store) Clear() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	s.removing = store.RemovedCleared
 	s.cache.Purge()
+	s.removing = store.RemovedCapacity
+	for key, e := range s.pinned {
+		s.callbacks.notify(key, e, store.RemovedCleared)
+	}
+	s.pinned = make(map[string]*entry.Entry)
+	s.totalCost = 0
+	s.prioritized = 0
+	s.expiry.reset()
 	return nil
 }
 
-// Close closes the store and cleans up resources
+// Close stops the cleanup goroutine. Entries are left in place; call Clear
+// to drop them. Close may be called more than once.
 func (s *Store) Close() error {
-	if s.cleanupTicker != nil {
-		s.cleanupTicker.Stop()
-	}
-
-	close(s.stopCleanup)
-	return s.Clear()
+	s.closeOnce.Do(func() {
+		if s.cleanupTicker != nil {
+			s.cleanupTicker.Stop()
+		}
+		close(s.stopCleanup)
+	})
+	return nil
 }
 
 // SetEvictCallback sets the callback for LRU evictions
 func (s *Store) SetEvictCallback(callback store.EvictCallback) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.evictCallback = callback
+	s.callbacks.evict = callback
 }
 
 // SetCleanupCallback sets the callback for TTL cleanup
 func (s *Store) SetCleanupCallback(callback store.EvictCallback) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.cleanupCallback = callback
+	s.callbacks.cleanup = callback
+}
+
+// SetRemovalCallback sets the callback for every removed entry
+func (s *Store) SetRemovalCallback(callback store.RemovalCallback) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callbacks.removal = callback
 }
 
 // Capacity returns the maximum number of entries the store can hold
@@ -175,36 +277,149 @@ func (s *Store) Capacity() int {
 	return s.capacity
 }
 
-// Cleanup removes expired entries and returns the number of entries removed
-func (s *Store) Cleanup() int {
+// SetMaxCost sets the total cost budget (0 disables the budget)
+func (s *Store) SetMaxCost(maxCost int64) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.maxCost = maxCost
+	s.makeRoomLocked(0, false)
+}
 
-	keys := s.cache.Keys()
+// TotalCost returns the summed cost of all stored entries
+func (s *Store) TotalCost() int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.totalCost
+}
+
+// Cleanup removes expired entries and returns the number of entries removed.
+// Only expired entries are visited, and the lock is released after every
+// batch of SetCleanupBatchSize removals so readers are never blocked for long.
+func (s *Store) Cleanup() int {
 	removed := 0
+	for {
+		n, more := s.cleanupBatch()
+		removed += n
+		if !more {
+			return removed
+		}
+	}
+}
 
-	for _, key := range keys {
-		if entry, found := s.cache.Peek(key); found && entry.IsExpired() {
-			s.cache.Remove(key)
-			removed++
+// cleanupBatch removes one batch of expired entries under the lock
+func (s *Store) cleanupBatch() (int, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-			if s.cleanupCallback != nil {
-				s.cleanupCallback(key, entry.Value)
-			}
+	return s.expiry.cleanupBatch(s.clock.Now(), s.cleanupBatchSize, func(key string) {
+		s.removeLocked(key, store.RemovedExpiredSweep)
+	})
+}
+
+// SetCleanupBatchSize sets how many expired entries Cleanup removes per
+// acquisition of the lock (DefaultCleanupBatchSize if size is not positive)
+func (s *Store) SetCleanupBatchSize(size int) {
+	if size <= 0 {
+		size = DefaultCleanupBatchSize
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cleanupBatchSize = size
+}
+
+// SetClock sets the clock used to expire entries created by the store and
+// to schedule cleanup (the system clock if c is nil). It must be called
+// before the store is used.
+func (s *Store) SetClock(c clock.Clock) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clock = clock.Or(c)
+}
+
+// peekLocked looks up an entry in the pinned set or the LRU without touching it
+func (s *Store) peekLocked(key string) (*entry.Entry, bool) {
+	if e, found := s.pinned[key]; found {
+		return e, true
+	}
+	return s.cache.Peek(key)
+}
+
+// removeLocked removes an entry from wherever it is stored, reporting it
+// with reason
+func (s *Store) removeLocked(key string, reason store.RemovalReason) {
+	if e, found := s.pinned[key]; found {
+		delete(s.pinned, key)
+		s.untrack(key, e)
+		s.callbacks.notify(key, e, reason)
+		return
+	}
+
+	s.removing = reason
+	s.cache.Remove(key)
+	s.removing = store.RemovedCapacity
+}
+
+// makeRoomLocked evicts unpinned entries until there is a free slot (when
+// needSlot is set) and weight more fits within the cost budget
+func (s *Store) makeRoomLocked(weight int64, needSlot bool) {
+	for s.cache.Len() > 0 {
+		overCapacity := needSlot && s.cache.Len() >= s.capacity
+		overBudget := s.maxCost > 0 && s.totalCost+weight > s.maxCost
+		if !overCapacity && !overBudget {
+			return
+		}
+
+		victim, ok := s.victimLocked()
+		if !ok {
+			return
+		}
+
+		reason := store.RemovedCost
+		if overCapacity {
+			reason = store.RemovedCapacity
 		}
+		s.removeLocked(victim, reason)
 	}
+}
 
-	return removed
+// victimLocked returns the next entry to evict, honouring priorities
+func (s *Store) victimLocked() (string, bool) {
+	if s.prioritized == 0 {
+		key, _, ok := s.cache.GetOldest()
+		return key, ok
+	}
+	// Keys are ordered from oldest to newest
+	return selectVictim(s.cache.Keys(), s.cache.Peek)
+}
+
+// track adds an entry to the cost accounting and expiration index
+func (s *Store) track(key string, e *entry.Entry) {
+	s.expiry.add(key, e)
+	s.totalCost += e.Weight()
+	if e.Priority != 0 {
+		s.prioritized++
+	}
+}
+
+// untrack removes an entry from the cost accounting and expiration index
+func (s *Store) untrack(key string, e *entry.Entry) {
+	s.expiry.remove(key, e)
+	s.totalCost -= e.Weight()
+	if e.Priority != 0 {
+		s.prioritized--
+	}
 }
 
-// startCleanup starts the automatic cleanup goroutine
-func (s *Store) startCleanup(interval time.Duration) {
-	s.cleanupTicker = time.NewTicker(interval)
+// StartCleanup starts a goroutine that calls Cleanup every interval of the
+// store's clock until the store is closed. It must be called at most once,
+// after SetClock.
+func (s *Store) StartCleanup(interval time.Duration) {
+	s.cleanupTicker = s.clock.NewTicker(interval)
 
 	go func() {
 		for {
 			select {
-			case <-s.cleanupTicker.C:
+			case <-s.cleanupTicker.C():
 				s.Cleanup()
 			case <-s.stopCleanup:
 				return
@@ -215,7 +430,13 @@ func (s *Store) startCleanup(interval time.Duration) {
 
 // Ensure Store implements the required interfaces
 var (
-	_ store.Store    = (*Store)(nil)
-	_ store.LRUStore = (*Store)(nil)
-	_ store.TTLStore = (*Store)(nil)
+	_ store.Store             = (*Store)(nil)
+	_ store.LRUStore          = (*Store)(nil)
+	_ store.TTLStore          = (*Store)(nil)
+	_ store.RemovalStore      = (*Store)(nil)
+	_ store.BatchCleanupStore = (*Store)(nil)
+	_ store.ClockStore        = (*Store)(nil)
+	_ store.CostStore         = (*Store)(nil)
+	_ store.AtomicStore       = (*Store)(nil)
+	_ store.CounterStore      = (*Store)(nil)
 )
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/vnykmshr/obcache-go/internal/clock"
 	"github.com/vnykmshr/obcache-go/internal/entry"
 	"github.com/vnykmshr/obcache-go/internal/eviction"
 	"github.com/vnykmshr/obcache-go/internal/store"
@@ -11,12 +12,26 @@ import (
 
 // StrategyStore implements an in-memory cache with pluggable eviction strategies
 type StrategyStore struct {
-	strategy        eviction.Strategy
-	mutex           sync.RWMutex
-	evictCallback   store.EvictCallback
-	cleanupCallback store.EvictCallback
-	cleanupTicker   *time.Ticker
-	stopCleanup     chan struct{}
+	strategy      eviction.Strategy
+	pinned        map[string]*entry.Entry
+	mutex         sync.RWMutex
+	callbacks     callbacks
+	cleanupTicker clock.Ticker
+	stopCleanup   chan struct{}
+	closeOnce     sync.Once
+	clock         clock.Clock
+
+	// Cost accounting, protected by mutex
+	maxCost     int64
+	totalCost   int64
+	prioritized int
+
+	// expiry indexes entries by expiration time for Cleanup, protected by mutex
+	expiry           *expiryIndex
+	cleanupBatchSize int
+
+	// version is the last version assigned to a written entry
+	version uint64
 }
 
 // NewWithStrategy creates a new memory store with the specified eviction strategy
@@ -24,8 +39,12 @@ func NewWithStrategy(config eviction.Config) (*StrategyStore, error) {
 	strategy := eviction.NewStrategy(config)
 
 	s := &StrategyStore{
-		strategy:    strategy,
-		stopCleanup: make(chan struct{}),
+		strategy:         strategy,
+		pinned:           make(map[string]*entry.Entry),
+		stopCleanup:      make(chan struct{}),
+		clock:            clock.Real(),
+		expiry:           newExpiryIndex(),
+		cleanupBatchSize: DefaultCleanupBatchSize,
 	}
 
 	return s, nil
@@ -39,7 +58,7 @@ func NewWithStrategyAndCleanup(config eviction.Config, cleanupInterval time.Dura
 	}
 
 	if cleanupInterval > 0 {
-		s.startCleanup(cleanupInterval)
+		s.StartCleanup(cleanupInterval)
 	}
 
 	return s, nil
@@ -48,58 +67,115 @@ func NewWithStrategyAndCleanup(config eviction.Config, cleanupInterval time.Dura
 // Get retrieves an entry by key
 func (s *StrategyStore) Get(key string) (*entry.Entry, bool) {
 	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
-	entry, found := s.strategy.Get(key)
+	entry, found := s.pinned[key]
 	if !found {
-		return nil, false
+		entry, found = s.strategy.Get(key)
+	}
+	if found && !entry.IsExpired() {
+		// Touch the entry for TTL tracking
+		entry.Touch()
+		entry.Slide()
+		s.mutex.RUnlock()
+		return entry, true
 	}
+	s.mutex.RUnlock()
+
+	// Remove an expired entry before reporting the miss, so the removal is
+	// visible to the caller. Only remove the entry we saw; it may have been
+	// replaced while the lock was released.
+	if found {
+		s.mutex.Lock()
+		if current, stillThere := s.peekLocked(key); stillThere && current == entry {
+			s.removeLocked(key, store.RemovedExpiredOnAccess)
+		}
+		s.mutex.Unlock()
+	}
+	return nil, false
+}
 
-	// Check if entry has expired
-	if entry.IsExpired() {
-		// Remove expired entry (do this in a separate goroutine to avoid deadlock)
-		go func() {
-			s.mutex.Lock()
-			s.strategy.Remove(key)
-			s.mutex.Unlock()
+// Set stores an entry with the given key
+func (s *StrategyStore) Set(key string, e *entry.Entry, opts ...store.SetOption) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-			if s.cleanupCallback != nil {
-				s.cleanupCallback(key, entry.Value)
-			}
-		}()
-		return nil, false
+	existing, exists := s.peekLocked(key)
+	if !store.ApplySetOptions(opts).Allows(liveEntry(existing, exists)) {
+		return store.ErrNotStored
 	}
 
-	// Touch the entry for TTL tracking
-	entry.Touch()
-	return entry, true
+	s.setLocked(key, e, existing, exists)
+	return nil
 }
 
-// Set stores an entry with the given key
-func (s *StrategyStore) Set(key string, entry *entry.Entry) error {
+// Update atomically replaces the entry for key with the result of fn
+func (s *StrategyStore) Update(key string, fn store.UpdateFunc) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	evictedKey, wasEvicted := s.strategy.Add(key, entry)
+	existing, exists := s.peekLocked(key)
+	current := existing
+	if !liveEntry(existing, exists) {
+		current = nil
+	}
 
-	// Call eviction callback if an entry was evicted
-	// Note: The evicted entry is no longer in the strategy, so we can't retrieve its value
-	// This is a limitation of the current design that we'll need to address
-	if wasEvicted && s.evictCallback != nil && evictedKey != "" {
-		// For now, call the callback with a placeholder value
-		// In a production implementation, the strategy would need to return both key and value
-		s.evictCallback(evictedKey, "evicted")
+	e, err := fn(current)
+	if err != nil || e == nil {
+		return err
 	}
 
+	s.setLocked(key, e, existing, exists)
 	return nil
 }
 
+// IncrBy atomically adds delta to the integer stored at key
+func (s *StrategyStore) IncrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	return incrBy(s.Update, key, delta, ttl, s.clock)
+}
+
+// setLocked stores e under key, replacing existing if present, and assigns
+// it the next version
+func (s *StrategyStore) setLocked(key string, e, existing *entry.Entry, exists bool) {
+	s.version++
+	e.Version = s.version
+
+	if e.NoEvict {
+		if exists {
+			s.removeLocked(key, store.RemovedReplaced)
+		}
+		s.pinned[key] = e
+		s.track(key, e)
+		s.makeRoomLocked(0, false)
+		return
+	}
+
+	// Updating a key already tracked by the strategy keeps its eviction
+	// history (e.g. LFU frequency); otherwise make room for a new key first
+	if _, pinned := s.pinned[key]; exists && !pinned {
+		s.untrack(key, existing)
+		s.strategy.Add(key, e)
+		s.track(key, e)
+		s.callbacks.notify(key, existing, store.RemovedReplaced)
+		s.makeRoomLocked(0, false)
+		return
+	}
+
+	if exists {
+		s.removeLocked(key, store.RemovedReplaced)
+	}
+
+	// Evicting up front means the strategy never evicts on its own, so the
+	// callback always receives the evicted entry's real value
+	s.makeRoomLocked(e.Weight(), true)
+	s.strategy.Add(key, e)
+	s.track(key, e)
+}
+
 // Delete removes an entry by key
 func (s *StrategyStore) Delete(key string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.strategy.Remove(key)
+	s.removeLocked(key, store.RemovedDeleted)
 	return nil
 }
 
@@ -110,12 +186,17 @@ func (s *StrategyStore) Keys() []string {
 
 	keys := s.strategy.Keys()
 	// Filter out expired keys
-	validKeys := make([]string, 0, len(keys))
+	validKeys := make([]string, 0, len(keys)+len(s.pinned))
 	for _, key := range keys {
 		if entry, found := s.strategy.Peek(key); found && !entry.IsExpired() {
 			validKeys = append(validKeys, key)
 		}
 	}
+	for key, entry := range s.pinned {
+		if !entry.IsExpired() {
+			validKeys = append(validKeys, key)
+		}
+	}
 
 	return validKeys
 }
@@ -133,6 +214,11 @@ func (s *StrategyStore) Len() int {
 			count++
 		}
 	}
+	for _, entry := range s.pinned {
+		if !entry.IsExpired() {
+			count++
+		}
+	}
 
 	return count
 }
@@ -142,32 +228,56 @@ func (s *StrategyStore) Clear() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	if s.callbacks.removal != nil {
+		for _, key := range s.strategy.Keys() {
+			if e, found := s.strategy.Peek(key); found {
+				s.callbacks.notify(key, e, store.RemovedCleared)
+			}
+		}
+		for key, e := range s.pinned {
+			s.callbacks.notify(key, e, store.RemovedCleared)
+		}
+	}
+
 	s.strategy.Clear()
+	s.pinned = make(map[string]*entry.Entry)
+	s.totalCost = 0
+	s.prioritized = 0
+	s.expiry.reset()
 	return nil
 }
 
-// Close closes the store and cleans up resources
+// Close stops the cleanup goroutine. Entries are left in place; call Clear
+// to drop them. Close may be called more than once.
 func (s *StrategyStore) Close() error {
-	if s.cleanupTicker != nil {
-		s.cleanupTicker.Stop()
-	}
-
-	close(s.stopCleanup)
-	return s.Clear()
+	s.closeOnce.Do(func() {
+		if s.cleanupTicker != nil {
+			s.cleanupTicker.Stop()
+		}
+		close(s.stopCleanup)
+	})
+	return nil
 }
 
 // SetEvictCallback sets the callback for evictions
 func (s *StrategyStore) SetEvictCallback(callback store.EvictCallback) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.evictCallback = callback
+	s.callbacks.evict = callback
 }
 
 // SetCleanupCallback sets the callback for TTL cleanup
 func (s *StrategyStore) SetCleanupCallback(callback store.EvictCallback) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	s.cleanupCallback = callback
+	s.callbacks.cleanup = callback
+}
+
+// SetRemovalCallback sets the callback for every removed entry
+func (s *StrategyStore) SetRemovalCallback(callback store.RemovalCallback) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.callbacks.removal = callback
 }
 
 // Capacity returns the maximum number of entries the store can hold
@@ -175,36 +285,152 @@ func (s *StrategyStore) Capacity() int {
 	return s.strategy.Capacity()
 }
 
-// Cleanup removes expired entries and returns the number of entries removed
+// Cleanup removes expired entries and returns the number of entries removed.
+// Only expired entries are visited, and the lock is released after every
+// batch of SetCleanupBatchSize removals so readers are never blocked for long.
 func (s *StrategyStore) Cleanup() int {
+	removed := 0
+	for {
+		n, more := s.cleanupBatch()
+		removed += n
+		if !more {
+			return removed
+		}
+	}
+}
+
+// cleanupBatch removes one batch of expired entries under the lock
+func (s *StrategyStore) cleanupBatch() (int, bool) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	keys := s.strategy.Keys()
-	removed := 0
+	return s.expiry.cleanupBatch(s.clock.Now(), s.cleanupBatchSize, func(key string) {
+		s.removeLocked(key, store.RemovedExpiredSweep)
+	})
+}
 
-	for _, key := range keys {
-		if entry, found := s.strategy.Peek(key); found && entry.IsExpired() {
-			s.strategy.Remove(key)
-			removed++
+// SetCleanupBatchSize sets how many expired entries Cleanup removes per
+// acquisition of the lock (DefaultCleanupBatchSize if size is not positive)
+func (s *StrategyStore) SetCleanupBatchSize(size int) {
+	if size <= 0 {
+		size = DefaultCleanupBatchSize
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.cleanupBatchSize = size
+}
 
-			if s.cleanupCallback != nil {
-				s.cleanupCallback(key, entry.Value)
-			}
+// SetClock sets the clock used to expire entries created by the store and
+// to schedule cleanup (the system clock if c is nil). It must be called
+// before the store is used.
+func (s *StrategyStore) SetClock(c clock.Clock) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.clock = clock.Or(c)
+}
+
+// SetMaxCost sets the total cost budget (0 disables the budget)
+func (s *StrategyStore) SetMaxCost(maxCost int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxCost = maxCost
+	s.makeRoomLocked(0, false)
+}
+
+// TotalCost returns the summed cost of all stored entries
+func (s *StrategyStore) TotalCost() int64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.totalCost
+}
+
+// peekLocked looks up an entry in the pinned set or the strategy without touching it
+func (s *StrategyStore) peekLocked(key string) (*entry.Entry, bool) {
+	if e, found := s.pinned[key]; found {
+		return e, true
+	}
+	return s.strategy.Peek(key)
+}
+
+// removeLocked removes an entry from wherever it is stored, reporting it
+// with reason, and reports whether there was one
+func (s *StrategyStore) removeLocked(key string, reason store.RemovalReason) bool {
+	e, found := s.pinned[key]
+	if found {
+		delete(s.pinned, key)
+	} else if e, found = s.strategy.Peek(key); found {
+		s.strategy.Remove(key)
+	} else {
+		return false
+	}
+
+	s.untrack(key, e)
+	s.callbacks.notify(key, e, reason)
+	return true
+}
+
+// makeRoomLocked evicts unpinned entries until there is a free slot (when
+// needSlot is set) and weight more fits within the cost budget
+func (s *StrategyStore) makeRoomLocked(weight int64, needSlot bool) {
+	capacity := s.strategy.Capacity()
+	for s.strategy.Len() > 0 {
+		overCapacity := needSlot && capacity > 0 && s.strategy.Len() >= capacity
+		overBudget := s.maxCost > 0 && s.totalCost+weight > s.maxCost
+		if !overCapacity && !overBudget {
+			return
+		}
+
+		victim, ok := s.victimLocked()
+		if !ok {
+			return
+		}
+		reason := store.RemovedCost
+		if overCapacity {
+			reason = store.RemovedCapacity
+		}
+		if !s.removeLocked(victim, reason) {
+			return
 		}
 	}
+}
 
-	return removed
+// victimLocked returns the next entry to evict, honouring priorities
+func (s *StrategyStore) victimLocked() (string, bool) {
+	if s.prioritized == 0 {
+		return s.strategy.Victim()
+	}
+	// Ties are broken by the strategy's key order
+	return selectVictim(s.strategy.Keys(), s.strategy.Peek)
+}
+
+// track adds an entry to the cost accounting and expiration index
+func (s *StrategyStore) track(key string, e *entry.Entry) {
+	s.expiry.add(key, e)
+	s.totalCost += e.Weight()
+	if e.Priority != 0 {
+		s.prioritized++
+	}
+}
+
+// untrack removes an entry from the cost accounting and expiration index
+func (s *StrategyStore) untrack(key string, e *entry.Entry) {
+	s.expiry.remove(key, e)
+	s.totalCost -= e.Weight()
+	if e.Priority != 0 {
+		s.prioritized--
+	}
 }
 
-// startCleanup starts the automatic cleanup goroutine
-func (s *StrategyStore) startCleanup(interval time.Duration) {
-	s.cleanupTicker = time.NewTicker(interval)
+// StartCleanup starts a goroutine that calls Cleanup every interval of the
+// store's clock until the store is closed. It must be called at most once,
+// after SetClock.
+func (s *StrategyStore) StartCleanup(interval time.Duration) {
+	s.cleanupTicker = s.clock.NewTicker(interval)
 
 	go func() {
 		for {
 			select {
-			case <-s.cleanupTicker.C:
+			case <-s.cleanupTicker.C():
 				s.Cleanup()
 			case <-s.stopCleanup:
 				return
@@ -229,7 +455,13 @@ func (s *StrategyStore) GetEvictionType() string {
 
 // Ensure StrategyStore implements the required interfaces
 var (
-	_ store.Store    = (*StrategyStore)(nil)
-	_ store.LRUStore = (*StrategyStore)(nil)
-	_ store.TTLStore = (*StrategyStore)(nil)
+	_ store.Store             = (*StrategyStore)(nil)
+	_ store.LRUStore          = (*StrategyStore)(nil)
+	_ store.TTLStore          = (*StrategyStore)(nil)
+	_ store.RemovalStore      = (*StrategyStore)(nil)
+	_ store.BatchCleanupStore = (*StrategyStore)(nil)
+	_ store.ClockStore        = (*StrategyStore)(nil)
+	_ store.CostStore         = (*StrategyStore)(nil)
+	_ store.AtomicStore       = (*StrategyStore)(nil)
+	_ store.CounterStore      = (*StrategyStore)(nil)
 )
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"github.com/vnykmshr/obcache-go/internal/entry"
+)
+
+// selectVictim picks the entry to evict from keys, which must be given in the
+// store's natural eviction order. The lowest-priority entry wins and ties go
+// to the entry that comes first in eviction order.
+func selectVictim(keys []string, peek func(key string) (*entry.Entry, bool)) (string, bool) {
+	var victim string
+	var victimPriority int
+	found := false
+
+	for _, key := range keys {
+		e, ok := peek(key)
+		if !ok {
+			continue
+		}
+		if !found || e.Priority < victimPriority {
+			victim = key
+			victimPriority = e.Priority
+			found = true
+		}
+	}
+
+	return victim, found
+}
+
+// liveEntry reports whether a looked-up entry is present and not expired
+func liveEntry(e *entry.Entry, found bool) bool {
+	return found && !e.IsExpired()
+}
@@ -0,0 +1,172 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// keyspaceEvents maps the keyevent notifications the store subscribes to
+// onto the removal reasons they are reported with
+var keyspaceEvents = map[string]store.RemovalReason{
+	"expired": store.RemovedExpiredSweep,
+	"evicted": store.RemovedCapacity,
+	"del":     store.RemovedDeletedExternally,
+}
+
+// ownDeleteWindow is how long a DEL issued by the store suppresses the
+// matching del notification. Deleting a missing key publishes nothing, so
+// unmatched suppressions must lapse.
+const ownDeleteWindow = 10 * time.Second
+
+// subscriber is implemented by clients that support pub/sub
+// (e.g. *redis.Client and *redis.ClusterClient)
+type subscriber interface {
+	Subscribe(ctx context.Context, channels ...string) *redis.PubSub
+}
+
+// optioner is implemented by clients that expose their options, from which
+// the database number of the keyevent channels is taken
+type optioner interface {
+	Options() *redis.Options
+}
+
+// WatchKeyspace subscribes to the keyevent notifications Redis publishes
+// when it expires or evicts a key, or a key is deleted, and reports the keys
+// under the store's prefix to the removal callback. Deletes made through this
+// store are not reported, so the callback only sees keys other clients
+// deleted. Cleanup callbacks are called for expired keys.
+//
+// Redis only publishes these notifications if notify-keyspace-events
+// includes "Exeg" (or "EA"). With Redis Cluster, only notifications from the
+// node the subscription connects to are received. The subscription ends when
+// the store is closed.
+func (s *Store) WatchKeyspace() error {
+	sub, ok := s.client.(subscriber)
+	if !ok {
+		return fmt.Errorf("redis client %T does not support pub/sub", s.client)
+	}
+
+	db := 0
+	if o, ok := s.client.(optioner); ok {
+		db = o.Options().DB
+	}
+	channels := make([]string, 0, len(keyspaceEvents))
+	for event := range keyspaceEvents {
+		channels = append(channels, fmt.Sprintf("__keyevent@%d__:%s", db, event))
+	}
+
+	pubsub := sub.Subscribe(s.ctx, channels...)
+	if _, err := pubsub.Receive(s.ctx); err != nil {
+		_ = pubsub.Close() //nolint:errcheck // The subscription failed anyway
+		return backendError(err)
+	}
+
+	s.deletesMu.Lock()
+	if s.keyspace != nil {
+		s.deletesMu.Unlock()
+		_ = pubsub.Close() //nolint:errcheck // Already watching
+		return nil
+	}
+	s.keyspace = pubsub
+	s.keyspaceDone = make(chan struct{})
+	s.deletes = make(map[string]time.Time)
+	s.deletesMu.Unlock()
+
+	go s.watchKeyspace(pubsub.Channel(), s.keyspaceDone)
+	return nil
+}
+
+// watchKeyspace reports keyevent notifications until the subscription ends
+func (s *Store) watchKeyspace(messages <-chan *redis.Message, done chan struct{}) {
+	defer close(done)
+
+	for msg := range messages {
+		event := msg.Channel[strings.LastIndexByte(msg.Channel, ':')+1:]
+		reason, ok := keyspaceEvents[event]
+		if !ok {
+			continue
+		}
+		key := s.extractKey(msg.Payload)
+		if key == "" {
+			continue
+		}
+		if reason == store.RemovedDeletedExternally && s.ownDelete(msg.Payload) {
+			continue
+		}
+
+		s.mu.RLock()
+		cleanupCallback, removalCallback := s.cleanupCallback, s.removalCallback
+		s.mu.RUnlock()
+
+		if reason == store.RemovedExpiredSweep && cleanupCallback != nil {
+			cleanupCallback(key, nil)
+		}
+		if removalCallback != nil {
+			removalCallback(key, nil, reason)
+		}
+	}
+}
+
+// stopKeyspace ends the keyspace subscription, if any, and waits for the
+// notifications already received to be reported
+func (s *Store) stopKeyspace() error {
+	s.deletesMu.Lock()
+	pubsub, done := s.keyspace, s.keyspaceDone
+	s.keyspace = nil
+	s.deletesMu.Unlock()
+
+	if pubsub == nil {
+		return nil
+	}
+	err := pubsub.Close()
+	<-done
+
+	s.deletesMu.Lock()
+	s.deletes = nil
+	s.deletesMu.Unlock()
+	return err
+}
+
+// expectDeletes records that the store is about to delete redisKeys, so the
+// del notifications they cause are not reported as external deletes
+func (s *Store) expectDeletes(redisKeys ...string) {
+	s.deletesMu.Lock()
+	defer s.deletesMu.Unlock()
+	if s.deletes == nil {
+		return
+	}
+
+	// Wall-clock time, since notifications arrive in real time whatever
+	// clock entries expire by
+	now := time.Now()
+	if len(s.deletes) > 1024 {
+		for redisKey, deadline := range s.deletes {
+			if now.After(deadline) {
+				delete(s.deletes, redisKey)
+			}
+		}
+	}
+	for _, redisKey := range redisKeys {
+		s.deletes[redisKey] = now.Add(ownDeleteWindow)
+	}
+}
+
+// ownDelete reports whether a del notification for redisKey was caused by
+// the store, consuming the expectation
+func (s *Store) ownDelete(redisKey string) bool {
+	s.deletesMu.Lock()
+	defer s.deletesMu.Unlock()
+
+	deadline, found := s.deletes[redisKey]
+	if !found {
+		return false
+	}
+	delete(s.deletes, redisKey)
+	return !time.Now().After(deadline)
+}
@@ -0,0 +1,219 @@
+package redis_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+	"github.com/vnykmshr/obcache-go/internal/store/memory"
+	redisstore "github.com/vnykmshr/obcache-go/internal/store/redis"
+	"github.com/vnykmshr/obcache-go/pkg/obcachetest"
+)
+
+// newMiniRedisStore creates a store backed by a MiniRedis, so the WATCH and
+// Lua script paths run without a Redis server
+func newMiniRedisStore(t *testing.T, prefix string) *redisstore.Store {
+	t.Helper()
+	server := obcachetest.NewMiniRedis(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close() //nolint:errcheck // Best-effort cleanup
+	})
+
+	s, err := redisstore.New(&redisstore.Config{
+		Client:    client,
+		KeyPrefix: prefix,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Redis store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = s.Close() //nolint:errcheck // Best-effort cleanup
+	})
+	return s
+}
+
+func TestRedisStoreSlidingExpiration(t *testing.T) {
+	s := newMiniRedisStore(t, "sliding-test:")
+	testKey := "session"
+
+	e := entry.New("session-data", 150*time.Millisecond)
+	e.IdleTTL = 150 * time.Millisecond
+	if err := s.Set(testKey, e); err != nil {
+		t.Fatalf("Failed to set sliding entry: %v", err)
+	}
+
+	// Accessing the entry extends the Redis key TTL past the original expiry
+	for i := 0; i < 4; i++ {
+		time.Sleep(75 * time.Millisecond)
+		if _, found := s.Get(testKey); !found {
+			t.Fatalf("Expected sliding entry to survive access %d", i)
+		}
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	if _, found := s.Get(testKey); found {
+		t.Fatal("Expected sliding entry to expire once idle")
+	}
+}
+
+func TestRedisStoreKeysDoNotSlideEntries(t *testing.T) {
+	s := newMiniRedisStore(t, "sliding-keys-test:")
+	testKey := "session"
+
+	e := entry.New("session-data", 150*time.Millisecond)
+	e.IdleTTL = 150 * time.Millisecond
+	if err := s.Set(testKey, e); err != nil {
+		t.Fatalf("Failed to set sliding entry: %v", err)
+	}
+	if n := s.Len(); n != 1 {
+		t.Fatalf("Expected 1 live key, got %d", n)
+	}
+
+	// Counting keys is not an access and must not keep the entry alive
+	for i := 0; i < 6; i++ {
+		time.Sleep(75 * time.Millisecond)
+		s.Len()
+	}
+
+	if n := s.Len(); n != 0 {
+		t.Fatalf("Expected idle sliding entry to expire, got %d live keys", n)
+	}
+	if _, found := s.Get(testKey); found {
+		t.Fatal("Expected idle sliding entry to expire")
+	}
+}
+
+func TestRedisStoreUpdate(t *testing.T) {
+	s := newMiniRedisStore(t, "update-test:")
+	testKey := "counter"
+
+	if err := s.Set(testKey, entry.New(float64(1), time.Minute)); err != nil {
+		t.Fatalf("Failed to set entry: %v", err)
+	}
+	before, _ := s.Get(testKey)
+
+	err := s.Update(testKey, func(existing *entry.Entry) (*entry.Entry, error) {
+		if existing == nil {
+			return nil, fmt.Errorf("expected existing entry")
+		}
+		return entry.New(existing.Value.(float64)+1, time.Minute), nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	after, found := s.Get(testKey)
+	if !found || after.Value != float64(2) {
+		t.Fatalf("Expected updated value 2, got %v (found=%v)", after, found)
+	}
+	if after.Version == before.Version {
+		t.Fatal("Expected Update to assign a new version")
+	}
+}
+
+func TestRedisStoreIncrBy(t *testing.T) {
+	s := newMiniRedisStore(t, "incr-test:")
+	testKey := "hits"
+
+	if n, err := s.IncrBy(testKey, 5, time.Minute); err != nil || n != 5 {
+		t.Fatalf("Expected 5, got %d, %v", n, err)
+	}
+	if n, _ := s.IncrBy(testKey, -2, time.Hour); n != 3 {
+		t.Fatalf("Expected 3, got %d", n)
+	}
+
+	e, found := s.Get(testKey)
+	if !found || e.Value != int64(3) {
+		t.Fatalf("Expected counter value 3, got %v (found=%v)", e, found)
+	}
+	if ttl := e.TTL(); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("Expected TTL from creation to be kept, got %v", ttl)
+	}
+}
+
+func TestCounterStoresHonourZeroTTL(t *testing.T) {
+	memoryStore, err := memory.New(10)
+	if err != nil {
+		t.Fatalf("Failed to create memory store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = memoryStore.Close() //nolint:errcheck // Best-effort cleanup
+	})
+
+	// A store default TTL must not replace an explicit zero TTL
+	server := obcachetest.NewMiniRedis(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close() //nolint:errcheck // Best-effort cleanup
+	})
+	redisStore, err := redisstore.New(&redisstore.Config{
+		Client:     client,
+		KeyPrefix:  "incr-ttl-test:",
+		DefaultTTL: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Redis store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = redisStore.Close() //nolint:errcheck // Best-effort cleanup
+	})
+
+	stores := map[string]store.CounterStore{
+		"memory": memoryStore,
+		"redis":  redisStore,
+	}
+
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			if _, err := s.IncrBy("forever", 1, 0); err != nil {
+				t.Fatalf("IncrBy failed: %v", err)
+			}
+			if e, found := s.Get("forever"); !found || e.HasExpiry() {
+				t.Fatalf("Expected a counter without expiry, got %+v (found=%v)", e, found)
+			}
+
+			if _, err := s.IncrBy("bounded", 1, time.Minute); err != nil {
+				t.Fatalf("IncrBy failed: %v", err)
+			}
+			if e, found := s.Get("bounded"); !found || e.TTL() <= 0 || e.TTL() > time.Minute {
+				t.Fatalf("Expected a counter expiring within a minute, got %+v (found=%v)", e, found)
+			}
+		})
+	}
+}
+
+func TestRedisStoreLeases(t *testing.T) {
+	s := newMiniRedisStore(t, "lease-test:")
+	testKey := "report"
+
+	token, acquired, err := s.AcquireLease(testKey, time.Second)
+	if err != nil || !acquired {
+		t.Fatalf("Expected to acquire lease, got %v, %v", acquired, err)
+	}
+	if _, again, _ := s.AcquireLease(testKey, time.Second); again {
+		t.Fatal("Expected lease to be exclusive")
+	}
+
+	// Fenced writes only succeed with the current token
+	if err := s.Set(testKey, entry.New("stale", time.Minute), store.WithLease(token-1)); !errors.Is(err, store.ErrNotStored) {
+		t.Fatalf("Expected fenced write with stale token to fail, got %v", err)
+	}
+	if err := s.Set(testKey, entry.New("fresh", time.Minute), store.WithLease(token)); err != nil {
+		t.Fatalf("Expected fenced write to succeed, got %v", err)
+	}
+
+	if err := s.ReleaseLease(testKey, token); err != nil {
+		t.Fatalf("ReleaseLease failed: %v", err)
+	}
+	next, acquired, _ := s.AcquireLease(testKey, time.Second)
+	if !acquired || next <= token {
+		t.Fatalf("Expected a larger fencing token after release, got %d (previous %d)", next, token)
+	}
+	_ = s.ReleaseLease(testKey, next)
+}
@@ -3,13 +3,17 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/vnykmshr/obcache-go/internal/clock"
 	"github.com/vnykmshr/obcache-go/internal/entry"
 	"github.com/vnykmshr/obcache-go/internal/store"
 )
@@ -21,8 +25,17 @@ type Store struct {
 	defaultTTL      time.Duration
 	evictCallback   store.EvictCallback
 	cleanupCallback store.EvictCallback
+	removalCallback store.RemovalCallback
+	clock           clock.Clock
 	mu              sync.RWMutex
 	ctx             context.Context
+
+	// Keyspace notification subscription (see WatchKeyspace) and the keys
+	// the store is deleting itself, protected by deletesMu
+	deletesMu    sync.Mutex
+	keyspace     *redis.PubSub
+	keyspaceDone chan struct{}
+	deletes      map[string]time.Time
 }
 
 // Config holds Redis store configuration
@@ -40,12 +53,82 @@ type Config struct {
 	Context context.Context
 }
 
+// maxUpdateRetries bounds how often Update retries after a concurrent write
+const maxUpdateRetries = 16
+
+// acquireLeaseScript takes a lease with SET NX PX. The fencing token is the
+// server time in microseconds, so it grows across acquisitions without
+// needing a separate counter key.
+var acquireLeaseScript = redis.NewScript(`
+local t = redis.call('TIME')
+local token = t[1] .. string.format('%06d', tonumber(t[2]))
+if redis.call('SET', KEYS[1], token, 'NX', 'PX', ARGV[1]) then
+	return token
+end
+return false
+`)
+
+// releaseLeaseScript deletes a lease only if it is still held with the token
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// fencedSetScript writes an entry only while the lease is held with the token
+var fencedSetScript = redis.NewScript(`
+if redis.call('GET', KEYS[2]) ~= ARGV[1] then
+	return 0
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[2])
+end
+return 1
+`)
+
+// ScriptHashes returns the SHA1 hashes of the Lua scripts the store runs,
+// keyed by name, for test servers that emulate the scripts instead of
+// running Lua
+func ScriptHashes() map[string]string {
+	return map[string]string{
+		"acquireLease": acquireLeaseScript.Hash(),
+		"releaseLease": releaseLeaseScript.Hash(),
+		"fencedSet":    fencedSetScript.Hash(),
+	}
+}
+
+// watcher is implemented by clients that support optimistic transactions
+// (e.g. *redis.Client and *redis.ClusterClient)
+type watcher interface {
+	Watch(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error
+}
+
 // SerializedEntry represents an entry as stored in Redis
 type SerializedEntry struct {
 	Value      json.RawMessage `json:"value"`
 	CreatedAt  time.Time       `json:"created_at"`
 	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
 	LastAccess time.Time       `json:"last_access"`
+
+	// Version identifies the write that stored this entry
+	Version uint64 `json:"version,omitempty"`
+
+	// ComputeTime is the XFetch recompute duration, in nanoseconds
+	ComputeTime time.Duration `json:"compute_time,omitempty"`
+
+	// IdleTTL and MaxLifetime configure sliding expiration, in nanoseconds
+	IdleTTL     time.Duration `json:"idle_ttl,omitempty"`
+	MaxLifetime time.Duration `json:"max_lifetime,omitempty"`
+
+	// Per-entry metadata; NoEvict and Cost are informational because
+	// eviction in Redis is governed by the server's maxmemory policy
+	Priority int      `json:"priority,omitempty"`
+	NoEvict  bool     `json:"no_evict,omitempty"`
+	Cost     int64    `json:"cost,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
 }
 
 // New creates a new Redis store with the given configuration
@@ -68,65 +151,138 @@ func New(config *Config) (*Store, error) {
 		client:     config.Client,
 		keyPrefix:  keyPrefix,
 		defaultTTL: config.DefaultTTL,
+		clock:      clock.Real(),
 		ctx:        ctx,
 	}
 
 	return s, nil
 }
 
-// Get retrieves an entry by key
+// Get retrieves an entry by key. Failures to read the entry are reported as
+// misses; use GetE to tell them apart.
 func (s *Store) Get(key string) (*entry.Entry, bool) {
+	e, err := s.GetE(key)
+	return e, err == nil
+}
+
+// GetE retrieves an entry by key, returning store.ErrNotFound for missing
+// keys and wrapping store.ErrBackendUnavailable or store.ErrCorruptEntry when
+// Redis cannot be reached or holds an entry that cannot be decoded
+func (s *Store) GetE(key string) (*entry.Entry, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	redisKey := s.buildKey(key)
-	result := s.client.Get(s.ctx, redisKey)
-	if result.Err() != nil {
-		if result.Err() == redis.Nil {
-			return nil, false // Key not found
-		}
-		return nil, false // Other Redis errors treated as miss
+	data, err := s.client.Get(s.ctx, redisKey).Result()
+	if err == redis.Nil {
+		return nil, store.ErrNotFound
 	}
-
-	data, err := result.Result()
 	if err != nil {
-		return nil, false
+		return nil, backendError(err)
+	}
+
+	// Counters are stored as bare integers so INCRBY can operate on them;
+	// their expiry lives only in the Redis key TTL
+	if counter, ok := s.counterEntry(s.client, redisKey, data); ok {
+		return counter, nil
 	}
 
 	// Deserialize the entry
 	entry, err := s.deserializeEntry([]byte(data))
 	if err != nil {
-		// If deserialization fails, remove the corrupted key
+		// Remove the corrupted key so the next write replaces it
+		s.expectDeletes(redisKey)
 		s.client.Del(s.ctx, redisKey)
-		return nil, false
+		return nil, fmt.Errorf("%w: %q: %w", store.ErrCorruptEntry, key, err)
+	}
+
+	// Sliding entries rely on the Redis key TTL for idle expiry, so the stored
+	// ExpiresAt may be stale; slide it and extend the key TTL with GETEX
+	if entry.IsSliding() {
+		entry.Slide()
+		if !entry.IsExpired() {
+			entry.Touch()
+			_ = s.client.GetEx(s.ctx, redisKey, entry.TTL()).Err() //nolint:errcheck // Best-effort TTL extension
+			return entry, nil
+		}
 	}
 
 	// Check if entry has expired
 	if entry.IsExpired() {
 		// Remove expired entry
+		s.expectDeletes(redisKey)
 		s.client.Del(s.ctx, redisKey)
 
-		// Call cleanup callback if set
+		// Call cleanup callbacks if set
 		if s.cleanupCallback != nil {
 			go s.cleanupCallback(key, entry.Value)
 		}
-		return nil, false
+		if s.removalCallback != nil {
+			go s.removalCallback(key, entry.Value, store.RemovedExpiredOnAccess)
+		}
+		return nil, store.ErrNotFound
 	}
 
-	// Update last access time and save back to Redis
+	// Access time is only updated on the returned copy: writing the entry
+	// back would overwrite concurrent writes and break Update's WATCH
 	entry.Touch()
-	_ = s.saveEntryToRedis(redisKey, entry)
 
-	return entry, true
+	return entry, nil
 }
 
 // Set stores an entry with the given key
-func (s *Store) Set(key string, entry *entry.Entry) error {
+func (s *Store) Set(key string, entry *entry.Entry, opts ...store.SetOption) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	entry.Version = newVersion()
 	redisKey := s.buildKey(key)
-	return s.saveEntryToRedis(redisKey, entry)
+	return s.saveEntryToRedis(redisKey, entry, store.ApplySetOptions(opts))
+}
+
+// Update atomically replaces the entry for key with the result of fn using
+// WATCH/MULTI. fn is called again if the key changes before the write commits.
+func (s *Store) Update(key string, fn store.UpdateFunc) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.client.(watcher)
+	if !ok {
+		return fmt.Errorf("redis client %T does not support WATCH", s.client)
+	}
+
+	redisKey := s.buildKey(key)
+	txf := func(tx *redis.Tx) error {
+		var current *entry.Entry
+		data, err := tx.Get(s.ctx, redisKey).Result()
+		switch {
+		case err == nil:
+			if e, live := s.liveEntry(tx, redisKey, data); live {
+				current = e
+			}
+		case err != redis.Nil:
+			return backendError(err)
+		}
+
+		e, err := fn(current)
+		if err != nil || e == nil {
+			return err
+		}
+		e.Version = newVersion()
+
+		_, err = tx.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+			return s.writeEntry(pipe, redisKey, e, store.SetOptions{})
+		})
+		return backendError(err)
+	}
+
+	for i := 0; i < maxUpdateRetries; i++ {
+		err := w.Watch(s.ctx, txf, redisKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("update of key %q failed after %d attempts: %w", key, maxUpdateRetries, redis.TxFailedErr)
 }
 
 // Delete removes an entry by key
@@ -135,7 +291,8 @@ func (s *Store) Delete(key string) error {
 	defer s.mu.Unlock()
 
 	redisKey := s.buildKey(key)
-	return s.client.Del(s.ctx, redisKey).Err()
+	s.expectDeletes(redisKey)
+	return backendError(s.client.Del(s.ctx, redisKey).Err())
 }
 
 // Keys returns all keys currently in the store
@@ -162,8 +319,14 @@ func (s *Store) Keys() []string {
 			continue
 		}
 
-		// Check if the entry is valid (not expired)
-		if _, found := s.Get(cacheKey); found {
+		// Check if the entry is valid (not expired) without sliding it: Keys
+		// runs on every write via the key count and must not keep idle
+		// entries alive
+		data, err := s.client.Get(s.ctx, redisKey).Result()
+		if err != nil {
+			continue
+		}
+		if _, live := s.liveEntry(s.client, redisKey, data); live {
 			cacheKeys = append(cacheKeys, cacheKey)
 		}
 	}
@@ -184,26 +347,27 @@ func (s *Store) Clear() error {
 	pattern := s.buildKey("*")
 	result := s.client.Keys(s.ctx, pattern)
 	if result.Err() != nil {
-		return result.Err()
+		return backendError(result.Err())
 	}
 
 	keys, err := result.Result()
 	if err != nil {
-		return err
+		return backendError(err)
 	}
 
 	if len(keys) > 0 {
-		return s.client.Del(s.ctx, keys...).Err()
+		s.expectDeletes(keys...)
+		return backendError(s.client.Del(s.ctx, keys...).Err())
 	}
 
 	return nil
 }
 
-// Close closes the store and cleans up resources
+// Close ends the keyspace subscription, if any. The keys are left in Redis,
+// where other processes sharing the prefix may still use them, and the client
+// is left open for its owner to close. Close may be called more than once.
 func (s *Store) Close() error {
-	// Redis client cleanup is handled externally
-	// We just clear our data
-	return s.Clear()
+	return s.stopKeyspace()
 }
 
 // SetEvictCallback sets the callback for evictions (not applicable for Redis)
@@ -220,6 +384,25 @@ func (s *Store) SetCleanupCallback(callback store.EvictCallback) {
 	s.cleanupCallback = callback
 }
 
+// SetRemovalCallback sets the callback for removed entries. Expired entries
+// found on read are always reported. Keys Redis expires or evicts on its own,
+// and keys other clients delete, are only reported while WatchKeyspace is
+// active, and without their values.
+func (s *Store) SetRemovalCallback(callback store.RemovalCallback) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removalCallback = callback
+}
+
+// SetClock sets the clock that entries read from Redis measure their expiry
+// by (the system clock if c is nil). Redis still expires keys by its own
+// clock. It must be called before the store is used.
+func (s *Store) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = clock.Or(c)
+}
+
 // Cleanup removes expired entries (Redis handles TTL automatically)
 // This method is provided for interface compatibility but is less useful with Redis
 func (s *Store) Cleanup() int {
@@ -241,6 +424,117 @@ func (s *Store) extractKey(redisKey string) string {
 	return strings.TrimPrefix(redisKey, s.keyPrefix)
 }
 
+// IncrBy atomically adds delta to the counter at key with INCRBY. A missing
+// key is first created as zero with ttl (SET NX PX) in the same transaction,
+// so the TTL is only applied on creation.
+func (s *Store) IncrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	redisKey := s.buildKey(key)
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(s.ctx, func(pipe redis.Pipeliner) error {
+		// A zero ttl creates the counter without expiry, as in the memory store
+		pipe.SetArgs(s.ctx, redisKey, 0, redis.SetArgs{Mode: "NX", TTL: max(ttl, 0)})
+		incr = pipe.IncrBy(s.ctx, redisKey, delta)
+		return nil
+	})
+	// SET NX reports redis.Nil when the counter already exists
+	if err != nil && err != redis.Nil {
+		if incr != nil && incr.Err() != nil {
+			return 0, counterError(incr.Err())
+		}
+		return 0, backendError(err)
+	}
+
+	if err := incr.Err(); err != nil {
+		return 0, counterError(err)
+	}
+	return incr.Val(), nil
+}
+
+// counterEntry decodes a bare integer written by IncrBy, taking its expiry
+// from the key TTL
+func (s *Store) counterEntry(c redis.Cmdable, redisKey, data string) (*entry.Entry, bool) {
+	n, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	ttl := c.PTTL(s.ctx, redisKey).Val()
+	return entry.NewWithClock(n, max(ttl, 0), s.clock), true
+}
+
+// liveEntry decodes data read from redisKey and reports whether it is still
+// live, without touching the key. Sliding entries take their expiry from the
+// key TTL, since GETEX extends it on access without rewriting the entry.
+func (s *Store) liveEntry(c redis.Cmdable, redisKey, data string) (*entry.Entry, bool) {
+	if counter, ok := s.counterEntry(c, redisKey, data); ok {
+		return counter, true
+	}
+	e, err := s.deserializeEntry([]byte(data))
+	if err != nil {
+		return nil, false
+	}
+	if e.IsSliding() {
+		if ttl := c.PTTL(s.ctx, redisKey).Val(); ttl > 0 {
+			e.UpdateExpiry(ttl)
+		}
+	}
+	return e, !e.IsExpired()
+}
+
+// counterError maps INCRBY failures onto the store's counter errors
+func counterError(err error) error {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "not an integer"):
+		return fmt.Errorf("%w: %v", store.ErrNotInteger, err)
+	case strings.Contains(msg, "overflow"):
+		return fmt.Errorf("%w: %v", store.ErrOverflow, err)
+	default:
+		return backendError(err)
+	}
+}
+
+// backendError wraps errors talking to Redis in store.ErrBackendUnavailable.
+// Error replies from the server (including redis.Nil) are returned as they
+// are, since Redis was reachable and answered.
+func backendError(err error) error {
+	var reply redis.Error
+	if err == nil || errors.As(err, &reply) || errors.Is(err, store.ErrBackendUnavailable) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", store.ErrBackendUnavailable, err)
+}
+
+// AcquireLease takes the lease for key for ttl if nobody holds it
+func (s *Store) AcquireLease(key string, ttl time.Duration) (int64, bool, error) {
+	result, err := acquireLeaseScript.Run(s.ctx, s.client, []string{leaseKey(s.buildKey(key))}, ttl.Milliseconds()).Text()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, backendError(err)
+	}
+
+	token, err := strconv.ParseInt(result, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid lease token %q: %w", result, err)
+	}
+	return token, true, nil
+}
+
+// ReleaseLease gives up the lease if it is still held with token
+func (s *Store) ReleaseLease(key string, token int64) error {
+	return backendError(releaseLeaseScript.Run(s.ctx, s.client, []string{leaseKey(s.buildKey(key))}, token).Err())
+}
+
+// leaseKey returns the key of the lease guarding redisKey. The hash tag
+// keeps it in the same cluster slot as the entry for fenced writes.
+func leaseKey(redisKey string) string {
+	return "lease:{" + redisKey + "}"
+}
+
 // serializeEntry converts an entry to JSON for Redis storage
 func (s *Store) serializeEntry(e *entry.Entry) ([]byte, error) {
 	valueBytes, err := json.Marshal(e.Value)
@@ -249,13 +543,21 @@ func (s *Store) serializeEntry(e *entry.Entry) ([]byte, error) {
 	}
 
 	serialized := SerializedEntry{
-		Value:      valueBytes,
-		CreatedAt:  e.CreatedAt,
-		LastAccess: e.AccessedAt,
+		Value:       valueBytes,
+		CreatedAt:   e.CreatedAt,
+		LastAccess:  e.AccessedAt,
+		Version:     e.Version,
+		ComputeTime: e.ComputeTime,
+		IdleTTL:     e.IdleTTL,
+		MaxLifetime: e.MaxLifetime,
+		Priority:    e.Priority,
+		NoEvict:     e.NoEvict,
+		Cost:        e.Cost,
+		Tags:        e.Tags,
 	}
 
 	if e.HasExpiry() {
-		serialized.ExpiresAt = e.ExpiresAt
+		serialized.ExpiresAt = e.Expiry()
 	}
 
 	return json.Marshal(serialized)
@@ -274,18 +576,24 @@ func (s *Store) deserializeEntry(data []byte) (*entry.Entry, error) {
 	}
 
 	// Create a new entry with current time, then manually set the fields
-	var e *entry.Entry
+	var ttl time.Duration
 	if serialized.ExpiresAt != nil {
-		ttl := serialized.ExpiresAt.Sub(serialized.CreatedAt)
-		e = entry.New(value, ttl)
-	} else {
-		e = entry.NewWithoutTTL(value)
+		ttl = serialized.ExpiresAt.Sub(serialized.CreatedAt)
 	}
+	e := entry.NewWithClock(value, ttl, s.clock)
 
 	// Manually restore the timestamps by direct field access
 	// Note: This requires the Entry fields to be exported
 	e.CreatedAt = serialized.CreatedAt
 	e.AccessedAt = serialized.LastAccess
+	e.Version = serialized.Version
+	e.ComputeTime = serialized.ComputeTime
+	e.IdleTTL = serialized.IdleTTL
+	e.MaxLifetime = serialized.MaxLifetime
+	e.Priority = serialized.Priority
+	e.NoEvict = serialized.NoEvict
+	e.Cost = serialized.Cost
+	e.Tags = serialized.Tags
 	if serialized.ExpiresAt != nil {
 		e.ExpiresAt = serialized.ExpiresAt
 	}
@@ -293,8 +601,14 @@ func (s *Store) deserializeEntry(data []byte) (*entry.Entry, error) {
 	return e, nil
 }
 
-// saveEntryToRedis saves an entry to Redis with appropriate TTL
-func (s *Store) saveEntryToRedis(redisKey string, e *entry.Entry) error {
+// saveEntryToRedis saves an entry to Redis with appropriate TTL.
+// Write conditions map to SET NX / SET XX so they are evaluated atomically.
+func (s *Store) saveEntryToRedis(redisKey string, e *entry.Entry, opts store.SetOptions) error {
+	return s.writeEntry(s.client, redisKey, e, opts)
+}
+
+// writeEntry issues the commands that store e on c, which may be a pipeline
+func (s *Store) writeEntry(c redis.Cmdable, redisKey string, e *entry.Entry, opts store.SetOptions) error {
 	data, err := s.serializeEntry(e)
 	if err != nil {
 		return err
@@ -306,7 +620,8 @@ func (s *Store) saveEntryToRedis(redisKey string, e *entry.Entry) error {
 		remaining := e.TTL()
 		if remaining <= 0 {
 			// Entry has already expired
-			return s.client.Del(s.ctx, redisKey).Err()
+			s.expectDeletes(redisKey)
+			return backendError(c.Del(s.ctx, redisKey).Err())
 		}
 		redisTTL = remaining
 	} else if s.defaultTTL > 0 {
@@ -314,14 +629,53 @@ func (s *Store) saveEntryToRedis(redisKey string, e *entry.Entry) error {
 		redisTTL = s.defaultTTL
 	}
 
-	if redisTTL > 0 {
-		return s.client.SetEx(s.ctx, redisKey, string(data), redisTTL).Err()
+	if opts.LeaseToken != 0 {
+		stored, err := fencedSetScript.Run(s.ctx, c, []string{redisKey, leaseKey(redisKey)},
+			opts.LeaseToken, string(data), redisTTL.Milliseconds()).Int()
+		if err != nil {
+			return backendError(err)
+		}
+		if stored == 0 {
+			return store.ErrNotStored
+		}
+		return nil
+	}
+
+	var mode string
+	switch opts.Condition {
+	case store.SetIfAbsent:
+		mode = "NX"
+	case store.SetIfPresent:
+		mode = "XX"
 	}
-	return s.client.Set(s.ctx, redisKey, string(data), 0).Err()
+
+	if mode != "" {
+		err := c.SetArgs(s.ctx, redisKey, string(data), redis.SetArgs{Mode: mode, TTL: redisTTL}).Err()
+		if err == redis.Nil {
+			return store.ErrNotStored
+		}
+		return backendError(err)
+	}
+
+	// SET with a TTL uses PX for sub-second TTLs, where SETEX would round
+	// them up to a second
+	return backendError(c.Set(s.ctx, redisKey, string(data), redisTTL).Err())
+}
+
+// newVersion returns a random non-zero entry version. A plain SET does not
+// read the previous entry, so versions are unique rather than sequential.
+func newVersion() uint64 {
+	return rand.Uint64() | 1
 }
 
 // Ensure Store implements the required interfaces
 var (
-	_ store.Store    = (*Store)(nil)
-	_ store.TTLStore = (*Store)(nil)
+	_ store.Store        = (*Store)(nil)
+	_ store.TTLStore     = (*Store)(nil)
+	_ store.RemovalStore = (*Store)(nil)
+	_ store.ClockStore   = (*Store)(nil)
+	_ store.ErrorStore   = (*Store)(nil)
+	_ store.AtomicStore  = (*Store)(nil)
+	_ store.CounterStore = (*Store)(nil)
+	_ store.LeaseStore   = (*Store)(nil)
 )
@@ -41,6 +41,30 @@ type Stats interface {
 	HitRate() float64
 }
 
+// EvictionReasonStats is implemented by stats that break removed entries down
+// by reason. Exporters label eviction counters with the reasons it reports.
+type EvictionReasonStats interface {
+	// EvictionsByReason returns removal counts keyed by reason label
+	EvictionsByReason() map[string]int64
+}
+
+// evictionsByReason returns the eviction counts to export by reason label.
+// Stats without a breakdown report all evictions as capacity evictions.
+func evictionsByReason(stats Stats) map[string]int64 {
+	if reasons, ok := stats.(EvictionReasonStats); ok {
+		return reasons.EvictionsByReason()
+	}
+	return map[string]int64{"capacity": stats.Evictions()}
+}
+
+// ErrorStats is implemented by stats that count failed store operations.
+// Exporters report the counts on the CacheErrorsTotal counter, labelled by
+// operation.
+type ErrorStats interface {
+	// ErrorsByOperation returns failure counts keyed by operation
+	ErrorsByOperation() map[string]int64
+}
+
 // Operation represents different cache operations for metrics
 type Operation string
 
@@ -75,6 +99,8 @@ type MetricNames struct {
 	CacheInvalidationsTotal string
 	CacheOperationsTotal    string
 	CacheErrorsTotal        string
+	CacheWarmKeysTotal      string
+	CacheWriteFailuresTotal string
 
 	// Histograms
 	CacheOperationDuration string
@@ -85,6 +111,7 @@ type MetricNames struct {
 	CacheKeysCount        string
 	CacheInFlightRequests string
 	CacheHitRate          string
+	CacheWriteQueueDepth  string
 }
 
 // DefaultMetricNames returns the default metric names with proper namespacing
@@ -96,12 +123,15 @@ func DefaultMetricNames() MetricNames {
 		CacheInvalidationsTotal: "obcache_invalidations_total",
 		CacheOperationsTotal:    "obcache_operations_total",
 		CacheErrorsTotal:        "obcache_errors_total",
+		CacheWarmKeysTotal:      "obcache_warm_keys_total",
+		CacheWriteFailuresTotal: "obcache_write_failures_total",
 		CacheOperationDuration:  "obcache_operation_duration_seconds",
 		CacheKeySize:            "obcache_key_size_bytes",
 		CacheValueSize:          "obcache_value_size_bytes",
 		CacheKeysCount:          "obcache_keys_count",
 		CacheInFlightRequests:   "obcache_inflight_requests",
 		CacheHitRate:            "obcache_hit_rate",
+		CacheWriteQueueDepth:    "obcache_write_queue_depth",
 	}
 }
 
@@ -216,8 +216,17 @@ func (o *OpenTelemetryExporter) ExportStats(stats Stats, labels Labels) error {
 	// Record counters (using the current values as incremental updates)
 	o.hitsCounter.Add(o.ctx, stats.Hits(), metric.WithAttributes(attrs...))
 	o.missesCounter.Add(o.ctx, stats.Misses(), metric.WithAttributes(attrs...))
-	o.evictionsCounter.Add(o.ctx, stats.Evictions(), metric.WithAttributes(attrs...))
+	for reason, count := range evictionsByReason(stats) {
+		reasonAttrs := append(attrs[:len(attrs):len(attrs)], attribute.String("reason", reason))
+		o.evictionsCounter.Add(o.ctx, count, metric.WithAttributes(reasonAttrs...))
+	}
 	o.invalidationsCounter.Add(o.ctx, stats.Invalidations(), metric.WithAttributes(attrs...))
+	if errorStats, ok := stats.(ErrorStats); ok {
+		for operation, count := range errorStats.ErrorsByOperation() {
+			opAttrs := append(attrs[:len(attrs):len(attrs)], attribute.String("operation", operation))
+			o.errorsCounter.Add(o.ctx, count, metric.WithAttributes(opAttrs...))
+		}
+	}
 
 	// Record gauges
 	o.keysGauge.Record(o.ctx, stats.KeyCount(), metric.WithAttributes(attrs...))
@@ -197,12 +197,26 @@ func (p *PrometheusExporter) ExportStats(stats Stats, labels Labels) error {
 	p.invalidationsTotal.With(baseLabels).Add(float64(stats.Invalidations()))
 
 	// For evictions, we need to add the reason label
-	evictionLabels := make(prometheus.Labels)
-	for k, v := range baseLabels {
-		evictionLabels[k] = v
+	for reason, count := range evictionsByReason(stats) {
+		evictionLabels := make(prometheus.Labels)
+		for k, v := range baseLabels {
+			evictionLabels[k] = v
+		}
+		evictionLabels["reason"] = reason
+		p.evictionsTotal.With(evictionLabels).Add(float64(count))
+	}
+
+	// Errors are labelled by the operation that failed
+	if errorStats, ok := stats.(ErrorStats); ok {
+		for operation, count := range errorStats.ErrorsByOperation() {
+			errorLabels := make(prometheus.Labels)
+			for k, v := range baseLabels {
+				errorLabels[k] = v
+			}
+			errorLabels["operation"] = operation
+			p.errorsTotal.With(errorLabels).Add(float64(count))
+		}
 	}
-	evictionLabels["reason"] = "capacity" // Default reason for stats export
-	p.evictionsTotal.With(evictionLabels).Add(float64(stats.Evictions()))
 
 	// Update gauges
 	p.keysCount.With(baseLabels).Set(float64(stats.KeyCount()))
@@ -0,0 +1,134 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// errVersionMismatch aborts a CompareAndSwap whose expected version is stale
+var errVersionMismatch = errors.New("version mismatch")
+
+// SetNX stores value only if key is missing or expired. It reports whether
+// the value was stored.
+func (c *Cache) SetNX(key string, value any, ttl time.Duration) (bool, error) {
+	err := c.Set(key, value, ttl, WithOnlyIfAbsent())
+	if errors.Is(err, ErrNotStored) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetWithVersion retrieves a value together with its entry version. The
+// version changes on every write to the key and can be passed to
+// CompareAndSwap to detect concurrent modification.
+func (c *Cache) GetWithVersion(key string) (any, uint64, bool) {
+	value, e, found := c.lookup(context.Background(), key, c.config.EarlyExpirationBeta)
+	if !found {
+		return nil, 0, false
+	}
+	return value, e.Version, true
+}
+
+// CompareAndSwap replaces the value for key only if the entry's version still
+// equals version, as returned by GetWithVersion. The entry keeps its remaining
+// TTL and per-entry options. It reports whether the swap happened; a missing
+// key never matches. A swapped value is passed to the Writer like a Set.
+func (c *Cache) CompareAndSwap(key string, version uint64, value any) (bool, error) {
+	err := c.update(key, func(existing *entry.Entry) (*entry.Entry, error) {
+		if existing == nil || existing.Version != version {
+			return nil, errVersionMismatch
+		}
+		return c.replacementEntry(existing, value)
+	})
+	if errors.Is(err, errVersionMismatch) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := c.writeBack(WriteOp{Key: key, Value: value}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Update atomically replaces the value for key with the result of fn. fn
+// receives the current value, or nil if the key is missing or expired; if it
+// returns an error the cache is left unchanged and the error is returned.
+// Existing entries keep their remaining TTL and per-entry options, new ones
+// use the default TTL. fn runs while the key is locked and must not call back
+// into the cache; with Redis it may run more than once under contention. The
+// new value is passed to the Writer like a Set.
+func (c *Cache) Update(key string, fn func(old any) (any, error)) error {
+	var updated any
+	err := c.update(key, func(existing *entry.Entry) (*entry.Entry, error) {
+		var old any
+		if existing != nil {
+			value, err := c.decompressValue(existing)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode current value: %w", err)
+			}
+			old = value
+		}
+
+		value, err := fn(old)
+		if err != nil {
+			return nil, err
+		}
+		updated = value
+		return c.replacementEntry(existing, value)
+	})
+	if err != nil {
+		return err
+	}
+	return c.writeBack(WriteOp{Key: key, Value: updated})
+}
+
+// update runs an atomic read-modify-write against the store
+func (c *Cache) update(key string, fn store.UpdateFunc) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	atomicStore, ok := c.store.(store.AtomicStore)
+	if !ok {
+		return fmt.Errorf("store %T does not support atomic updates", c.store)
+	}
+
+	var err error
+	c.lock(func() {
+		err = atomicStore.Update(key, fn)
+		if err == nil {
+			c.updateKeyCount()
+		}
+	})
+
+	return err
+}
+
+// replacementEntry builds the entry that replaces existing with value,
+// carrying over its expiry and per-entry options. A nil existing entry
+// yields a new entry with the default TTL.
+func (c *Cache) replacementEntry(existing *entry.Entry, value any) (*entry.Entry, error) {
+	if existing == nil {
+		ttl := applyJitter(c.config.DefaultTTL, c.config.TTLJitter)
+		e, err := c.createCompressedEntry(value, ttl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create entry: %w", err)
+		}
+		return e, nil
+	}
+
+	e, err := c.createCompressedEntry(value, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create entry: %w", err)
+	}
+
+	e.Inherit(existing)
+	return e, nil
+}
@@ -0,0 +1,111 @@
+package obcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetNX(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	stored, err := cache.SetNX("key", "first", time.Hour)
+	if err != nil || !stored {
+		t.Fatalf("Expected first SetNX to store, got %v, %v", stored, err)
+	}
+
+	stored, err = cache.SetNX("key", "second", time.Hour)
+	if err != nil || stored {
+		t.Fatalf("Expected second SetNX to be rejected, got %v, %v", stored, err)
+	}
+
+	if value, _ := cache.Get("key"); value != "first" {
+		t.Fatalf("Expected first, got %v", value)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	for _, evictionType := range setOptionEvictionTypes {
+		t.Run(string(evictionType), func(t *testing.T) {
+			cache, err := New(NewDefaultConfig().WithEvictionType(evictionType))
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+
+			if swapped, _ := cache.CompareAndSwap("missing", 1, "value"); swapped {
+				t.Fatal("Expected CompareAndSwap on a missing key to fail")
+			}
+
+			_ = cache.Set("key", "v1", time.Hour, WithTags("t"))
+			_, version, found := cache.GetWithVersion("key")
+			if !found || version == 0 {
+				t.Fatalf("Expected a versioned entry, got version %d (found=%v)", version, found)
+			}
+
+			swapped, err := cache.CompareAndSwap("key", version, "v2")
+			if err != nil || !swapped {
+				t.Fatalf("Expected swap to succeed, got %v, %v", swapped, err)
+			}
+
+			// The old version is now stale
+			if swapped, _ := cache.CompareAndSwap("key", version, "v3"); swapped {
+				t.Fatal("Expected swap with stale version to fail")
+			}
+
+			value, newVersion, _ := cache.GetWithVersion("key")
+			if value != "v2" || newVersion == version {
+				t.Fatalf("Expected v2 with a new version, got %v (version %d)", value, newVersion)
+			}
+
+			// Per-entry options and TTL survive the swap
+			if removed, _ := cache.DeleteByTag("t"); removed != 1 {
+				t.Fatalf("Expected swapped entry to keep its tags, removed %d", removed)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	increment := func(old any) (any, error) {
+		if old == nil {
+			return 1, nil
+		}
+		return old.(int) + 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cache.Update("counter", increment); err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := cache.Get("counter"); value != 50 {
+		t.Fatalf("Expected 50 after concurrent updates, got %v", value)
+	}
+
+	errBoom := errors.New("boom")
+	err = cache.Update("counter", func(old any) (any, error) {
+		return nil, errBoom
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("Expected update error to propagate, got %v", err)
+	}
+	if value, _ := cache.Get("counter"); value != 50 {
+		t.Fatalf("Expected failed update to leave value unchanged, got %v", value)
+	}
+}
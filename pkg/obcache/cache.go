@@ -2,17 +2,23 @@ package obcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/vnykmshr/obcache-go/internal/clock"
 	"github.com/vnykmshr/obcache-go/internal/entry"
 	"github.com/vnykmshr/obcache-go/internal/eviction"
 	"github.com/vnykmshr/obcache-go/internal/singleflight"
 	"github.com/vnykmshr/obcache-go/internal/store"
+	"github.com/vnykmshr/obcache-go/internal/store/breaker"
 	"github.com/vnykmshr/obcache-go/internal/store/memory"
 	redisstore "github.com/vnykmshr/obcache-go/internal/store/redis"
 	"github.com/vnykmshr/obcache-go/pkg/compression"
@@ -53,6 +59,7 @@ type Cache struct {
 	hooks  *Hooks
 	sf     *singleflight.Group[string, any]
 	mu     sync.RWMutex
+	clock  clock.Clock
 
 	// Compression
 	compressor compression.Compressor
@@ -62,6 +69,12 @@ type Cache struct {
 	metricsLabels   metrics.Labels
 	metricsStop     chan struct{}
 	metricsWg       sync.WaitGroup
+
+	// writeBehind queues writes for the Writer in WriteBehind mode
+	writeBehind *writeBehindQueue
+
+	// closed is set once Close has been called
+	closed atomic.Bool
 }
 
 // New creates a new Cache instance with the given configuration
@@ -70,21 +83,13 @@ func New(config *Config) (*Cache, error) {
 		config = NewDefaultConfig()
 	}
 
-	// Create the appropriate store based on configuration
-	var cacheStore store.Store
-	var err error
-
-	switch config.StoreType {
-	case StoreTypeMemory:
-		cacheStore, err = createMemoryStore(config)
-	case StoreTypeRedis:
-		cacheStore, err = createRedisStore(config)
-	default:
-		return nil, fmt.Errorf("unsupported store type: %v", config.StoreType)
-	}
-
-	if err != nil {
-		return nil, err
+	// Use the configured store or create one based on configuration
+	cacheStore := config.Store
+	if cacheStore == nil {
+		var err error
+		if cacheStore, err = NewStore(config); err != nil {
+			return nil, err
+		}
 	}
 
 	cache := &Cache{
@@ -93,6 +98,7 @@ func New(config *Config) (*Cache, error) {
 		stats:  &Stats{},
 		hooks:  config.Hooks,
 		sf:     &singleflight.Group[string, any]{},
+		clock:  clock.Or(config.Clock),
 	}
 
 	// Initialize compression if configured
@@ -105,61 +111,143 @@ func New(config *Config) (*Cache, error) {
 		return nil, fmt.Errorf("failed to initialize metrics: %w", err)
 	}
 
-	// Set up store callbacks for statistics and hooks
+	if config.Writer != nil && config.WriteMode == WriteBehind {
+		cache.writeBehind = newWriteBehindQueue(cache, config.Writer, config.WriteBehind)
+	}
+
+	if circuitStore, ok := cacheStore.(store.CircuitStore); ok {
+		circuitStore.SetCircuitCallback(cache.circuitChanged)
+		circuitStore.SetRejectCallback(cache.stats.incShortCircuits)
+	}
+
+	// Set up store callbacks for statistics and hooks. Stores that report
+	// removal reasons need nothing else; for others, evictions are assumed
+	// to be for capacity and cleanups for expiry
+	if removalStore, ok := cacheStore.(store.RemovalStore); ok {
+		removalStore.SetRemovalCallback(func(key string, value any, reason store.RemovalReason) {
+			cache.removed(key, value, evictReasonFor(reason))
+		})
+		return cache, nil
+	}
+
 	if lruStore, ok := cacheStore.(store.LRUStore); ok {
 		lruStore.SetEvictCallback(func(key string, value any) {
-			cache.stats.incEvictions()
-			if cache.hooks != nil {
-				// Use EvictReasonCapacity for strategy-based evictions
-				// Check if this is the new strategy store
-				if _, isStrategyStore := cacheStore.(*memory.StrategyStore); isStrategyStore {
-					cache.hooks.invokeOnEvict(key, value, EvictReasonCapacity)
-				} else {
-					cache.hooks.invokeOnEvict(key, value, EvictReasonLRU)
-				}
-			}
+			cache.removed(key, value, EvictReasonCapacity)
 		})
 	}
 
 	if ttlStore, ok := cacheStore.(store.TTLStore); ok {
 		ttlStore.SetCleanupCallback(func(key string, value any) {
-			cache.stats.incEvictions()
-			if cache.hooks != nil {
-				cache.hooks.invokeOnEvict(key, value, EvictReasonTTL)
-			}
+			cache.removed(key, value, EvictReasonTTL)
 		})
 	}
 
 	return cache, nil
 }
 
+// removed records an entry leaving the store, invoking OnEvict hooks for
+// evictions and OnInvalidate hooks for entries deleted by other clients
+func (c *Cache) removed(key string, value any, reason EvictReason) {
+	c.stats.incEvictionsFor(reason)
+	if reason == EvictReasonDeletedExternally {
+		c.stats.incInvalidations()
+		if c.hooks != nil {
+			c.hooks.invokeOnInvalidateWithCtx(context.Background(), key, nil)
+		}
+		return
+	}
+	if !reason.IsEviction() {
+		return
+	}
+
+	c.stats.incEvictions()
+	if c.hooks != nil {
+		c.hooks.invokeOnEvict(key, value, reason)
+	}
+}
+
+// evictReasonFor translates a store removal reason
+func evictReasonFor(reason store.RemovalReason) EvictReason {
+	switch reason {
+	case store.RemovedCapacity:
+		return EvictReasonCapacity
+	case store.RemovedCost:
+		return EvictReasonCost
+	case store.RemovedExpiredOnAccess:
+		return EvictReasonExpiredOnAccess
+	case store.RemovedExpiredSweep:
+		return EvictReasonTTL
+	case store.RemovedReplaced:
+		return EvictReasonReplaced
+	case store.RemovedDeleted:
+		return EvictReasonDeleted
+	case store.RemovedDeletedExternally:
+		return EvictReasonDeletedExternally
+	default:
+		return EvictReasonCleared
+	}
+}
+
 // NewSimple creates a simple cache with minimal configuration
 // This is perfect for most use cases where you just need basic caching
 func NewSimple(maxEntries int, defaultTTL time.Duration) (*Cache, error) {
 	return New(NewSimpleConfig(maxEntries, defaultTTL))
 }
 
+// Store is the storage backend a Cache keeps its entries in. Entries are an
+// internal type, so stores are created with NewStore, optionally wrapped (see
+// obcachetest.FaultStore), and passed to New through Config.Store.
+type Store = store.Store
+
+// NewStore creates the store New would create for config: a memory store or
+// a Redis store depending on config.StoreType
+func NewStore(config *Config) (Store, error) {
+	switch config.StoreType {
+	case StoreTypeMemory:
+		return createMemoryStore(config)
+	case StoreTypeRedis:
+		return createRedisStore(config)
+	default:
+		return nil, fmt.Errorf("unsupported store type: %v", config.StoreType)
+	}
+}
+
 // createMemoryStore creates a memory-based store
 func createMemoryStore(config *Config) (store.Store, error) {
+	var memStore memoryStore
+	var err error
+
 	// Use pluggable eviction strategy if EvictionType is set to non-LRU
 	// For backward compatibility, fall back to the original implementation for LRU
 	if config.EvictionType != "" && config.EvictionType != eviction.LRU {
-		evictionConfig := eviction.Config{
+		memStore, err = memory.NewWithStrategy(eviction.Config{
 			Type:     config.EvictionType,
 			Capacity: config.MaxEntries,
-		}
+		})
+	} else {
+		// Default to original LRU implementation for compatibility
+		memStore, err = memory.New(config.MaxEntries)
+	}
 
-		if config.CleanupInterval > 0 {
-			return memory.NewWithStrategyAndCleanup(evictionConfig, config.CleanupInterval)
-		}
-		return memory.NewWithStrategy(evictionConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	// Default to original LRU implementation for compatibility
+	memStore.SetMaxCost(config.MaxCost)
+	memStore.SetCleanupBatchSize(config.CleanupBatchSize)
+	memStore.SetClock(config.Clock)
 	if config.CleanupInterval > 0 {
-		return memory.NewWithCleanup(config.MaxEntries, config.CleanupInterval)
+		memStore.StartCleanup(config.CleanupInterval)
 	}
-	return memory.New(config.MaxEntries)
+	return memStore, nil
+}
+
+// memoryStore is implemented by both memory store implementations
+type memoryStore interface {
+	store.CostStore
+	store.BatchCleanupStore
+	store.ClockStore
+	StartCleanup(interval time.Duration)
 }
 
 // createRedisStore creates a Redis-based store
@@ -194,75 +282,334 @@ func createRedisStore(config *Config) (store.Store, error) {
 		redisConfig.Client = client
 	}
 
-	return redisstore.New(redisConfig)
+	redisStore, err := redisstore.New(redisConfig)
+	if err != nil {
+		return nil, err
+	}
+	redisStore.SetClock(config.Clock)
+
+	if config.Redis.KeyspaceNotifications {
+		if err := redisStore.WatchKeyspace(); err != nil {
+			return nil, fmt.Errorf("failed to subscribe to keyspace notifications: %w", err)
+		}
+	}
+
+	if config.Redis.CircuitBreaker == nil {
+		return redisStore, nil
+	}
+	return createCircuitBreaker(redisStore, config)
 }
 
-// Get retrieves a value from the cache by key
+// createCircuitBreaker guards a store with a circuit breaker and, if
+// configured, an in-memory fallback tier
+func createCircuitBreaker(inner store.Store, config *Config) (store.Store, error) {
+	cb := config.Redis.CircuitBreaker
+	breakerConfig := &breaker.Config{
+		Inner:            inner,
+		FailureThreshold: cb.FailureThreshold,
+		LatencyThreshold: cb.LatencyThreshold,
+		OpenTimeout:      cb.OpenTimeout,
+		HalfOpenProbes:   cb.HalfOpenProbes,
+	}
+
+	if cb.FallbackEntries > 0 {
+		fallback, err := memory.New(cb.FallbackEntries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fallback store: %w", err)
+		}
+		breakerConfig.Fallback = fallback
+	}
+
+	breakerStore, err := breaker.New(breakerConfig)
+	if err != nil {
+		return nil, err
+	}
+	breakerStore.SetClock(config.Clock)
+	return breakerStore, nil
+}
+
+// Get retrieves a value from the cache by key. With a Loader configured,
+// misses are loaded through it (see Fetch); a failed load reports false.
 func (c *Cache) Get(key string) (any, bool) {
+	if c.closed.Load() {
+		return nil, false
+	}
+
+	start := time.Now()
+	defer func() {
+		c.recordCacheOperation(metrics.OperationGet, time.Since(start))
+	}()
+
+	value, _, found := c.lookup(context.Background(), key, c.config.EarlyExpirationBeta)
+	if !found && c.config.Loader != nil {
+		loaded, err := c.readThrough(context.Background(), key)
+		return loaded, err == nil
+	}
+	return value, found
+}
+
+// GetE retrieves a value from the cache by key like Get, but reports why no
+// value was returned: ErrNotFound on a miss, or an error wrapping
+// ErrBackendUnavailable or ErrCorruptEntry if the store could not be read.
+// With a Loader configured, misses are loaded through it and loader errors
+// are returned.
+func (c *Cache) GetE(key string) (any, error) {
 	start := time.Now()
 	defer func() {
 		c.recordCacheOperation(metrics.OperationGet, time.Since(start))
 	}()
 
+	value, _, err := c.lookupE(context.Background(), key, c.config.EarlyExpirationBeta)
+	if errors.Is(err, ErrNotFound) && c.config.Loader != nil {
+		return c.readThrough(context.Background(), key)
+	}
+	return value, err
+}
+
+// lookup retrieves a value along with its entry metadata, recording a hit or miss.
+// Entries selected for probabilistic early expiration with beta are reported as misses.
+// Store errors are recorded as errors and reported as not found.
+func (c *Cache) lookup(ctx context.Context, key string, beta float64) (any, *entry.Entry, bool) {
+	value, e, err := c.lookupE(ctx, key, beta)
+	return value, e, err == nil
+}
+
+// lookupE is lookup returning ErrNotFound for misses and the store or
+// decompression error for failed reads
+func (c *Cache) lookupE(ctx context.Context, key string, beta float64) (any, *entry.Entry, error) {
+	if c.closed.Load() {
+		return nil, nil, ErrClosed
+	}
+
 	var result any
-	var found bool
-	ctx := context.Background()
+	var resultEntry *entry.Entry
+	var lookupErr error
 
 	c.rlock(func() {
-		entry, ok := c.store.Get(key)
-		if !ok {
-			c.miss(ctx, key)
-			return
+		e, err := store.GetE(c.store, key)
+		if err == nil && e.ExpiresEarly(beta) {
+			err = ErrNotFound
 		}
 
-		value, err := c.decompressValue(entry)
-		if err != nil {
-			c.miss(ctx, key)
-			return
+		var value any
+		if err == nil {
+			if value, err = c.decompressValue(e); err != nil {
+				err = fmt.Errorf("%w: %q: %w", ErrCorruptEntry, key, err)
+			}
 		}
 
-		c.hit(ctx, key, value)
-		result = value
-		found = true
+		switch {
+		case err == nil:
+			c.hit(ctx, key, value)
+			result = value
+			resultEntry = e
+		case errors.Is(err, ErrNotFound):
+			c.miss(ctx, key)
+		default:
+			c.failed(key, metrics.OperationGet, err)
+		}
+		lookupErr = err
 	})
 
-	return result, found
+	return result, resultEntry, lookupErr
 }
 
-// Set stores a value in the cache with the specified key and TTL
-func (c *Cache) Set(key string, value any, ttl time.Duration) error {
+// failed records a store operation on key that returned err
+func (c *Cache) failed(key string, op metrics.Operation, err error) {
+	c.stats.incErrors(op)
+	if c.hooks != nil {
+		c.hooks.invokeOnError(key, err)
+	}
+}
+
+// Set stores a value in the cache with the specified key and TTL.
+// Options adjust how the individual entry is stored, e.g. WithSliding.
+// With a Writer configured, the value is also written to the backing store
+// (see WriteMode); if that fails the entry is removed from the cache again.
+func (c *Cache) Set(key string, value any, ttl time.Duration, options ...SetOption) error {
 	start := time.Now()
 	defer func() {
 		c.recordCacheOperation(metrics.OperationSet, time.Since(start))
 	}()
 
+	opts := &setOptions{
+		ttl:    ttl,
+		jitter: c.config.TTLJitter,
+	}
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	if err := c.set(key, value, opts); err != nil {
+		return err
+	}
+	return c.writeBack(WriteOp{Key: key, Value: value})
+}
+
+// setOptions carries per-call write parameters for set
+type setOptions struct {
+	ttl         time.Duration
+	jitter      float64
+	computeTime time.Duration
+	sliding     bool
+	maxLifetime time.Duration
+	priority    int
+	noEvict     bool
+	cost        int64
+	tags        []string
+	condition   store.SetCondition
+	leaseToken  int64
+}
+
+// SetOption is a function that configures a single Set call
+type SetOption func(*setOptions)
+
+// WithSliding enables sliding expiration for the entry: the TTL passed to Set
+// becomes an idle timeout that restarts on every successful Get. A positive
+// maxLifetime caps the total lifetime of the entry regardless of access.
+func WithSliding(maxLifetime time.Duration) SetOption {
+	return func(opts *setOptions) {
+		opts.sliding = true
+		opts.maxLifetime = maxLifetime
+	}
+}
+
+// WithPriority sets the entry's eviction priority. When the memory store must
+// evict, lower-priority entries go first; the default priority is 0.
+func WithPriority(priority int) SetOption {
+	return func(opts *setOptions) {
+		opts.priority = priority
+	}
+}
+
+// WithNoEvict pins the entry so capacity and cost limits never evict it.
+// The entry still expires according to its TTL. Pinned entries do not count
+// towards MaxEntries. Only applies to memory store.
+func WithNoEvict() SetOption {
+	return func(opts *setOptions) {
+		opts.noEvict = true
+	}
+}
+
+// WithCost sets the entry's weight against Config.MaxCost (default 1)
+func WithCost(cost int64) SetOption {
+	return func(opts *setOptions) {
+		opts.cost = cost
+	}
+}
+
+// WithTags labels the entry so it can be removed with DeleteByTag
+func WithTags(tags ...string) SetOption {
+	return func(opts *setOptions) {
+		opts.tags = append(opts.tags, tags...)
+	}
+}
+
+// WithOnlyIfAbsent makes Set write only if the key is missing or expired.
+// Set returns ErrNotStored otherwise.
+func WithOnlyIfAbsent() SetOption {
+	return func(opts *setOptions) {
+		opts.condition = store.SetIfAbsent
+	}
+}
+
+// WithOnlyIfPresent makes Set write only if the key already exists.
+// Set returns ErrNotStored otherwise.
+func WithOnlyIfPresent() SetOption {
+	return func(opts *setOptions) {
+		opts.condition = store.SetIfPresent
+	}
+}
+
+// set stores a value using the given write parameters
+func (c *Cache) set(key string, value any, opts *setOptions) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
+	ttl := opts.ttl
 	if ttl <= 0 {
 		ttl = c.config.DefaultTTL
 	}
+	ttl = applyJitter(ttl, opts.jitter)
 
 	entry, err := c.createCompressedEntry(value, ttl)
 	if err != nil {
 		return fmt.Errorf("failed to create entry: %w", err)
 	}
+	entry.ComputeTime = opts.computeTime
+	if opts.sliding {
+		entry.IdleTTL = ttl
+		entry.MaxLifetime = opts.maxLifetime
+	}
+	entry.Priority = opts.priority
+	entry.NoEvict = opts.noEvict
+	entry.Cost = opts.cost
+	entry.Tags = opts.tags
+
+	var storeOpts []store.SetOption
+	switch opts.condition {
+	case store.SetIfAbsent:
+		storeOpts = append(storeOpts, store.IfAbsent())
+	case store.SetIfPresent:
+		storeOpts = append(storeOpts, store.IfPresent())
+	}
+	if opts.leaseToken != 0 {
+		storeOpts = append(storeOpts, store.WithLease(opts.leaseToken))
+	}
 
 	var setErr error
 	c.lock(func() {
-		setErr = c.store.Set(key, entry)
+		setErr = c.store.Set(key, entry, storeOpts...)
 		if setErr == nil {
 			c.updateKeyCount()
 		}
 	})
 
+	if setErr != nil && !errors.Is(setErr, ErrNotStored) {
+		c.failed(key, metrics.OperationSet, setErr)
+	}
 	return setErr
 }
 
+// applyJitter subtracts a random portion, up to fraction of ttl, from ttl
+func applyJitter(ttl time.Duration, fraction float64) time.Duration {
+	if ttl <= 0 || fraction <= 0 {
+		return ttl
+	}
+	if fraction > 1 {
+		fraction = 1
+	}
+
+	jittered := ttl - time.Duration(rand.Float64()*fraction*float64(ttl))
+	if jittered <= 0 {
+		return time.Nanosecond
+	}
+	return jittered
+}
+
 // Put stores a value using the default TTL
 func (c *Cache) Put(key string, value any) error {
 	return c.Set(key, value, c.config.DefaultTTL)
 }
 
-// Delete removes a key from the cache
+// Delete removes a key from the cache, and from the backing store if a
+// Writer is configured
 func (c *Cache) Delete(key string) error {
+	if err := c.Invalidate(key); err != nil {
+		return err
+	}
+	return c.writeBack(WriteOp{Key: key, Delete: true})
+}
+
+// Invalidate removes a key from the cache only. Unlike Delete it never calls
+// the Writer, so the backing store keeps the value and the next read loads it
+// again.
+func (c *Cache) Invalidate(key string) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+
 	var err error
 	ctx := context.Background()
 
@@ -277,11 +624,89 @@ func (c *Cache) Delete(key string) error {
 		}
 	})
 
+	if err != nil {
+		c.failed(key, metrics.OperationDelete, err)
+	}
 	return err
 }
 
+// DeleteByTag removes every entry carrying the given tag and returns how many
+// entries were removed
+func (c *Cache) DeleteByTag(tag string) (int, error) {
+	return c.deleteWhere(func(_ string, entry *entry.Entry) bool {
+		return entry.HasTag(tag)
+	})
+}
+
+// DeleteByPrefix removes every entry whose key starts with prefix and returns
+// how many entries were removed
+func (c *Cache) DeleteByPrefix(prefix string) (int, error) {
+	return c.deleteWhere(func(key string, _ *entry.Entry) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// InvalidateNamespace removes every result cached by functions wrapped with
+// the given namespace (see WithNamespace and FuncNamespace) and returns how
+// many entries were removed
+func (c *Cache) InvalidateNamespace(namespace string) (int, error) {
+	return c.DeleteByPrefix(namespace + namespaceSeparator)
+}
+
+// InvalidateFunc removes every result cached for fn by Wrap under its default
+// namespace. Pass the original function, not the wrapped one; functions
+// wrapped with WithNamespace must be invalidated with InvalidateNamespace.
+func (c *Cache) InvalidateFunc(fn any) (int, error) {
+	namespace := FuncNamespace(fn)
+	if namespace == "" {
+		return 0, fmt.Errorf("obcache.InvalidateFunc: %T is not a function", fn)
+	}
+	return c.InvalidateNamespace(namespace)
+}
+
+// deleteWhere removes every live entry matching fn and returns how many
+// entries were removed
+func (c *Cache) deleteWhere(match func(key string, entry *entry.Entry) bool) (int, error) {
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	ctx := context.Background()
+	removed := 0
+
+	var err error
+	c.lock(func() {
+		for _, key := range c.store.Keys() {
+			entry, found := c.store.Get(key)
+			if !found || !match(key, entry) {
+				continue
+			}
+
+			if err = c.store.Delete(key); err != nil {
+				return
+			}
+			removed++
+			c.stats.incInvalidations()
+			if c.hooks != nil {
+				c.hooks.invokeOnInvalidateWithCtx(ctx, key, nil)
+			}
+		}
+		c.updateKeyCount()
+	})
+
+	return removed, err
+}
+
 // Clear removes all entries from the cache
 func (c *Cache) Clear() error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	return c.clear()
+}
+
+// clear removes all entries from the store, reporting them as invalidated
+func (c *Cache) clear() error {
 	var err error
 	ctx := context.Background()
 
@@ -310,6 +735,10 @@ func (c *Cache) Stats() *Stats {
 
 // Keys returns all current cache keys
 func (c *Cache) Keys() []string {
+	if c.closed.Load() {
+		return nil
+	}
+
 	var keys []string
 	c.rlock(func() {
 		keys = c.store.Keys()
@@ -319,6 +748,10 @@ func (c *Cache) Keys() []string {
 
 // Len returns the current number of entries in the cache
 func (c *Cache) Len() int {
+	if c.closed.Load() {
+		return 0
+	}
+
 	var length int
 	c.rlock(func() {
 		length = c.store.Len()
@@ -328,6 +761,10 @@ func (c *Cache) Len() int {
 
 // Has checks if a key exists in the cache
 func (c *Cache) Has(key string) bool {
+	if c.closed.Load() {
+		return false
+	}
+
 	var exists bool
 	c.rlock(func() {
 		entry, found := c.store.Get(key)
@@ -338,6 +775,10 @@ func (c *Cache) Has(key string) bool {
 
 // TTL returns the remaining TTL for a key
 func (c *Cache) TTL(key string) (time.Duration, bool) {
+	if c.closed.Load() {
+		return 0, false
+	}
+
 	var ttl time.Duration
 	var found bool
 	c.rlock(func() {
@@ -350,9 +791,25 @@ func (c *Cache) TTL(key string) (time.Duration, bool) {
 	return ttl, found
 }
 
-// Close closes the cache and cleans up resources
+// Close stops background work and releases the store's resources. In
+// WriteBehind mode it first waits for every queued write to be flushed.
+// Entries are left in the store, since a Redis store may be shared with other
+// processes, unless Config.ClearOnClose is set. Close may be called more than
+// once; later operations on the cache return ErrClosed.
 func (c *Cache) Close() error {
+	if !c.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	if c.writeBehind != nil {
+		c.writeBehind.close()
+	}
+
 	var err error
+	if c.config.ClearOnClose {
+		err = c.clear()
+	}
+
 	c.lock(func() {
 		if c.metricsStop != nil {
 			close(c.metricsStop)
@@ -361,18 +818,24 @@ func (c *Cache) Close() error {
 		if c.metricsExporter != nil {
 			c.metricsExporter.Close()
 		}
-		err = c.store.Close()
+		err = errors.Join(err, c.store.Close())
 	})
 	return err
 }
 
 // Cleanup removes expired entries and returns count removed
 func (c *Cache) Cleanup() int {
+	if c.closed.Load() {
+		return 0
+	}
+
 	var removed int
 	c.lock(func() {
 		if store, ok := c.store.(store.TTLStore); ok {
 			removed = store.Cleanup()
-			c.updateKeyCount()
+			if removed > 0 {
+				c.updateKeyCount()
+			}
 		}
 	})
 	return removed
@@ -394,12 +857,7 @@ func (c *Cache) getKeyGenFunc() KeyGenFunc {
 
 // createCompressedEntry creates a cache entry with compression if applicable
 func (c *Cache) createCompressedEntry(value any, ttl time.Duration) (*entry.Entry, error) {
-	var cacheEntry *entry.Entry
-	if ttl > 0 {
-		cacheEntry = entry.New(nil, ttl) // We'll set the value after compression
-	} else {
-		cacheEntry = entry.NewWithoutTTL(nil)
-	}
+	cacheEntry := entry.NewWithClock(nil, ttl, c.clock) // We'll set the value after compression
 
 	// Only try compression if it's enabled
 	if c.config.Compression != nil && c.config.Compression.Enabled {
@@ -445,6 +903,10 @@ func (c *Cache) decompressValue(entry *entry.Entry) (any, error) {
 		// Value was stored with compression logic (might be compressed or serialized)
 		data, ok := entry.Value.([]byte)
 		if !ok {
+			// Native counters are stored as plain integers
+			if _, isCounter := entry.Value.(int64); isCounter && !entry.IsCompressed {
+				return entry.Value, nil
+			}
 			return nil, fmt.Errorf("serialized value is not []byte")
 		}
 
@@ -548,12 +1010,12 @@ func (c *Cache) initializeMetrics() error {
 func (c *Cache) metricsReporter() {
 	defer c.metricsWg.Done()
 
-	ticker := time.NewTicker(c.config.Metrics.ReportingInterval)
+	ticker := c.clock.NewTicker(c.config.Metrics.ReportingInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.C():
 			c.exportCurrentStats()
 		case <-c.metricsStop:
 			// Final stats export before shutting down
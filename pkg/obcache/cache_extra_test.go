@@ -1,6 +1,8 @@
 package obcache
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -190,16 +192,70 @@ func TestCacheClose(t *testing.T) {
 		t.Fatalf("Failed to create cache: %v", err)
 	}
 
-	// Add an entry
 	_ = cache.Set("key1", "value1", time.Hour)
 
-	// Close should not error
-	cache.Close()
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Expected a second Close to be a no-op, got %v", err)
+	}
 
-	// After close, operations should still work but cleanup stops
-	_ = cache.Set("key2", "value2", time.Hour)
-	if !cache.Has("key2") {
-		t.Fatal("Cache should still work after Close")
+	if err := cache.Set("key2", "value2", time.Hour); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected Set to return ErrClosed, got %v", err)
+	}
+	if _, err := cache.GetE("key1"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected GetE to return ErrClosed, got %v", err)
+	}
+	if err := cache.Delete("key1"); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected Delete to return ErrClosed, got %v", err)
+	}
+	if _, err := cache.IncrBy("counter", 1, time.Hour); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected IncrBy to return ErrClosed, got %v", err)
+	}
+	if _, err := cache.GetOrLoad(context.Background(), "key1", func(context.Context) (any, error) {
+		return "loaded", nil
+	}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected GetOrLoad to return ErrClosed, got %v", err)
+	}
+	if _, found := cache.Get("key1"); found || cache.Has("key1") || cache.Len() != 0 {
+		t.Fatal("Expected a closed cache to report no entries")
+	}
+
+	// Wrapped functions call through without caching
+	calls := 0
+	wrapped := Wrap(cache, func(n int) int {
+		calls++
+		return n * 2
+	})
+	if wrapped(2) != 4 || wrapped(2) != 4 || calls != 2 {
+		t.Fatalf("Expected wrapped calls to bypass the closed cache, got %d calls", calls)
+	}
+}
+
+func TestCacheCloseKeepsEntries(t *testing.T) {
+	for _, clearOnClose := range []bool{false, true} {
+		shared, err := NewStore(NewDefaultConfig())
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+
+		cache, err := New(NewDefaultConfig().WithStore(shared).WithClearOnClose(clearOnClose))
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		_ = cache.Set("key", "value", time.Hour)
+		if err := cache.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+
+		want := 1
+		if clearOnClose {
+			want = 0
+		}
+		if got := shared.Len(); got != want {
+			t.Fatalf("Expected %d entries after Close with ClearOnClose=%v, got %d", want, clearOnClose, got)
+		}
 	}
 }
 
@@ -0,0 +1,81 @@
+package obcache
+
+import (
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// CircuitState is the state of the circuit breaker guarding a Redis store
+type CircuitState = store.CircuitState
+
+const (
+	// CircuitClosed passes operations through to Redis
+	CircuitClosed = store.CircuitClosed
+
+	// CircuitOpen short-circuits operations without contacting Redis
+	CircuitOpen = store.CircuitOpen
+
+	// CircuitHalfOpen lets probe operations through to find out whether
+	// Redis has recovered
+	CircuitHalfOpen = store.CircuitHalfOpen
+)
+
+// CircuitBreakerConfig configures the circuit breaker around a Redis store.
+// While the breaker is open, reads are misses (so a Loader loads them) or are
+// served from a local fallback tier, writes go to the fallback tier or are
+// dropped, and Delete and Clear fail with ErrCircuitOpen.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed operations that
+	// opens the breaker
+	// Default: 5
+	FailureThreshold int
+
+	// LatencyThreshold makes operations slower than it count as failures,
+	// so a slow Redis trips the breaker before its client timeouts do
+	// Default: 0 (only errors count)
+	LatencyThreshold time.Duration
+
+	// OpenTimeout is how long the breaker stays open before probing Redis
+	// Default: 5 seconds
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of probe operations that must succeed
+	// for the breaker to close; one failed probe opens it again
+	// Default: 1
+	HalfOpenProbes int
+
+	// FallbackEntries sizes an in-memory fallback tier that serves reads and
+	// writes while the breaker is open. It is cleared when the breaker closes.
+	// Default: 0 (no fallback tier)
+	FallbackEntries int
+}
+
+// NewDefaultCircuitBreakerConfig returns a CircuitBreakerConfig with sensible defaults
+func NewDefaultCircuitBreakerConfig() *CircuitBreakerConfig {
+	return &CircuitBreakerConfig{
+		FailureThreshold: 5,
+		OpenTimeout:      5 * time.Second,
+		HalfOpenProbes:   1,
+	}
+}
+
+// CircuitState returns the state of the Redis circuit breaker.
+// Caches without a circuit breaker are always CircuitClosed.
+func (c *Cache) CircuitState() CircuitState {
+	if circuitStore, ok := c.store.(store.CircuitStore); ok {
+		return circuitStore.CircuitState()
+	}
+	return CircuitClosed
+}
+
+// circuitChanged records a circuit breaker state change
+func (c *Cache) circuitChanged(from, to CircuitState) {
+	c.stats.setCircuitState(to)
+	if to == CircuitOpen {
+		c.stats.incCircuitOpens()
+	}
+	if c.hooks != nil {
+		c.hooks.invokeOnCircuitStateChange(from, to)
+	}
+}
@@ -0,0 +1,129 @@
+package obcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// newUnreachableRedisCache creates a Redis-backed cache whose server refuses
+// connections, guarded by a circuit breaker
+func newUnreachableRedisCache(t *testing.T, cb *CircuitBreakerConfig) *Cache {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{
+		Addr:        "127.0.0.1:1",
+		MaxRetries:  -1,
+		DialTimeout: 100 * time.Millisecond,
+	})
+	t.Cleanup(func() {
+		_ = client.Close() //nolint:errcheck // Best-effort cleanup
+	})
+
+	config := NewRedisConfigWithClient(client)
+	config.Redis.CircuitBreaker = cb
+	cache, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	return cache
+}
+
+func TestCircuitBreakerOpensOnUnreachableRedis(t *testing.T) {
+	var transitions []string
+	cb := NewDefaultCircuitBreakerConfig()
+	cb.FailureThreshold = 2
+	cb.OpenTimeout = time.Hour
+
+	cache := newUnreachableRedisCache(t, cb)
+	cache.hooks.AddOnCircuitStateChange(func(from, to CircuitState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetE("key"); !errors.Is(err, ErrBackendUnavailable) {
+			t.Fatalf("Expected backend error, got %v", err)
+		}
+	}
+	if cache.CircuitState() != CircuitOpen {
+		t.Fatalf("Expected circuit to be open, got %s", cache.CircuitState())
+	}
+
+	// Open circuit reads are misses and writes are dropped without errors
+	if _, err := cache.GetE("key"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected open circuit to report a miss, got %v", err)
+	}
+	if err := cache.Set("key", "value", time.Minute); err != nil {
+		t.Fatalf("Expected open circuit to drop the write, got %v", err)
+	}
+	if err := cache.Delete("key"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected open circuit to reject Delete, got %v", err)
+	}
+
+	stats := cache.Stats()
+	if stats.CircuitState() != CircuitOpen || stats.CircuitOpens() != 1 {
+		t.Fatalf("Expected stats to show one opening, got state %s and %d opens", stats.CircuitState(), stats.CircuitOpens())
+	}
+	if stats.ShortCircuits() != 3 {
+		t.Fatalf("Expected 3 short-circuited operations, got %d", stats.ShortCircuits())
+	}
+	if stats.ErrorsByOperation()["get"] != 2 {
+		t.Fatalf("Expected only the 2 failed reads to count as errors, got %v", stats.ErrorsByOperation())
+	}
+	if len(transitions) != 1 || transitions[0] != "closed->open" {
+		t.Fatalf("Expected a closed->open hook call, got %v", transitions)
+	}
+
+	rec := httptest.NewRecorder()
+	cache.DebugHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var response DebugResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode debug response: %v", err)
+	}
+	if response.Stats.Circuit == nil || response.Stats.Circuit.State != "open" || response.Stats.Circuit.ShortCircuits != 3 {
+		t.Fatalf("Expected the debug handler to report the open circuit, got %+v", response.Stats.Circuit)
+	}
+}
+
+func TestCircuitBreakerFallbackServesLoadedValues(t *testing.T) {
+	cb := NewDefaultCircuitBreakerConfig()
+	cb.FailureThreshold = 1
+	cb.OpenTimeout = time.Hour
+	cb.FallbackEntries = 10
+
+	cache := newUnreachableRedisCache(t, cb)
+	loads := 0
+	cache.config.Loader = LoaderFunc(func(_ context.Context, key string) (any, error) {
+		loads++
+		return "loaded:" + key, nil
+	})
+
+	// The first read fails against Redis, opening the circuit; the loaded
+	// value is then cached in the fallback tier
+	for i := 0; i < 3; i++ {
+		value, found := cache.Get("key")
+		if !found || value != "loaded:key" {
+			t.Fatalf("Expected the loaded value, got %v, %v", value, found)
+		}
+	}
+	if loads != 1 {
+		t.Fatalf("Expected the fallback tier to serve repeated reads, got %d loads", loads)
+	}
+}
+
+func TestCircuitStateWithoutBreaker(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	if cache.CircuitState() != CircuitClosed {
+		t.Fatalf("Expected caches without a breaker to report closed, got %s", cache.CircuitState())
+	}
+}
@@ -0,0 +1,123 @@
+package obcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/eviction"
+)
+
+// cleanupConfigs covers both memory store implementations
+func cleanupConfigs() map[string]*Config {
+	return map[string]*Config{
+		"lru": NewDefaultConfig().WithCleanupInterval(0).WithCleanupBatchSize(3),
+		"lfu": NewDefaultConfig().WithCleanupInterval(0).WithCleanupBatchSize(3).WithEvictionType(eviction.LFU),
+	}
+}
+
+func TestCleanupRemovesOnlyExpiredEntries(t *testing.T) {
+	for name, config := range cleanupConfigs() {
+		t.Run(name, func(t *testing.T) {
+			evicted := 0
+			hooks := &Hooks{}
+			hooks.AddOnEvict(func(string, any, EvictReason) {
+				evicted++
+			})
+			cache, _ := New(config.WithHooks(hooks))
+
+			// More expired entries than fit in one batch
+			for i := 0; i < 10; i++ {
+				_ = cache.Set(fmt.Sprintf("short-%d", i), i, 10*time.Millisecond)
+			}
+			_ = cache.Set("pinned", 1, 10*time.Millisecond, WithNoEvict())
+			_ = cache.Set("long", 1, time.Hour)
+			_ = cache.Set("forever", 1, 0, WithSliding(0))
+
+			// Rewritten with a longer TTL before expiring
+			_ = cache.Set("rewritten", 1, 10*time.Millisecond)
+			_ = cache.Set("rewritten", 2, time.Hour)
+
+			time.Sleep(20 * time.Millisecond)
+
+			if removed := cache.Cleanup(); removed != 11 {
+				t.Fatalf("Expected 11 expired entries removed, got %d", removed)
+			}
+			if evicted != 11 {
+				t.Fatalf("Expected 11 TTL eviction hooks, got %d", evicted)
+			}
+			for _, key := range []string{"long", "forever", "rewritten"} {
+				if !cache.Has(key) {
+					t.Fatalf("Expected %s to survive cleanup", key)
+				}
+			}
+			if removed := cache.Cleanup(); removed != 0 {
+				t.Fatalf("Expected nothing left to clean up, got %d", removed)
+			}
+		})
+	}
+}
+
+func TestCleanupKeepsSlidEntries(t *testing.T) {
+	for name, config := range cleanupConfigs() {
+		t.Run(name, func(t *testing.T) {
+			cache, _ := New(config)
+
+			_ = cache.Set("session", "data", 40*time.Millisecond, WithSliding(0))
+
+			// Accessing the entry pushes its expiry past its original one
+			time.Sleep(25 * time.Millisecond)
+			if _, found := cache.Get("session"); !found {
+				t.Fatal("Expected sliding entry to be present")
+			}
+			time.Sleep(25 * time.Millisecond)
+
+			if removed := cache.Cleanup(); removed != 0 {
+				t.Fatalf("Expected slid entry to survive cleanup, removed %d", removed)
+			}
+			if !cache.Has("session") {
+				t.Fatal("Expected slid entry to remain")
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			if removed := cache.Cleanup(); removed != 1 {
+				t.Fatalf("Expected idle entry to be cleaned up, removed %d", removed)
+			}
+		})
+	}
+}
+
+func TestCleanupAfterDeleteAndClear(t *testing.T) {
+	for name, config := range cleanupConfigs() {
+		t.Run(name, func(t *testing.T) {
+			cache, _ := New(config)
+
+			_ = cache.Set("deleted", 1, 10*time.Millisecond)
+			_ = cache.Set("cleared", 1, 10*time.Millisecond)
+			_ = cache.Delete("deleted")
+			_ = cache.Clear()
+			_ = cache.Set("fresh", 1, time.Hour)
+
+			time.Sleep(20 * time.Millisecond)
+
+			if removed := cache.Cleanup(); removed != 0 {
+				t.Fatalf("Expected removed entries to have left the index, removed %d", removed)
+			}
+			if !cache.Has("fresh") {
+				t.Fatal("Expected fresh entry to remain")
+			}
+		})
+	}
+}
+
+func BenchmarkCleanupFewExpired(b *testing.B) {
+	cache, _ := New(NewDefaultConfig().WithMaxEntries(10000).WithCleanupInterval(0))
+	for i := 0; i < 10000; i++ {
+		_ = cache.Set(fmt.Sprintf("key-%d", i), i, time.Hour)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Cleanup()
+	}
+}
@@ -0,0 +1,17 @@
+package obcache
+
+import "github.com/vnykmshr/obcache-go/internal/clock"
+
+// Clock is the time source the cache consults for entry expiry, cleanup,
+// metrics reporting and write-behind flushing. Tests can configure a fake
+// clock such as obcachetest.FakeClock to observe expiration without sleeping.
+type Clock = clock.Clock
+
+// Ticker delivers the ticks of a Clock, like time.Ticker
+type Ticker = clock.Ticker
+
+// SystemClock returns the Clock backed by the time package, which is used
+// when Config.Clock is nil
+func SystemClock() Clock {
+	return clock.Real()
+}
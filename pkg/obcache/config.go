@@ -41,6 +41,18 @@ type RedisConfig struct {
 	// KeyPrefix is prepended to all cache keys
 	// Default: "obcache:"
 	KeyPrefix string
+
+	// KeyspaceNotifications subscribes to Redis keyspace notifications for
+	// keys under KeyPrefix, so keys Redis expires or evicts reach OnEvict and
+	// keys deleted by other clients reach OnInvalidate. The server must have
+	// notify-keyspace-events set to include "Exeg".
+	// Default: false (only expiry noticed on read is reported)
+	KeyspaceNotifications bool
+
+	// CircuitBreaker guards the store with a circuit breaker, so an
+	// unhealthy Redis degrades to misses instead of blocking every call
+	// If nil, no circuit breaker is used
+	CircuitBreaker *CircuitBreakerConfig
 }
 
 // MetricsConfig holds metrics exporter configuration
@@ -73,15 +85,42 @@ type Config struct {
 	// Default: 1000
 	MaxEntries int
 
+	// MaxCost sets a total cost budget for the memory store. Each entry
+	// weighs its WithCost value (1 by default) and entries are evicted to keep
+	// the summed cost within the budget.
+	// Only applies to memory store
+	// Default: 0 (no cost budget)
+	MaxCost int64
+
 	// DefaultTTL sets the default time-to-live for cache entries
 	// Default: 5 minutes
 	DefaultTTL time.Duration
 
+	// TTLJitter randomises entry lifetimes so that entries written together do
+	// not all expire at the same instant. It is the maximum fraction of the TTL
+	// (between 0 and 1) subtracted at random from each entry's TTL.
+	// Default: 0 (disabled)
+	TTLJitter float64
+
+	// EarlyExpirationBeta enables XFetch-style probabilistic early expiration
+	// on read for entries that record their compute time (such as results of
+	// wrapped functions). Larger values recompute earlier; 1.0 is a good start.
+	// Default: 0 (disabled)
+	EarlyExpirationBeta float64
+
 	// CleanupInterval sets how often expired entries are cleaned up
 	// Only applies to memory store (Redis handles TTL automatically)
 	// Default: 1 minute
 	CleanupInterval time.Duration
 
+	// CleanupBatchSize limits how many expired entries a cleanup pass removes
+	// before briefly releasing the store lock. Cleanup only visits expired
+	// entries, so its cost grows with the number of expirations rather than
+	// the size of the cache.
+	// Only applies to memory store
+	// Default: 1024
+	CleanupBatchSize int
+
 	// EvictionType sets the eviction strategy for memory store
 	// Only applies to memory store
 	// Default: LRU
@@ -105,6 +144,40 @@ type Config struct {
 	// Compression holds compression configuration
 	// If nil, compression will be disabled
 	Compression *compression.Config
+
+	// Loader loads values for keys missing from the cache (read-through).
+	// Get and Fetch call it on a miss and cache the result for DefaultTTL.
+	// If nil, misses are returned to the caller.
+	Loader Loader
+
+	// Writer receives the writes made with Set, Put and Delete so the backing
+	// store stays in sync with the cache. See WriteMode for when it is called.
+	// If nil, writes only affect the cache.
+	Writer Writer
+
+	// WriteMode selects whether Writer is called synchronously or from a
+	// background queue
+	// Default: WriteThrough
+	WriteMode WriteMode
+
+	// WriteBehind configures the queue used in WriteBehind mode
+	// If nil, NewDefaultWriteBehindConfig is used
+	WriteBehind *WriteBehindConfig
+
+	// Clock is the time source for entry expiry, cleanup, metrics reporting
+	// and write-behind flushing. Redis still expires keys by its own clock.
+	// If nil, the system clock is used
+	Clock Clock
+
+	// Store is used instead of creating a store from StoreType. The cache
+	// takes ownership of it and closes it on Close.
+	// If nil, NewStore creates the store
+	Store Store
+
+	// ClearOnClose removes every entry from the store when the cache is
+	// closed. Leave it off for Redis stores shared with other processes.
+	// Default: false (Close leaves entries in place)
+	ClearOnClose bool
 }
 
 // KeyGenFunc defines a function that generates cache keys from function arguments
@@ -171,18 +244,54 @@ func (c *Config) WithMaxEntries(maxEntries int) *Config {
 	return c
 }
 
+// WithMaxCost sets the total cost budget for the memory store
+func (c *Config) WithMaxCost(maxCost int64) *Config {
+	c.MaxCost = maxCost
+	return c
+}
+
 // WithDefaultTTL sets the default TTL for cache entries
 func (c *Config) WithDefaultTTL(ttl time.Duration) *Config {
 	c.DefaultTTL = ttl
 	return c
 }
 
+// WithTTLJitter sets the maximum fraction of the TTL randomly subtracted from each entry
+func (c *Config) WithTTLJitter(fraction float64) *Config {
+	c.TTLJitter = fraction
+	return c
+}
+
+// WithEarlyExpiration enables probabilistic early expiration with the given beta
+func (c *Config) WithEarlyExpiration(beta float64) *Config {
+	c.EarlyExpirationBeta = beta
+	return c
+}
+
 // WithCleanupInterval sets the cleanup interval for expired entries
 func (c *Config) WithCleanupInterval(interval time.Duration) *Config {
 	c.CleanupInterval = interval
 	return c
 }
 
+// WithCleanupBatchSize sets how many expired entries are removed per cleanup batch
+func (c *Config) WithCleanupBatchSize(size int) *Config {
+	c.CleanupBatchSize = size
+	return c
+}
+
+// WithClock sets the time source used for expiry and periodic work
+func (c *Config) WithClock(clock Clock) *Config {
+	c.Clock = clock
+	return c
+}
+
+// WithStore sets the store the cache uses instead of creating one
+func (c *Config) WithStore(s Store) *Config {
+	c.Store = s
+	return c
+}
+
 // WithKeyGenFunc sets a custom key generation function
 func (c *Config) WithKeyGenFunc(fn KeyGenFunc) *Config {
 	c.KeyGenFunc = fn
@@ -221,3 +330,32 @@ func (c *Config) WithEvictionType(evictionType eviction.EvictionType) *Config {
 	c.EvictionType = evictionType
 	return c
 }
+
+// WithLoader sets the loader used for read-through on cache misses
+func (c *Config) WithLoader(loader Loader) *Config {
+	c.Loader = loader
+	return c
+}
+
+// WithWriter sets a writer that is called synchronously on every write
+// (write-through)
+func (c *Config) WithWriter(writer Writer) *Config {
+	c.Writer = writer
+	c.WriteMode = WriteThrough
+	return c
+}
+
+// WithClearOnClose sets whether Close removes every entry from the store
+func (c *Config) WithClearOnClose(clear bool) *Config {
+	c.ClearOnClose = clear
+	return c
+}
+
+// WithWriteBehind sets a writer that is called asynchronously from a batched,
+// retrying queue (write-behind). A nil config uses the defaults.
+func (c *Config) WithWriteBehind(writer Writer, config *WriteBehindConfig) *Config {
+	c.Writer = writer
+	c.WriteMode = WriteBehind
+	c.WriteBehind = config
+	return c
+}
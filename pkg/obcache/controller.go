@@ -0,0 +1,197 @@
+package obcache
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Controller manages the cached results of one wrapped function. It derives
+// keys exactly as the wrapped function does (same key function, namespace,
+// context stripping, ignored and variadic arguments), so callers never have
+// to recompute them by hand.
+//
+// Methods taking args expect the arguments of a call to the wrapped function,
+// including a leading context if it has one. Arguments of numeric types are
+// converted to the parameter type, and a variadic parameter accepts either the
+// individual values or a single slice.
+type Controller struct {
+	cache   *Cache
+	fnValue reflect.Value
+	fnType  reflect.Type
+	opts    *WrapOptions
+}
+
+// WrapWithController is like Wrap but also returns a Controller for the
+// wrapped function's cached results
+func WrapWithController[T any](cache *Cache, fn T, options ...WrapOption) (T, *Controller) {
+	opts := newWrapOptions(cache, options)
+	wrapped := wrapFunction(cache, fn, opts)
+
+	return wrapped, &Controller{
+		cache:   cache,
+		fnValue: reflect.ValueOf(fn),
+		fnType:  reflect.TypeOf(fn),
+		opts:    opts,
+	}
+}
+
+// Key returns the cache key the wrapped function uses for a call with args
+func (c *Controller) Key(args ...any) (string, error) {
+	values, err := c.callArgs(args)
+	if err != nil {
+		return "", err
+	}
+	return wrappedKey(c.opts, c.fnType, values), nil
+}
+
+// Invalidate removes the cached result of a call with args
+func (c *Controller) Invalidate(args ...any) error {
+	key, err := c.Key(args...)
+	if err != nil {
+		return err
+	}
+	return c.cache.Invalidate(key)
+}
+
+// Refresh calls the wrapped function with args now and caches the result in
+// place of any existing one, as a regular miss would. It returns the
+// function's error, if any; the error is only cached with WithErrorCaching.
+// Refresh does not join calls already in flight for the same arguments, so
+// its result reflects state at the time of the call.
+func (c *Controller) Refresh(args ...any) error {
+	values, err := c.callArgs(args)
+	if err != nil {
+		return err
+	}
+	key := wrappedKey(c.opts, c.fnType, values)
+
+	ctx, cancel := loadContext(contextArg(c.fnType, values), c.opts)
+	defer cancel()
+
+	defer c.cache.countPanic()
+	fn := wrappedLoadFunc(c.fnValue, c.fnType, values, hasErrorReturn(c.fnType))
+	if _, err := c.cache.loadAndStore(ctx, key, c.opts, fn, 0); err != nil {
+		c.cache.stats.incRefreshFailures()
+		return err
+	}
+
+	c.cache.stats.incRefreshes()
+	return nil
+}
+
+// Peek returns the results a call with args would get from the cache, without
+// calling the function, recording hits or misses, or triggering refreshes.
+// The results are in the function's order, including a cached error. It
+// reports false if nothing is cached or args do not match the function.
+func (c *Controller) Peek(args ...any) ([]any, bool) {
+	values, err := c.callArgs(args)
+	if err != nil {
+		return nil, false
+	}
+
+	cached, found := c.cache.peek(wrappedKey(c.opts, c.fnType, values))
+	if !found {
+		return nil, false
+	}
+
+	results := convertCachedValue(cached, c.fnType, hasErrorReturn(c.fnType))
+	out := make([]any, len(results))
+	for i, result := range results {
+		if result.IsValid() {
+			out[i] = result.Interface()
+		}
+	}
+	return out, true
+}
+
+// InvalidateAll removes every cached result of the wrapped function and
+// returns how many entries were removed. It requires a namespace, which Wrap
+// assigns unless WithKeyFunc is used without WithNamespace.
+func (c *Controller) InvalidateAll() (int, error) {
+	if c.opts.Namespace == "" {
+		return 0, fmt.Errorf("obcache: cannot invalidate all results of a function without a namespace")
+	}
+	return c.cache.InvalidateNamespace(c.opts.Namespace)
+}
+
+// callArgs converts args to the values a reflect.MakeFunc wrapper receives
+// for the call, with variadic arguments collected into a slice
+func (c *Controller) callArgs(args []any) ([]reflect.Value, error) {
+	numIn := c.fnType.NumIn()
+	variadic := c.fnType.IsVariadic()
+
+	if (!variadic && len(args) != numIn) || (variadic && len(args) < numIn-1) {
+		return nil, fmt.Errorf("obcache: function takes %d arguments, got %d", numIn, len(args))
+	}
+
+	values := make([]reflect.Value, numIn)
+	for i := 0; i < numIn; i++ {
+		paramType := c.fnType.In(i)
+		if variadic && i == numIn-1 {
+			slice, err := variadicArg(args[i:], paramType)
+			if err != nil {
+				return nil, fmt.Errorf("obcache: argument %d: %w", i, err)
+			}
+			values[i] = slice
+			break
+		}
+
+		value, err := argValue(args[i], paramType)
+		if err != nil {
+			return nil, fmt.Errorf("obcache: argument %d: %w", i, err)
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// variadicArg collects variadic arguments into a slice of sliceType. A single
+// argument that already is such a slice is used as is.
+func variadicArg(args []any, sliceType reflect.Type) (reflect.Value, error) {
+	if len(args) == 1 && args[0] != nil && reflect.TypeOf(args[0]).AssignableTo(sliceType) {
+		return argValue(args[0], sliceType)
+	}
+
+	slice := reflect.MakeSlice(sliceType, len(args), len(args))
+	for i, arg := range args {
+		value, err := argValue(arg, sliceType.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		slice.Index(i).Set(value)
+	}
+	return slice, nil
+}
+
+// argValue converts arg to a value of type t
+func argValue(arg any, t reflect.Type) (reflect.Value, error) {
+	value := reflect.New(t).Elem()
+	if arg == nil {
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			return value, nil
+		}
+		return reflect.Value{}, fmt.Errorf("nil is not a valid %s", t)
+	}
+
+	v := reflect.ValueOf(arg)
+	switch {
+	case v.Type().AssignableTo(t):
+		value.Set(v)
+	case isNumeric(v.Kind()) && isNumeric(t.Kind()):
+		converted := v.Convert(t)
+		if !converted.Convert(v.Type()).Equal(v) {
+			return reflect.Value{}, fmt.Errorf("%v cannot be represented as %s", arg, t)
+		}
+		value.Set(converted)
+	default:
+		return reflect.Value{}, fmt.Errorf("%s is not assignable to %s", v.Type(), t)
+	}
+	return value, nil
+}
+
+// isNumeric reports whether k is an integer or floating-point kind
+func isNumeric(k reflect.Kind) bool {
+	return (k >= reflect.Int && k <= reflect.Uintptr) || k == reflect.Float32 || k == reflect.Float64
+}
@@ -0,0 +1,189 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type controllerUser struct {
+	ID   int64
+	Name string
+}
+
+func TestControllerInvalidate(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	names := map[int64]string{42: "alice", 7: "bob"}
+	calls := 0
+	getUser, ctrl := WrapWithController(cache, func(_ context.Context, id int64) (*controllerUser, error) {
+		calls++
+		return &controllerUser{ID: id, Name: names[id]}, nil
+	})
+
+	ctx := context.Background()
+	getUser(ctx, 42)
+	getUser(ctx, 7)
+
+	// A write happens; drop only the affected result. Untyped constants are
+	// converted to the parameter type.
+	names[42] = "alice smith"
+	if err := ctrl.Invalidate(ctx, 42); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+
+	if u, _ := getUser(ctx, 42); u.Name != "alice smith" {
+		t.Fatalf("Expected fresh result after invalidation, got %q", u.Name)
+	}
+	getUser(ctx, 7)
+	if calls != 3 {
+		t.Fatalf("Expected 3 calls (other result still cached), got %d", calls)
+	}
+}
+
+func TestControllerKeyMatchesWrappedFunction(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	sum, ctrl := WrapWithController(cache, func(_ context.Context, _ string, nums ...int) int {
+		total := 0
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	}, WithIgnoreArgs(1))
+
+	sum(context.Background(), "trace-1", 1, 2, 3)
+
+	// Context and ignored arguments do not matter; variadic arguments may be
+	// passed individually or as a slice
+	for _, args := range [][]any{
+		{context.TODO(), "trace-2", 1, 2, 3},
+		{nil, "", []int{1, 2, 3}},
+	} {
+		key, err := ctrl.Key(args...)
+		if err != nil {
+			t.Fatalf("Key failed: %v", err)
+		}
+		if !cache.Has(key) {
+			t.Fatalf("Expected key %q for %v to match the cached call, have %v", key, args, cache.Keys())
+		}
+	}
+}
+
+func TestControllerPeek(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	errNotFound := errors.New("not found")
+	lookup, ctrl := WrapWithController(cache, func(id int) (string, int, error) {
+		if id < 0 {
+			return "", 0, errNotFound
+		}
+		return "item", id, nil
+	}, WithErrorCaching())
+
+	if _, found := ctrl.Peek(1); found {
+		t.Fatal("Expected nothing cached before the first call")
+	}
+
+	lookup(1)
+	lookup(-1)
+	hits, misses := cache.Stats().Hits(), cache.Stats().Misses()
+
+	results, found := ctrl.Peek(1)
+	if !found || results[0] != "item" || results[1] != 1 || results[2] != nil {
+		t.Fatalf("Expected cached results, got %v (found=%v)", results, found)
+	}
+	results, found = ctrl.Peek(-1)
+	if !found || !errors.Is(results[2].(error), errNotFound) {
+		t.Fatalf("Expected cached error, got %v (found=%v)", results, found)
+	}
+
+	if cache.Stats().Hits() != hits || cache.Stats().Misses() != misses {
+		t.Fatal("Expected Peek not to record hits or misses")
+	}
+	if _, found := ctrl.Peek("wrong type"); found {
+		t.Fatal("Expected Peek with mismatched arguments to report false")
+	}
+}
+
+func TestControllerRefresh(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	version := 1
+	calls := 0
+	load, ctrl := WrapWithController(cache, func(key string) (int, error) {
+		calls++
+		if key == "bad" {
+			return 0, errors.New("failed")
+		}
+		return version, nil
+	})
+
+	load("config")
+	version = 2
+	if err := ctrl.Refresh("config"); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if v, _ := load("config"); v != 2 {
+		t.Fatalf("Expected refreshed value 2, got %d", v)
+	}
+	if calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+
+	if err := ctrl.Refresh("bad"); err == nil {
+		t.Fatal("Expected Refresh to return the function's error")
+	}
+	if cache.Stats().Refreshes() != 1 || cache.Stats().RefreshFailures() != 1 {
+		t.Fatalf("Expected 1 refresh and 1 failure, got %d and %d", cache.Stats().Refreshes(), cache.Stats().RefreshFailures())
+	}
+}
+
+func TestControllerInvalidateAll(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	square, ctrl := WrapWithController(cache, func(n int) int { return n * n })
+	double := Wrap(cache, func(n int) int { return n * 2 })
+	for i := 0; i < 3; i++ {
+		square(i)
+		double(i)
+	}
+
+	removed, err := ctrl.InvalidateAll()
+	if err != nil || removed != 3 {
+		t.Fatalf("Expected 3 entries removed, got %d, %v", removed, err)
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("Expected other function's results to remain, got %d entries", cache.Len())
+	}
+
+	// Without a namespace there is no way to tell the function's entries apart
+	_, custom := WrapWithController(cache, func(n int) int { return n }, WithKeyFunc(SimpleKeyFunc))
+	if _, err := custom.InvalidateAll(); err == nil {
+		t.Fatal("Expected InvalidateAll without a namespace to fail")
+	}
+}
+
+func TestControllerArgumentErrors(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+	_, ctrl := WrapWithController(cache, func(_ int8, _ string) int { return 0 })
+
+	tests := []struct {
+		name string
+		args []any
+	}{
+		{"too few", []any{1}},
+		{"too many", []any{1, "a", 2}},
+		{"wrong type", []any{"a", "a"}},
+		{"nil for value type", []any{nil, "a"}},
+		{"out of range", []any{300, "a"}},
+		{"fractional", []any{1.5, "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ctrl.Invalidate(tt.args...); err == nil {
+				t.Fatal("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package obcache
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// IncrBy atomically adds delta to the integer counter at key and returns the
+// new value. A missing or expired key starts from zero and is created with
+// ttl (0 uses the default TTL); incrementing an existing counter keeps its
+// expiry. Counters are stored natively (INCRBY in Redis) and uncompressed.
+func (c *Cache) IncrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	if c.closed.Load() {
+		return 0, ErrClosed
+	}
+
+	counterStore, ok := c.store.(store.CounterStore)
+	if !ok {
+		return 0, fmt.Errorf("store %T does not support counters", c.store)
+	}
+
+	if ttl <= 0 {
+		ttl = c.config.DefaultTTL
+	}
+
+	var value int64
+	var err error
+	c.lock(func() {
+		value, err = counterStore.IncrBy(key, delta, ttl)
+		if err == nil {
+			c.updateKeyCount()
+		}
+	})
+
+	return value, err
+}
+
+// Incr atomically increments the counter at key by one
+func (c *Cache) Incr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrBy(key, 1, ttl)
+}
+
+// Decr atomically decrements the counter at key by one
+func (c *Cache) Decr(key string, ttl time.Duration) (int64, error) {
+	return c.IncrBy(key, -1, ttl)
+}
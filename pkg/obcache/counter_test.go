@@ -0,0 +1,94 @@
+package obcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/compression"
+)
+
+func TestIncrDecr(t *testing.T) {
+	for _, evictionType := range setOptionEvictionTypes {
+		t.Run(string(evictionType), func(t *testing.T) {
+			cache, err := New(NewDefaultConfig().WithEvictionType(evictionType))
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+
+			if n, err := cache.Incr("hits", time.Hour); err != nil || n != 1 {
+				t.Fatalf("Expected 1 from first Incr, got %d, %v", n, err)
+			}
+			if n, _ := cache.IncrBy("hits", 10, time.Hour); n != 11 {
+				t.Fatalf("Expected 11, got %d", n)
+			}
+			if n, _ := cache.Decr("hits", time.Hour); n != 10 {
+				t.Fatalf("Expected 10, got %d", n)
+			}
+			if value, _ := cache.Get("hits"); value != int64(10) {
+				t.Fatalf("Expected Get to return int64(10), got %v (%T)", value, value)
+			}
+
+			// Plain integer values can be incremented too
+			_ = cache.Set("plain", 5, time.Hour)
+			if n, _ := cache.Incr("plain", time.Hour); n != 6 {
+				t.Fatalf("Expected 6, got %d", n)
+			}
+
+			_ = cache.Set("text", "hello", time.Hour)
+			if _, err := cache.Incr("text", time.Hour); !errors.Is(err, ErrNotInteger) {
+				t.Fatalf("Expected ErrNotInteger, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIncrTTLOnCreate(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	_, _ = cache.Incr("counter", 100*time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+
+	// Incrementing does not extend the TTL set on creation
+	_, _ = cache.Incr("counter", time.Hour)
+	if ttl, _ := cache.TTL("counter"); ttl > 50*time.Millisecond {
+		t.Fatalf("Expected TTL from creation to be kept, got %v", ttl)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if n, _ := cache.Incr("counter", time.Hour); n != 1 {
+		t.Fatalf("Expected expired counter to restart at 1, got %d", n)
+	}
+}
+
+func TestIncrConcurrent(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = cache.Incr("counter", time.Hour)
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := cache.Get("counter"); value != int64(100) {
+		t.Fatalf("Expected 100, got %v", value)
+	}
+}
+
+func TestIncrWithCompression(t *testing.T) {
+	config := NewDefaultConfig().WithCompression(compression.NewDefaultConfig().WithEnabled(true))
+	cache, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	_, _ = cache.IncrBy("counter", 3, time.Hour)
+	if value, found := cache.Get("counter"); !found || value != int64(3) {
+		t.Fatalf("Expected counter to be readable with compression enabled, got %v (found=%v)", value, found)
+	}
+}
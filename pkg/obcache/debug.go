@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/store"
 )
 
 const expiredTTL = "expired"
@@ -16,15 +18,23 @@ type DebugResponse struct {
 
 // DebugStats represents cache statistics in the debug response
 type DebugStats struct {
-	Hits          int64        `json:"hits"`
-	Misses        int64        `json:"misses"`
-	Evictions     int64        `json:"evictions"`
-	Invalidations int64        `json:"invalidations"`
-	KeyCount      int64        `json:"keyCount"`
-	InFlight      int64        `json:"inFlight"`
-	HitRate       float64      `json:"hitRate"`
-	Total         int64        `json:"total"`
-	Config        *DebugConfig `json:"config"`
+	Hits          int64         `json:"hits"`
+	Misses        int64         `json:"misses"`
+	Evictions     int64         `json:"evictions"`
+	Invalidations int64         `json:"invalidations"`
+	KeyCount      int64         `json:"keyCount"`
+	InFlight      int64         `json:"inFlight"`
+	HitRate       float64       `json:"hitRate"`
+	Total         int64         `json:"total"`
+	Circuit       *DebugCircuit `json:"circuit,omitempty"`
+	Config        *DebugConfig  `json:"config"`
+}
+
+// DebugCircuit represents the Redis circuit breaker in the debug response
+type DebugCircuit struct {
+	State         string `json:"state"`
+	Opens         int64  `json:"opens"`
+	ShortCircuits int64  `json:"shortCircuits"`
 }
 
 // DebugConfig represents cache configuration in the debug response
@@ -78,6 +88,14 @@ func (c *Cache) DebugHandler() http.Handler {
 			},
 		}
 
+		if _, ok := c.store.(store.CircuitStore); ok {
+			response.Stats.Circuit = &DebugCircuit{
+				State:         c.CircuitState().String(),
+				Opens:         c.stats.CircuitOpens(),
+				ShortCircuits: c.stats.ShortCircuits(),
+			}
+		}
+
 		// Collect keys if requested
 		if includeKeys {
 			c.mu.RLock()
@@ -89,7 +107,7 @@ func (c *Cache) DebugHandler() http.Handler {
 					debugKey := DebugKey{
 						Key:       key,
 						Value:     entry.Value,
-						ExpiresAt: entry.ExpiresAt,
+						ExpiresAt: entry.Expiry(),
 						CreatedAt: entry.CreatedAt,
 						Age:       formatDuration(entry.Age()),
 					}
@@ -0,0 +1,74 @@
+package obcache
+
+import (
+	"context"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// loadDistributed deduplicates a load across processes sharing a LeaseStore.
+// The lease holder loads the value and stores it with a write fenced on its
+// lease; everyone else polls the store until the value appears or the lease
+// is free again. A crashed holder therefore only delays the others until its
+// lease expires. Unless canFail is set, waiting does not stop when ctx is
+// done, since the caller has no way to report the error.
+func (c *Cache) loadDistributed(ctx context.Context, leases store.LeaseStore, key string, opts *WrapOptions, canFail bool, fn loadFunc) (any, error) {
+	pollCtx := ctx
+	if !canFail {
+		pollCtx = context.WithoutCancel(ctx)
+	}
+
+	for {
+		if value, found := c.peek(key); found {
+			return cachedResult(value)
+		}
+
+		token, acquired, err := leases.AcquireLease(key, opts.DistributedLeaseTTL)
+		if err != nil {
+			// Coordination is best-effort; load locally if leases are unavailable
+			return c.loadAndStore(ctx, key, opts, fn, 0)
+		}
+
+		if acquired {
+			value, err := c.loadAsLeaseHolder(ctx, key, opts, fn, token)
+			_ = leases.ReleaseLease(key, token) //nolint:errcheck // The lease expires on its own
+			return value, err
+		}
+
+		select {
+		case <-pollCtx.Done():
+			return nil, pollCtx.Err()
+		case <-time.After(opts.LeasePollInterval):
+		}
+	}
+}
+
+// loadAsLeaseHolder loads and stores a value while holding the lease
+func (c *Cache) loadAsLeaseHolder(ctx context.Context, key string, opts *WrapOptions, fn loadFunc, token int64) (any, error) {
+	// Another process may have stored the value between our miss and the lease
+	if value, found := c.peek(key); found {
+		return cachedResult(value)
+	}
+	return c.loadAndStore(ctx, key, opts, fn, token)
+}
+
+// peek reads a value from the store without recording a hit or miss
+func (c *Cache) peek(key string) (any, bool) {
+	var value any
+	var found bool
+
+	c.rlock(func() {
+		e, ok := c.store.Get(key)
+		if !ok {
+			return
+		}
+		decoded, err := c.decompressValue(e)
+		if err != nil {
+			return
+		}
+		value, found = decoded, true
+	})
+
+	return value, found
+}
@@ -0,0 +1,220 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+	"github.com/vnykmshr/obcache-go/internal/store/memory"
+)
+
+// leaseTestStore stands in for a Redis store shared by several processes
+type leaseTestStore struct {
+	*memory.Store
+
+	mu     sync.Mutex
+	leases map[string]testLease
+	next   int64
+}
+
+type testLease struct {
+	token   int64
+	expires time.Time
+}
+
+func newLeaseTestStore(t *testing.T) *leaseTestStore {
+	t.Helper()
+	s, err := memory.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return &leaseTestStore{Store: s, leases: make(map[string]testLease)}
+}
+
+func (s *leaseTestStore) AcquireLease(key string, ttl time.Duration) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lease, held := s.leases[key]; held && time.Now().Before(lease.expires) {
+		return 0, false, nil
+	}
+	s.next++
+	s.leases[key] = testLease{token: s.next, expires: time.Now().Add(ttl)}
+	return s.next, true, nil
+}
+
+func (s *leaseTestStore) ReleaseLease(key string, token int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leases[key].token == token {
+		delete(s.leases, key)
+	}
+	return nil
+}
+
+func (s *leaseTestStore) Set(key string, e *entry.Entry, opts ...store.SetOption) error {
+	if token := store.ApplySetOptions(opts).LeaseToken; token != 0 {
+		s.mu.Lock()
+		lease, held := s.leases[key]
+		s.mu.Unlock()
+		if !held || lease.token != token || time.Now().After(lease.expires) {
+			return store.ErrNotStored
+		}
+	}
+	return s.Store.Set(key, e, opts...)
+}
+
+// newSharedCaches returns caches that behave like separate processes sharing one store
+func newSharedCaches(t *testing.T, n int) []*Cache {
+	t.Helper()
+	shared := newLeaseTestStore(t)
+
+	caches := make([]*Cache, n)
+	for i := range caches {
+		cache, err := New(NewDefaultConfig())
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		cache.store = shared
+		caches[i] = cache
+	}
+	return caches
+}
+
+func TestGetOrLoad(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	var calls int64
+	loader := func(ctx context.Context) (any, error) {
+		atomic.AddInt64(&calls, 1)
+		return "value", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.GetOrLoad(context.Background(), "key", loader)
+		if err != nil || value != "value" {
+			t.Fatalf("Expected value, got %v, %v", value, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected loader to run once, ran %d times", calls)
+	}
+
+	errBackend := errors.New("backend down")
+	_, err := cache.GetOrLoad(context.Background(), "failing", func(ctx context.Context) (any, error) {
+		return nil, errBackend
+	}, WithErrorCaching())
+	if !errors.Is(err, errBackend) {
+		t.Fatalf("Expected loader error, got %v", err)
+	}
+	if _, err := cache.GetOrLoad(context.Background(), "failing", loader, WithErrorCaching()); !errors.Is(err, errBackend) {
+		t.Fatalf("Expected cached loader error, got %v", err)
+	}
+}
+
+func TestDistributedSingleflight(t *testing.T) {
+	caches := newSharedCaches(t, 3)
+
+	var calls int64
+	loader := func(ctx context.Context) (any, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func(cache *Cache) {
+			defer wg.Done()
+			value, err := cache.GetOrLoad(context.Background(), "key", loader,
+				WithDistributedSingleflight(time.Second), WithLeasePollInterval(5*time.Millisecond))
+			if err != nil || value != "value" {
+				t.Errorf("Expected value, got %v, %v", value, err)
+			}
+		}(caches[i%len(caches)])
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("Expected one load across all caches, got %d", calls)
+	}
+}
+
+func TestDistributedSingleflightLeaseExpiry(t *testing.T) {
+	caches := newSharedCaches(t, 1)
+	leases := caches[0].store.(*leaseTestStore)
+
+	// Simulate a holder that crashed without releasing its lease
+	if _, acquired, _ := leases.AcquireLease("key", 100*time.Millisecond); !acquired {
+		t.Fatal("Expected to acquire lease")
+	}
+
+	fn := func(ctx context.Context, id int) (string, error) {
+		return "loaded", nil
+	}
+	wrapped := Wrap(caches[0], fn, WithKeyFunc(func(args []any) string { return "key" }),
+		WithDistributedSingleflight(time.Second), WithLeasePollInterval(10*time.Millisecond))
+
+	start := time.Now()
+	value, err := wrapped(context.Background(), 1)
+	if err != nil || value != "loaded" {
+		t.Fatalf("Expected loaded value, got %q, %v", value, err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("Expected to wait for the stale lease to expire, waited %v", elapsed)
+	}
+
+	// Waiters give up when their context is done
+	_, _, _ = leases.AcquireLease("other", time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_, err = caches[0].GetOrLoad(ctx, "other", func(ctx context.Context) (any, error) {
+		return "unreachable", nil
+	}, WithDistributedSingleflight(time.Second), WithLeasePollInterval(5*time.Millisecond))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded while waiting on lease, got %v", err)
+	}
+}
+
+func TestDistributedSingleflightWithoutErrorResult(t *testing.T) {
+	caches := newSharedCaches(t, 2)
+
+	var calls int64
+	fn := func(ctx context.Context, id int) string {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(150 * time.Millisecond)
+		return "loaded"
+	}
+
+	// The load timeout fires while the second cache waits on the first one's
+	// lease; a function without an error result must still get a value
+	options := []WrapOption{
+		WithKeyFunc(func(args []any) string { return "key" }),
+		WithDistributedSingleflight(time.Second),
+		WithLeasePollInterval(5 * time.Millisecond),
+		WithLoadTimeout(50 * time.Millisecond),
+	}
+	holder := Wrap(caches[0], fn, options...)
+	waiter := Wrap(caches[1], fn, options...)
+
+	done := make(chan string, 1)
+	go func() { done <- holder(context.Background(), 1) }()
+	waitForCondition(t, time.Second, func() bool { return atomic.LoadInt64(&calls) == 1 })
+
+	if value := waiter(context.Background(), 1); value != "loaded" {
+		t.Fatalf("Expected waiter to get the loaded value, got %q", value)
+	}
+	if value := <-done; value != "loaded" {
+		t.Fatalf("Expected holder to get the loaded value, got %q", value)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected one load across both caches, got %d", calls)
+	}
+}
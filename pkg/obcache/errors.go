@@ -0,0 +1,58 @@
+package obcache
+
+import (
+	"errors"
+
+	"github.com/vnykmshr/obcache-go/internal/singleflight"
+	"github.com/vnykmshr/obcache-go/internal/store"
+)
+
+// ErrNotStored is returned by Set when a write condition such as
+// WithOnlyIfAbsent or WithOnlyIfPresent is not met
+var ErrNotStored = store.ErrNotStored
+
+// ErrNotInteger is returned by IncrBy when the key holds a non-integer value
+var ErrNotInteger = store.ErrNotInteger
+
+// ErrOverflow is returned by IncrBy when the result would overflow int64
+var ErrOverflow = store.ErrOverflow
+
+// ErrNotFound is returned by GetE when the key is not in the cache
+var ErrNotFound = store.ErrNotFound
+
+// ErrBackendUnavailable is wrapped by errors from a store backend that could
+// not be reached, such as a Redis server that is down
+var ErrBackendUnavailable = store.ErrBackendUnavailable
+
+// ErrCorruptEntry is wrapped by errors reading an entry that cannot be
+// decoded or decompressed
+var ErrCorruptEntry = store.ErrCorruptEntry
+
+// ErrCircuitOpen is wrapped, along with ErrBackendUnavailable, by errors
+// from operations the Redis circuit breaker rejected while open
+var ErrCircuitOpen = store.ErrCircuitOpen
+
+// ErrClosed is returned by cache operations after Close. Functions wrapped
+// with Wrap keep working after Close but call through without caching.
+var ErrClosed = errors.New("obcache: cache is closed")
+
+// ErrNoLoader is returned by Fetch on a miss when no Loader is configured
+var ErrNoLoader = errors.New("obcache: no loader configured")
+
+// ErrWarmInProgress is returned by Warmer.Warm when called while another
+// warming run of the same Warmer has not finished
+var ErrWarmInProgress = errors.New("obcache: warming already in progress")
+
+// ErrWriteQueueFull is returned by Set and Delete in WriteBehind mode when
+// the write-behind queue already holds WriteBehindConfig.QueueSize writes
+var ErrWriteQueueFull = errors.New("obcache: write-behind queue is full")
+
+// ErrWriterClosed is returned by Set and Delete in WriteBehind mode after the
+// cache has been closed
+var ErrWriterClosed = errors.New("obcache: write-behind queue is closed")
+
+// PanicError is the error returned in place of a panic by wrapped functions
+// and loaders using WithPanicAsError. It carries the panic value and the
+// stack of the goroutine that panicked; Unwrap returns the value if it is an
+// error.
+type PanicError = singleflight.PanicError
@@ -0,0 +1,167 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+	"github.com/vnykmshr/obcache-go/internal/store/memory"
+	"github.com/vnykmshr/obcache-go/pkg/compression"
+	"github.com/vnykmshr/obcache-go/pkg/metrics"
+)
+
+// unavailableStore is a memory store whose operations fail while err is set
+type unavailableStore struct {
+	*memory.Store
+	err error
+}
+
+func (s *unavailableStore) GetE(key string) (*entry.Entry, error) {
+	if s.err != nil {
+		return nil, errors.Join(store.ErrBackendUnavailable, s.err)
+	}
+	return store.GetE(s.Store, key)
+}
+
+func (s *unavailableStore) Set(key string, e *entry.Entry, opts ...store.SetOption) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.Store.Set(key, e, opts...)
+}
+
+func newUnavailableCache(t *testing.T, config *Config) (*Cache, *unavailableStore) {
+	t.Helper()
+	inner, err := memory.New(100)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	s := &unavailableStore{Store: inner}
+	cache, err := New(config.WithStore(s))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	return cache, s
+}
+
+func TestGetEReportsBackendErrors(t *testing.T) {
+	var hookErr error
+	hooks := &Hooks{}
+	hooks.AddOnError(func(key string, err error) {
+		hookErr = err
+	})
+	cache, s := newUnavailableCache(t, NewDefaultConfig().WithHooks(hooks))
+
+	_ = cache.Set("a", 1, time.Minute)
+	if value, err := cache.GetE("a"); err != nil || value != 1 {
+		t.Fatalf("Expected cached value, got %v (err=%v)", value, err)
+	}
+	if _, err := cache.GetE("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound for a missing key, got %v", err)
+	}
+
+	s.err = errors.New("connection refused")
+	if _, err := cache.GetE("a"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Expected ErrBackendUnavailable, got %v", err)
+	}
+	if _, found := cache.Get("a"); found {
+		t.Fatal("Expected Get to report no value while the backend is down")
+	}
+	if !errors.Is(hookErr, ErrBackendUnavailable) {
+		t.Fatalf("Expected OnError hook with the backend error, got %v", hookErr)
+	}
+	if err := cache.Set("b", 2, time.Minute); err == nil {
+		t.Fatal("Expected Set to fail while the backend is down")
+	}
+
+	stats := cache.Stats()
+	if stats.Misses() != 1 {
+		t.Fatalf("Expected store errors not to count as misses, got %d misses", stats.Misses())
+	}
+	if stats.Errors() != 3 {
+		t.Fatalf("Expected 3 errors, got %d", stats.Errors())
+	}
+	if byOp := stats.ErrorsByOperation(); byOp["get"] != 2 || byOp["set"] != 1 {
+		t.Fatalf("Unexpected errors by operation: %v", byOp)
+	}
+}
+
+func TestFetchReturnsBackendErrors(t *testing.T) {
+	loads := 0
+	config := NewDefaultConfig().WithLoader(LoaderFunc(func(context.Context, string) (any, error) {
+		loads++
+		return "loaded", nil
+	}))
+	cache, s := newUnavailableCache(t, config)
+
+	s.err = errors.New("connection refused")
+	if _, err := cache.Fetch(context.Background(), "a"); !errors.Is(err, ErrBackendUnavailable) {
+		t.Fatalf("Expected ErrBackendUnavailable from Fetch, got %v", err)
+	}
+	if loads != 0 {
+		t.Fatalf("Expected no load while the store is failing, got %d", loads)
+	}
+}
+
+func TestGetECorruptEntry(t *testing.T) {
+	config := NewDefaultConfig().WithCompression(&compression.Config{
+		Enabled:   true,
+		Algorithm: compression.CompressorGzip,
+		MinSize:   1,
+		Level:     -1,
+	})
+	cache, _ := New(config)
+
+	_ = cache.Set("a", strings.Repeat("value", 100), time.Minute)
+	e, found := cache.store.Get("a")
+	if !found || !e.IsCompressed {
+		t.Fatal("Expected a compressed entry")
+	}
+	e.Value = []byte("not gzip")
+
+	if _, err := cache.GetE("a"); !errors.Is(err, ErrCorruptEntry) {
+		t.Fatalf("Expected ErrCorruptEntry, got %v", err)
+	}
+	if n := cache.Stats().Errors(); n != 1 {
+		t.Fatalf("Expected 1 error, got %d", n)
+	}
+}
+
+func TestPrometheusExportsErrors(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	exporter, err := metrics.NewPrometheusExporter(metrics.NewDefaultConfig(), &metrics.PrometheusConfig{Registry: registry})
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+
+	stats := &Stats{}
+	stats.incErrors(metrics.OperationGet)
+	stats.incErrors(metrics.OperationGet)
+	if err := exporter.ExportStats(stats, metrics.Labels{"cache_name": "test"}); err != nil {
+		t.Fatalf("ExportStats failed: %v", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != metrics.DefaultMetricNames().CacheErrorsTotal {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "operation" && label.GetValue() == "get" && m.GetCounter().GetValue() == 2 {
+					return
+				}
+			}
+		}
+	}
+	t.Fatal("Expected 2 get errors on the errors counter")
+}
@@ -263,8 +263,8 @@ func TestEvictionCallbacks(t *testing.T) {
 		t.Errorf("Expected key1 to be evicted (FIFO), got %s", evictedKeys[0])
 	}
 
-	if len(evictedValues) > 0 && evictedValues[0] != "evicted" {
-		t.Errorf("Expected placeholder evicted value, got %v", evictedValues[0])
+	if len(evictedValues) > 0 && evictedValues[0] != "value1" {
+		t.Errorf("Expected evicted value value1, got %v", evictedValues[0])
 	}
 }
 
@@ -345,3 +345,118 @@ func TestConfigurationMethods(t *testing.T) {
 		t.Errorf("Expected eviction type to be FIFO, got %s", config.EvictionType)
 	}
 }
+
+func TestEvictionReasons(t *testing.T) {
+	for _, evictionType := range []eviction.EvictionType{eviction.LRU, eviction.LFU} {
+		t.Run(string(evictionType), func(t *testing.T) {
+			hookReasons := make(map[EvictReason]int)
+			hooks := &Hooks{}
+			hooks.AddOnEvict(func(_ string, _ any, reason EvictReason) {
+				hookReasons[reason]++
+			})
+
+			config := NewDefaultConfig().
+				WithMaxEntries(2).
+				WithMaxCost(10).
+				WithCleanupInterval(0).
+				WithEvictionType(evictionType).
+				WithHooks(hooks)
+			cache, err := New(config)
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+			defer cache.Close()
+
+			_ = cache.Set("a", 1, time.Hour)
+			_ = cache.Set("a", 2, time.Hour) // replaced
+			_ = cache.Set("b", 1, time.Hour)
+			_ = cache.Set("c", 1, time.Hour) // evicts a for capacity
+			_ = cache.Delete("c")
+			_ = cache.Set("d", 1, time.Hour, WithCost(10)) // evicts b for cost
+			_ = cache.Delete("d")
+			_ = cache.Set("e", 1, time.Millisecond)
+			_ = cache.Set("f", 1, time.Millisecond)
+
+			time.Sleep(5 * time.Millisecond)
+			_, _ = cache.Get("e") // expired on access, unless the sweep gets there first
+			cache.Cleanup()
+
+			_ = cache.Set("g", 1, time.Hour)
+			_ = cache.Clear()
+
+			stats := cache.Stats()
+			expected := map[EvictReason]int64{
+				EvictReasonReplaced: 1,
+				EvictReasonCapacity: 1,
+				EvictReasonCost:     1,
+				EvictReasonDeleted:  2,
+				EvictReasonCleared:  1,
+			}
+			for reason, want := range expected {
+				if got := stats.EvictionsFor(reason); got != want {
+					t.Errorf("Expected %d %v removals, got %d", want, reason, got)
+				}
+			}
+			if expired := stats.EvictionsFor(EvictReasonTTL) + stats.EvictionsFor(EvictReasonExpiredOnAccess); expired != 2 {
+				t.Errorf("Expected 2 expirations, got %d", expired)
+			}
+
+			// Only evictions count towards Evictions and reach OnEvict hooks
+			if evictions := stats.Evictions(); evictions != 4 {
+				t.Errorf("Expected 4 evictions, got %d", evictions)
+			}
+			if hookReasons[EvictReasonReplaced] != 0 || hookReasons[EvictReasonDeleted] != 0 || hookReasons[EvictReasonCleared] != 0 {
+				t.Errorf("Expected OnEvict only for evictions, got %v", hookReasons)
+			}
+			if hookReasons[EvictReasonCapacity] != 1 || hookReasons[EvictReasonCost] != 1 {
+				t.Errorf("Unexpected OnEvict reasons: %v", hookReasons)
+			}
+
+			byLabel := stats.EvictionsByReason()
+			if byLabel["capacity"] != 1 || byLabel["size_budget"] != 1 || byLabel["replaced"] != 1 {
+				t.Errorf("Unexpected reason counts: %v", byLabel)
+			}
+		})
+	}
+}
+
+func TestExpiredOnAccessReportedBeforeGetReturns(t *testing.T) {
+	for _, evictionType := range []eviction.EvictionType{eviction.LRU, eviction.LFU} {
+		t.Run(string(evictionType), func(t *testing.T) {
+			var hooked int
+			hooks := &Hooks{}
+			hooks.AddOnEvict(func(_ string, _ any, reason EvictReason) {
+				if reason == EvictReasonExpiredOnAccess {
+					hooked++
+				}
+			})
+
+			config := NewDefaultConfig().
+				WithCleanupInterval(0).
+				WithEvictionType(evictionType).
+				WithHooks(hooks)
+			cache, err := New(config)
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+			defer cache.Close()
+
+			_ = cache.Set("a", 1, time.Millisecond)
+			time.Sleep(5 * time.Millisecond)
+			if _, found := cache.Get("a"); found {
+				t.Fatal("Expected expired entry to miss")
+			}
+
+			// The removal is complete by the time Get reports the miss
+			if n := cache.Stats().EvictionsFor(EvictReasonExpiredOnAccess); n != 1 {
+				t.Fatalf("Expected 1 expired-on-access removal, got %d", n)
+			}
+			if hooked != 1 {
+				t.Fatalf("Expected OnEvict to be called once, got %d", hooked)
+			}
+			if n := cache.Len(); n != 0 {
+				t.Fatalf("Expected the expired entry to be removed, got %d entries", n)
+			}
+		})
+	}
+}
@@ -14,12 +14,23 @@ type Hooks struct {
 	// OnMiss is called when a cache key is not found or expired
 	OnMiss []OnMissHook
 
-	// OnEvict is called when a cache entry is evicted (LRU or TTL)
+	// OnEvict is called when a cache entry is evicted to make room or because
+	// it expired (see EvictReason.IsEviction)
 	OnEvict []OnEvictHook
 
 	// OnInvalidate is called when a cache entry is manually invalidated
 	OnInvalidate []OnInvalidateHook
 
+	// OnWriteError is called when the configured Writer fails to apply a write
+	OnWriteError []OnWriteErrorHook
+
+	// OnError is called when the store fails an operation, e.g. because the
+	// backend is unreachable or holds a corrupt entry
+	OnError []OnErrorHook
+
+	// OnCircuitStateChange is called when the Redis circuit breaker changes state
+	OnCircuitStateChange []OnCircuitStateChangeHook
+
 	// Context-aware hooks with function arguments
 	// OnHitCtx is called when a cache hit occurs with context and function arguments
 	OnHitCtx []OnHitHookCtx
@@ -64,6 +75,16 @@ type (
 	// OnInvalidateHook is called when a cache entry is invalidated
 	OnInvalidateHook func(key string)
 
+	// OnWriteErrorHook is called when a write to the backing store fails
+	OnWriteErrorHook func(key string, err error)
+
+	// OnErrorHook is called when a store operation on key fails
+	OnErrorHook func(key string, err error)
+
+	// OnCircuitStateChangeHook is called when the Redis circuit breaker
+	// moves from one state to another
+	OnCircuitStateChangeHook func(from, to CircuitState)
+
 	// Context-aware hook function type definitions
 	// OnHitHookCtx is called when a cache hit occurs with context and function arguments
 	OnHitHookCtx func(ctx context.Context, key string, value any, args []any)
@@ -175,25 +196,61 @@ type (
 	}
 )
 
-// EvictReason indicates why a cache entry was evicted
+// EvictReason indicates why an entry left the cache. OnEvict hooks and
+// Stats.Evictions only see evictions (see IsEviction); every reason is
+// counted in Stats.EvictionsByReason.
 type EvictReason int
 
 const (
 	// EvictReasonLRU indicates the entry was evicted due to LRU policy
+	//
+	// Deprecated: stores report capacity evictions as EvictReasonCapacity
+	// whatever their eviction policy.
 	EvictReasonLRU EvictReason = iota
 
-	// EvictReasonTTL indicates the entry was evicted due to TTL expiration
+	// EvictReasonTTL indicates the entry expired and was removed by a
+	// cleanup sweep
 	EvictReasonTTL
 
 	// EvictReasonCapacity indicates the entry was evicted due to capacity limits
 	EvictReasonCapacity
+
+	// EvictReasonExpiredOnAccess indicates the entry was found expired when
+	// read and removed
+	EvictReasonExpiredOnAccess
+
+	// EvictReasonCost indicates the entry was evicted to stay within MaxCost
+	EvictReasonCost
+
+	// EvictReasonReplaced indicates the entry was overwritten by a newer value
+	EvictReasonReplaced
+
+	// EvictReasonDeleted indicates the entry was removed explicitly
+	EvictReasonDeleted
+
+	// EvictReasonCleared indicates the entry was removed by Clear
+	EvictReasonCleared
+
+	// EvictReasonDeletedExternally indicates the entry was deleted from a
+	// shared store by another client, such as another process sharing the
+	// Redis cache. Such removals are reported to OnInvalidate hooks.
+	EvictReasonDeletedExternally
+
+	// numEvictReasons is the number of defined reasons
+	numEvictReasons
 )
 
 // String representations for EvictReason
 const (
-	evictReasonLRUString      = "LRU"
-	evictReasonTTLString      = "TTL"
-	evictReasonCapacityString = "Capacity"
+	evictReasonLRUString               = "LRU"
+	evictReasonTTLString               = "TTL"
+	evictReasonCapacityString          = "Capacity"
+	evictReasonExpiredOnAccessString   = "ExpiredOnAccess"
+	evictReasonCostString              = "Cost"
+	evictReasonReplacedString          = "Replaced"
+	evictReasonDeletedString           = "Deleted"
+	evictReasonClearedString           = "Cleared"
+	evictReasonDeletedExternallyString = "DeletedExternally"
 )
 
 func (r EvictReason) String() string {
@@ -204,11 +261,61 @@ func (r EvictReason) String() string {
 		return evictReasonTTLString
 	case EvictReasonCapacity:
 		return evictReasonCapacityString
+	case EvictReasonExpiredOnAccess:
+		return evictReasonExpiredOnAccessString
+	case EvictReasonCost:
+		return evictReasonCostString
+	case EvictReasonReplaced:
+		return evictReasonReplacedString
+	case EvictReasonDeleted:
+		return evictReasonDeletedString
+	case EvictReasonCleared:
+		return evictReasonClearedString
+	case EvictReasonDeletedExternally:
+		return evictReasonDeletedExternallyString
 	default:
 		return "Unknown"
 	}
 }
 
+// Label returns the reason in the form used for metric labels
+func (r EvictReason) Label() string {
+	switch r {
+	case EvictReasonLRU:
+		return "lru"
+	case EvictReasonTTL:
+		return "expired_sweep"
+	case EvictReasonCapacity:
+		return "capacity"
+	case EvictReasonExpiredOnAccess:
+		return "expired_lazy"
+	case EvictReasonCost:
+		return "size_budget"
+	case EvictReasonReplaced:
+		return "replaced"
+	case EvictReasonDeleted:
+		return "deleted"
+	case EvictReasonCleared:
+		return "cleared"
+	case EvictReasonDeletedExternally:
+		return "deleted_external"
+	default:
+		return "unknown"
+	}
+}
+
+// IsEviction reports whether the cache removed the entry on its own, to make
+// room or because it expired, rather than because it was overwritten,
+// deleted or cleared
+func (r EvictReason) IsEviction() bool {
+	switch r {
+	case EvictReasonLRU, EvictReasonTTL, EvictReasonCapacity, EvictReasonExpiredOnAccess, EvictReasonCost:
+		return true
+	default:
+		return false
+	}
+}
+
 // AddOnHit adds an OnHit hook
 func (h *Hooks) AddOnHit(hook OnHitHook) {
 	h.OnHit = append(h.OnHit, hook)
@@ -229,6 +336,21 @@ func (h *Hooks) AddOnInvalidate(hook OnInvalidateHook) {
 	h.OnInvalidate = append(h.OnInvalidate, hook)
 }
 
+// AddOnWriteError adds an OnWriteError hook
+func (h *Hooks) AddOnWriteError(hook OnWriteErrorHook) {
+	h.OnWriteError = append(h.OnWriteError, hook)
+}
+
+// AddOnError adds an OnError hook
+func (h *Hooks) AddOnError(hook OnErrorHook) {
+	h.OnError = append(h.OnError, hook)
+}
+
+// AddOnCircuitStateChange adds an OnCircuitStateChange hook
+func (h *Hooks) AddOnCircuitStateChange(hook OnCircuitStateChangeHook) {
+	h.OnCircuitStateChange = append(h.OnCircuitStateChange, hook)
+}
+
 // Context-aware hook builder methods
 // AddOnHitCtx adds an OnHitCtx hook
 func (h *Hooks) AddOnHitCtx(hook OnHitHookCtx) {
@@ -652,3 +774,30 @@ func (h *Hooks) invokeOnInvalidateWithCtx(ctx context.Context, key string, args
 		}
 	}
 }
+
+// invokeOnWriteError calls all OnWriteError hooks
+func (h *Hooks) invokeOnWriteError(key string, err error) {
+	for _, hook := range h.OnWriteError {
+		if hook != nil {
+			hook(key, err)
+		}
+	}
+}
+
+// invokeOnCircuitStateChange calls all OnCircuitStateChange hooks
+func (h *Hooks) invokeOnCircuitStateChange(from, to CircuitState) {
+	for _, hook := range h.OnCircuitStateChange {
+		if hook != nil {
+			hook(from, to)
+		}
+	}
+}
+
+// invokeOnError calls all OnError hooks
+func (h *Hooks) invokeOnError(key string, err error) {
+	for _, hook := range h.OnError {
+		if hook != nil {
+			hook(key, err)
+		}
+	}
+}
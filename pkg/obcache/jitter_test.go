@@ -0,0 +1,77 @@
+package obcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSetWithTTLJitter(t *testing.T) {
+	cache, err := New(NewDefaultConfig().WithTTLJitter(0.5))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := cache.Set(key, i, time.Hour); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+
+		ttl, found := cache.TTL(key)
+		if !found {
+			t.Fatalf("Expected to find %s", key)
+		}
+		if ttl > time.Hour || ttl < 30*time.Minute-time.Second {
+			t.Fatalf("Expected jittered TTL within [30m, 1h], got %v", ttl)
+		}
+		distinct[ttl.Truncate(time.Second)] = true
+	}
+
+	if len(distinct) < 2 {
+		t.Fatal("Expected jitter to spread TTLs across entries")
+	}
+}
+
+func TestApplyJitterBounds(t *testing.T) {
+	if got := applyJitter(time.Minute, 0); got != time.Minute {
+		t.Fatalf("Expected no jitter, got %v", got)
+	}
+	for i := 0; i < 100; i++ {
+		if got := applyJitter(time.Minute, 2); got <= 0 || got > time.Minute {
+			t.Fatalf("Expected jittered TTL in (0, 1m], got %v", got)
+		}
+	}
+}
+
+func TestWrapEarlyExpiration(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	calls := 0
+	fn := func(id int) int {
+		calls++
+		time.Sleep(10 * time.Millisecond)
+		return id * 2
+	}
+
+	// An enormous beta makes early recomputation on every read near-certain
+	eager := Wrap(cache, fn, WithTTL(time.Hour), WithEarlyExpiration(1e9), WithKeyFunc(func(args []any) string {
+		return "eager"
+	}))
+	eager(1)
+	eager(1)
+	if calls != 2 {
+		t.Fatalf("Expected early expiration to recompute, got %d calls", calls)
+	}
+
+	calls = 0
+	lazy := Wrap(cache, fn, WithTTL(time.Hour), WithKeyFunc(func(args []any) string {
+		return "lazy"
+	}))
+	lazy(1)
+	lazy(1)
+	if calls != 1 {
+		t.Fatalf("Expected cached result without early expiration, got %d calls", calls)
+	}
+}
@@ -2,37 +2,76 @@ package obcache
 
 import (
 	"crypto/sha256"
+	"encoding"
 	"encoding/hex"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 const noArgsKey = "no-args"
 
-// DefaultKeyFunc generates cache keys from function arguments using a hash-based approach
-// This function handles most common Go types and provides stable key generation
+// KeySchemaVersion identifies the encoding used by DefaultKeyFunc. It is
+// embedded in every generated key and bumped whenever the encoding changes,
+// so entries written under an older format are never read back.
+const KeySchemaVersion = 3
+
+// keySchemaPrefix starts every key produced by DefaultKeyFunc
+var keySchemaPrefix = "v" + strconv.Itoa(KeySchemaVersion) + ":"
+
+// maxReadableKeyLength is the longest encoding DefaultKeyFunc returns as is;
+// longer encodings are hashed
+const maxReadableKeyLength = 64
+
+// CacheKeyer can be implemented by argument types to control how they are
+// encoded into cache keys. CacheKey must return the same string for values
+// that should share a cached result and different strings otherwise.
+type CacheKeyer interface {
+	CacheKey() string
+}
+
+// DefaultKeyFunc generates cache keys from function arguments. Arguments are
+// encoded into a canonical, unambiguous form, so distinct arguments never
+// share a key: map entries are sorted, exported struct fields are all
+// included, and strings are length-prefixed. Types implementing CacheKeyer or
+// encoding.TextMarshaler (such as time.Time) are encoded by those methods.
+// Short encodings are used directly as keys; longer ones are replaced by their
+// SHA-256 hash. Keys start with the schema version (see KeySchemaVersion).
+//
+// Unexported fields are skipped, so internal state such as an embedded
+// sync.Mutex or a client's connection pool never changes the key. Arguments
+// whose identity lives in unexported fields, or that carry clients, loggers
+// and other handles, should implement CacheKeyer or be left out of the key
+// with WithIgnoreArgs.
 func DefaultKeyFunc(args []any) string {
 	if len(args) == 0 {
-		return noArgsKey
+		return keySchemaPrefix + noArgsKey
 	}
 
-	var parts []string
+	enc := &keyEncoder{}
 	for i, arg := range args {
-		key := argToKey(arg)
-		parts = append(parts, fmt.Sprintf("%d:%s", i, key))
+		if i > 0 {
+			enc.buf.WriteByte('|')
+		}
+		if arg == nil {
+			enc.buf.WriteString("nil")
+			continue
+		}
+		enc.encode(reflect.ValueOf(arg))
 	}
 
-	// For short keys, return directly
-	combined := strings.Join(parts, "|")
-	if len(combined) <= 64 {
-		return combined
+	// For short keys, return the encoding directly
+	combined := enc.buf.String()
+	if len(combined) <= maxReadableKeyLength {
+		return keySchemaPrefix + combined
 	}
 
-	// For longer keys, use SHA256 hash to prevent unbounded key growth
+	// For longer keys, use SHA256 hash to prevent unbounded key growth. No
+	// encoding starts with '#', so hashed keys cannot clash with readable ones
 	hash := sha256.Sum256([]byte(combined))
-	return hex.EncodeToString(hash[:])
+	return keySchemaPrefix + "#" + hex.EncodeToString(hash[:])
 }
 
 // SimpleKeyFunc generates simple cache keys by joining string representations
@@ -50,145 +89,191 @@ func SimpleKeyFunc(args []any) string {
 	return strings.Join(parts, ":")
 }
 
-// argToKey converts a single argument to a string key
-func argToKey(arg any) string {
-	if arg == nil {
-		return "nil"
-	}
+var (
+	cacheKeyerType    = reflect.TypeOf((*CacheKeyer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
 
-	v := reflect.ValueOf(arg)
-	t := v.Type()
+// keyEncoder writes the canonical encoding of values. Every encoded value is
+// self-delimiting: variable-length data is length-prefixed and numbers never
+// contain separators, so concatenated encodings can be split unambiguously.
+type keyEncoder struct {
+	buf strings.Builder
 
-	// Handle common types efficiently
-	switch t.Kind() {
+	// visiting holds the pointers on the current path, to detect cycles
+	visiting map[uintptr]bool
+}
+
+// encode writes the encoding of v
+func (e *keyEncoder) encode(v reflect.Value) {
+	if e.encodeCustom(v) {
+		return
+	}
+
+	switch v.Kind() {
 	case reflect.String:
-		return "s:" + v.String()
+		e.lengthPrefixed("s", v.String())
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return "i:" + strconv.FormatInt(v.Int(), 10)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return "u:" + strconv.FormatUint(v.Uint(), 10)
+		e.buf.WriteString("i:" + strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf.WriteString("u:" + strconv.FormatUint(v.Uint(), 10))
 	case reflect.Float32, reflect.Float64:
-		return "f:" + strconv.FormatFloat(v.Float(), 'g', -1, 64)
+		e.buf.WriteString("f:" + strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		e.buf.WriteString("c:" + strconv.FormatFloat(real(c), 'g', -1, 64) + "," + strconv.FormatFloat(imag(c), 'g', -1, 64))
 	case reflect.Bool:
-		return "b:" + strconv.FormatBool(v.Bool())
+		e.buf.WriteString("b:" + strconv.FormatBool(v.Bool()))
 	case reflect.Ptr:
+		e.encodePointer(v)
+	case reflect.Interface:
 		if v.IsNil() {
-			return "ptr:nil"
+			e.buf.WriteString("nil")
+			return
 		}
-		return "ptr:" + argToKey(v.Elem().Interface())
-	case reflect.Slice, reflect.Array:
-		return handleSliceOrArray(v)
-	case reflect.Map:
-		return handleMap(v)
-	case reflect.Struct:
-		return handleStruct(v, t)
-	case reflect.Interface:
+		e.encode(v.Elem())
+	case reflect.Slice:
 		if v.IsNil() {
-			return "iface:nil"
+			e.buf.WriteString("l:nil")
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.lengthPrefixed("y", string(v.Bytes()))
+			return
 		}
-		return "iface:" + argToKey(v.Elem().Interface())
+		e.encodeList("l", v)
+	case reflect.Array:
+		e.encodeList("a", v)
+	case reflect.Map:
+		e.encodeMap(v)
+	case reflect.Struct:
+		e.encodeStruct(v)
 	default:
-		// Fallback to string representation for other types
-		return fmt.Sprintf("%T:%v", arg, arg)
+		// Functions, channels and unsafe pointers have no value to encode
+		// beyond their identity
+		e.lengthPrefixed("x", fmt.Sprintf("%s@%x", v.Type(), v.Pointer()))
 	}
 }
 
-// handleSliceOrArray generates keys for slices and arrays
-func handleSliceOrArray(v reflect.Value) string {
-	if v.IsNil() {
-		return "slice:nil"
-	}
-
-	length := v.Len()
-	if length == 0 {
-		return "slice:empty"
+// encodeCustom encodes values through CacheKeyer or encoding.TextMarshaler,
+// reporting whether it did
+func (e *keyEncoder) encodeCustom(v reflect.Value) bool {
+	if !v.CanInterface() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return false
 	}
 
-	// For small slices, include all elements
-	if length <= 10 {
-		var elements []string
-		for i := 0; i < length; i++ {
-			elements = append(elements, argToKey(v.Index(i).Interface()))
+	switch {
+	case v.Type().Implements(cacheKeyerType):
+		e.lengthPrefixed("k", typeName(v.Type()))
+		e.lengthPrefixed("", v.Interface().(CacheKeyer).CacheKey())
+		return true
+	case v.Type().Implements(textMarshalerType):
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return false
 		}
-		return "slice:[" + strings.Join(elements, ",") + "]"
+		e.lengthPrefixed("t", typeName(v.Type()))
+		e.lengthPrefixed("", string(text))
+		return true
 	}
-
-	// For large slices, use length + hash of first and last elements
-	first := argToKey(v.Index(0).Interface())
-	last := argToKey(v.Index(length - 1).Interface())
-	return fmt.Sprintf("slice:len%d:%s...%s", length, first, last)
+	return false
 }
 
-// handleMap generates keys for maps
-func handleMap(v reflect.Value) string {
+// encodePointer encodes the value a pointer refers to
+func (e *keyEncoder) encodePointer(v reflect.Value) {
 	if v.IsNil() {
-		return "map:nil"
+		e.buf.WriteString("p:nil")
+		return
 	}
 
-	keys := v.MapKeys()
-	if len(keys) == 0 {
-		return "map:empty"
+	ptr := v.Pointer()
+	if e.visiting[ptr] {
+		e.buf.WriteString("p:cycle")
+		return
 	}
+	if e.visiting == nil {
+		e.visiting = make(map[uintptr]bool)
+	}
+	e.visiting[ptr] = true
+	defer delete(e.visiting, ptr)
 
-	// For small maps, include key-value pairs (sorted by key string representation for consistency)
-	if len(keys) <= 5 {
-		var pairs []string
-		keyStrs := make([]string, len(keys))
-		for i, key := range keys {
-			keyStrs[i] = argToKey(key.Interface())
-		}
-
-		// Simple sort for consistency
-		for i := 0; i < len(keyStrs); i++ {
-			for j := i + 1; j < len(keyStrs); j++ {
-				if keyStrs[i] > keyStrs[j] {
-					keyStrs[i], keyStrs[j] = keyStrs[j], keyStrs[i]
-					keys[i], keys[j] = keys[j], keys[i]
-				}
-			}
-		}
+	e.buf.WriteString("p:")
+	e.encode(v.Elem())
+}
 
-		for i, key := range keys {
-			keyStr := keyStrs[i]
-			valueStr := argToKey(v.MapIndex(key).Interface())
-			pairs = append(pairs, keyStr+"="+valueStr)
+// encodeList encodes every element of a slice or array
+func (e *keyEncoder) encodeList(tag string, v reflect.Value) {
+	e.buf.WriteString(tag + strconv.Itoa(v.Len()) + "[")
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			e.buf.WriteByte(',')
 		}
-		return "map:{" + strings.Join(pairs, ",") + "}"
+		e.encode(v.Index(i))
 	}
-
-	// For large maps, use length + type info
-	return fmt.Sprintf("map:len%d:type%s", len(keys), v.Type().String())
+	e.buf.WriteByte(']')
 }
 
-// handleStruct generates keys for structs
-func handleStruct(v reflect.Value, t reflect.Type) string {
-	numFields := v.NumField()
-	if numFields == 0 {
-		return "struct:empty"
+// encodeMap encodes every entry of a map, ordered by encoded key
+func (e *keyEncoder) encodeMap(v reflect.Value) {
+	if v.IsNil() {
+		e.buf.WriteString("m:nil")
+		return
 	}
 
-	var fields []string
-	for i := 0; i < numFields && i < 10; i++ { // Limit to first 10 fields
-		field := t.Field(i)
+	entries := make([][2]string, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		entries = append(entries, [2]string{e.sub(iter.Key()), e.sub(iter.Value())})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i][0] < entries[j][0]
+	})
 
-		// Skip unexported fields
-		if !field.IsExported() {
-			continue
+	e.buf.WriteString("m" + strconv.Itoa(len(entries)) + "{")
+	for i, entry := range entries {
+		if i > 0 {
+			e.buf.WriteByte(',')
 		}
+		e.buf.WriteString(entry[0] + "=" + entry[1])
+	}
+	e.buf.WriteByte('}')
+}
 
-		fieldValue := v.Field(i)
-		if !fieldValue.CanInterface() {
+// encodeStruct encodes the exported fields of a struct
+func (e *keyEncoder) encodeStruct(v reflect.Value) {
+	e.lengthPrefixed("S", typeName(v.Type()))
+	e.buf.WriteByte('{')
+	written := 0
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
 			continue
 		}
-
-		fieldKey := argToKey(fieldValue.Interface())
-		fields = append(fields, field.Name+":"+fieldKey)
+		if written > 0 {
+			e.buf.WriteByte(',')
+		}
+		e.encode(v.Field(i))
+		written++
 	}
+	e.buf.WriteByte('}')
+}
 
-	structName := t.Name()
-	if structName == "" {
-		structName = "anonymous"
-	}
+// sub returns the encoding of v on its own, sharing cycle detection state
+func (e *keyEncoder) sub(v reflect.Value) string {
+	inner := &keyEncoder{visiting: e.visiting}
+	inner.encode(v)
+	e.visiting = inner.visiting
+	return inner.buf.String()
+}
 
-	return "struct:" + structName + "{" + strings.Join(fields, ",") + "}"
+// lengthPrefixed writes tag, the length of s and s itself
+func (e *keyEncoder) lengthPrefixed(tag, s string) {
+	e.buf.WriteString(tag + strconv.Itoa(len(s)) + ":" + s)
+}
+
+// typeName returns a package-qualified name for t
+func typeName(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
 }
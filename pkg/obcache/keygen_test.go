@@ -1,8 +1,11 @@
 package obcache
 
 import (
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDefaultKeyFunc(t *testing.T) {
@@ -15,32 +18,37 @@ func TestDefaultKeyFunc(t *testing.T) {
 		{
 			name:     "single int",
 			args:     []any{42},
-			expected: "0:i:42",
+			expected: "v3:i:42",
 		},
 		{
 			name:     "single string",
 			args:     []any{"hello"},
-			expected: "0:s:hello",
+			expected: "v3:s5:hello",
 		},
 		{
 			name:     "multiple args",
 			args:     []any{"user", 123, true},
-			expected: "0:s:user|1:i:123|2:b:true",
+			expected: "v3:s4:user|i:123|b:true",
 		},
 		{
 			name:     "empty args",
 			args:     []any{},
-			expected: "no-args",
+			expected: "v3:no-args",
 		},
 		{
 			name:     "nil arg",
 			args:     []any{nil},
-			expected: "0:nil",
+			expected: "v3:nil",
 		},
 		{
 			name:     "mixed types",
 			args:     []any{"str", 42, 3.14, true, nil},
-			expected: "0:s:str|1:i:42|2:f:3.14|3:b:true|4:nil",
+			expected: "v3:s3:str|i:42|f:3.14|b:true|nil",
+		},
+		{
+			name:     "composite types",
+			args:     []any{[]int{1, 2}, map[string]bool{"b": false, "a": true}, []byte("xy")},
+			expected: "v3:l2[i:1,i:2]|m2{s1:a=b:true,s1:b=b:false}|y2:xy",
 		},
 	}
 
@@ -131,9 +139,9 @@ func TestKeyFuncLongKeys(t *testing.T) {
 	args := []any{longString}
 	key := DefaultKeyFunc(args)
 
-	// Should be hashed (64 characters for SHA256 hex)
-	if len(key) != 64 {
-		t.Fatalf("Expected hashed key length 64, got %d", len(key))
+	// Should be hashed (64 characters for SHA256 hex after the version)
+	if len(key) != len("v3:#")+64 || !strings.HasPrefix(key, "v3:#") {
+		t.Fatalf("Expected versioned hashed key, got %q", key)
 	}
 
 	// Should be consistent
@@ -183,6 +191,21 @@ func TestKeyFuncDifferentArgsProduceDifferentKeys(t *testing.T) {
 			args2: []any{"test", nil},
 			name:  "different arg count",
 		},
+		{
+			args1: []any{"a|s1:b"},
+			args2: []any{"a", "b"},
+			name:  "separator inside string",
+		},
+		{
+			args1: []any{[]string{"a,b"}},
+			args2: []any{[]string{"a", "b"}},
+			name:  "separator inside slice element",
+		},
+		{
+			args1: []any{[]int{}},
+			args2: []any{[]int(nil)},
+			name:  "empty and nil slice",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -197,6 +220,110 @@ func TestKeyFuncDifferentArgsProduceDifferentKeys(t *testing.T) {
 	}
 }
 
+func TestKeyFuncLargeValuesDoNotCollide(t *testing.T) {
+	// Slices that only differ in the middle
+	a := make([]int, 20)
+	b := make([]int, 20)
+	b[10] = 1
+	if DefaultKeyFunc([]any{a}) == DefaultKeyFunc([]any{b}) {
+		t.Fatal("Expected slices differing in the middle to produce different keys")
+	}
+
+	// Maps of the same size and type
+	m1 := map[string]int{}
+	m2 := map[string]int{}
+	for i := 0; i < 10; i++ {
+		m1[strconv.Itoa(i)] = i
+		m2[strconv.Itoa(i)] = i * 2
+	}
+	if DefaultKeyFunc([]any{m1}) == DefaultKeyFunc([]any{m2}) {
+		t.Fatal("Expected maps with different values to produce different keys")
+	}
+
+	// Structs differing after the tenth field
+	type wide struct {
+		F1, F2, F3, F4, F5, F6, F7, F8, F9, F10, F11 int
+	}
+	if DefaultKeyFunc([]any{wide{F11: 1}}) == DefaultKeyFunc([]any{wide{F11: 2}}) {
+		t.Fatal("Expected structs differing in a late field to produce different keys")
+	}
+}
+
+func TestKeyFuncSkipsUnexportedFields(t *testing.T) {
+	type query struct {
+		sync.Mutex
+		Name   string
+		client *strings.Builder
+	}
+
+	locked := &query{Name: "a", client: &strings.Builder{}}
+	locked.Lock()
+	defer locked.Unlock()
+	locked.client.WriteString("internal state")
+
+	key := DefaultKeyFunc([]any{locked})
+	if key != DefaultKeyFunc([]any{&query{Name: "a"}}) {
+		t.Fatalf("Expected mutex and client state not to affect the key, got %q", key)
+	}
+	if key == DefaultKeyFunc([]any{&query{Name: "b"}}) {
+		t.Fatal("Expected structs differing in an exported field to produce different keys")
+	}
+}
+
+type userKey struct {
+	ID    int
+	cache *int // Ignored by CacheKey
+}
+
+func (k userKey) CacheKey() string {
+	return "user:" + strconv.Itoa(k.ID)
+}
+
+func TestKeyFuncCacheKeyer(t *testing.T) {
+	a, b := 1, 2
+	key1 := DefaultKeyFunc([]any{userKey{ID: 7, cache: &a}})
+	key2 := DefaultKeyFunc([]any{userKey{ID: 7, cache: &b}})
+	if key1 != key2 {
+		t.Fatalf("Expected CacheKey to determine the key, got %q and %q", key1, key2)
+	}
+	if !strings.Contains(key1, "user:7") {
+		t.Fatalf("Expected key to contain CacheKey result, got %q", key1)
+	}
+	if key1 == DefaultKeyFunc([]any{userKey{ID: 8}}) {
+		t.Fatal("Expected different CacheKey results to produce different keys")
+	}
+
+	// Pointers to keyers use the same method
+	if DefaultKeyFunc([]any{&userKey{ID: 7}}) == DefaultKeyFunc([]any{&userKey{ID: 8}}) {
+		t.Fatal("Expected pointer keyers to produce different keys")
+	}
+}
+
+func TestKeyFuncTextMarshaler(t *testing.T) {
+	// The monotonic clock reading is not part of the marshalled time
+	now := time.Now()
+	if DefaultKeyFunc([]any{now}) != DefaultKeyFunc([]any{now.Round(0)}) {
+		t.Fatal("Expected equal times to produce the same key")
+	}
+	if DefaultKeyFunc([]any{now}) == DefaultKeyFunc([]any{now.Add(time.Nanosecond)}) {
+		t.Fatal("Expected different times to produce different keys")
+	}
+}
+
+func TestKeyFuncCycles(t *testing.T) {
+	type node struct {
+		Value int
+		Next  *node
+	}
+	n := &node{Value: 1}
+	n.Next = n
+
+	key := DefaultKeyFunc([]any{n})
+	if !strings.HasPrefix(key, "v3:") {
+		t.Fatalf("Expected versioned key for cyclic value, got %q", key)
+	}
+}
+
 func TestKeyFuncWithPointers(t *testing.T) {
 
 	// Test that pointers are dereferenced
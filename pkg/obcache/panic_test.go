@@ -0,0 +1,105 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWrapPanicPropagatesToAllCallers(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	release := make(chan struct{})
+	fn := func(id int) (string, error) {
+		<-release
+		panic("backend exploded")
+	}
+	wrapped := Wrap(cache, fn)
+
+	const callers = 4
+	var wg sync.WaitGroup
+	panics := make(chan any, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { panics <- recover() }()
+			_, _ = wrapped(1)
+		}()
+	}
+
+	waitForCondition(t, time.Second, func() bool { return cache.Stats().InFlight() == callers })
+	time.Sleep(20 * time.Millisecond) // let every caller join the shared call
+	close(release)
+	wg.Wait()
+	close(panics)
+
+	for r := range panics {
+		if _, ok := r.(*PanicError); !ok {
+			t.Fatalf("Expected every caller to panic with *PanicError, got %T", r)
+		}
+	}
+	if cache.Stats().Panics() != 1 {
+		t.Fatalf("Expected 1 panic counted, got %d", cache.Stats().Panics())
+	}
+	if cache.Stats().InFlight() != 0 {
+		t.Fatalf("Expected no in-flight calls after panic, got %d", cache.Stats().InFlight())
+	}
+}
+
+func TestWrapPanicAsError(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	errBackend := errors.New("backend exploded")
+	calls := 0
+	fn := func(ctx context.Context, id int) (string, error) {
+		calls++
+		if calls == 1 {
+			panic(errBackend)
+		}
+		return "recovered", nil
+	}
+	wrapped := Wrap(cache, fn, WithPanicAsError(), WithErrorCaching())
+
+	_, err := wrapped(context.Background(), 1)
+	var pe *PanicError
+	if !errors.As(err, &pe) || !errors.Is(err, errBackend) {
+		t.Fatalf("Expected *PanicError wrapping the panic value, got %v", err)
+	}
+
+	// Panics are never cached, even with error caching
+	if v, err := wrapped(context.Background(), 1); err != nil || v != "recovered" {
+		t.Fatalf("Expected the next call to run again, got %q, %v", v, err)
+	}
+	if cache.Stats().Panics() != 1 {
+		t.Fatalf("Expected 1 panic counted, got %d", cache.Stats().Panics())
+	}
+
+	_, err = cache.GetOrLoad(context.Background(), "loader", func(ctx context.Context) (any, error) {
+		panic("loader exploded")
+	}, WithPanicAsError())
+	if !errors.As(err, &pe) || pe.Value != "loader exploded" {
+		t.Fatalf("Expected GetOrLoad to return *PanicError, got %v", err)
+	}
+}
+
+func TestTryWrap(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	if _, err := TryWrap(cache, "not a function"); err == nil {
+		t.Fatal("Expected error for non-function")
+	}
+	if _, err := TryWrap(cache, func() {}); err == nil {
+		t.Fatal("Expected error for function without results")
+	}
+
+	wrapped, err := TryWrap(cache, func(x int) int { return x * 2 })
+	if err != nil {
+		t.Fatalf("Expected valid function to wrap, got %v", err)
+	}
+	if wrapped(21) != 42 {
+		t.Fatalf("Expected 42, got %d", wrapped(21))
+	}
+}
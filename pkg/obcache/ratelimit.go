@@ -0,0 +1,139 @@
+package obcache
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitOptions holds configuration options for a RateLimiter
+type RateLimitOptions struct {
+	// KeyPrefix is prepended to every limiter key stored in the cache
+	// Default: "ratelimit:"
+	KeyPrefix string
+}
+
+// RateLimitOption is a function that configures RateLimitOptions
+type RateLimitOption func(*RateLimitOptions)
+
+// WithRateLimitPrefix sets the prefix for the limiter's cache keys
+func WithRateLimitPrefix(prefix string) RateLimitOption {
+	return func(opts *RateLimitOptions) {
+		opts.KeyPrefix = prefix
+	}
+}
+
+// RateLimiter is a sliding-window rate limiter that keeps its state in a
+// Cache, so a Redis-backed cache shares limits across processes.
+//
+// It uses the sliding window counter approximation: requests are counted in
+// fixed windows, and the previous window's count is weighted by how much of
+// it still overlaps the sliding window ending now.
+type RateLimiter struct {
+	cache  *Cache
+	limit  int64
+	window time.Duration
+	opts   *RateLimitOptions
+}
+
+// NewRateLimiter creates a limiter that allows up to limit requests per key
+// within any window-long period
+func NewRateLimiter(cache *Cache, limit int64, window time.Duration, options ...RateLimitOption) (*RateLimiter, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("rate limit must be positive, got %d", limit)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("rate limit window must be positive, got %v", window)
+	}
+
+	opts := &RateLimitOptions{
+		KeyPrefix: "ratelimit:",
+	}
+
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	return &RateLimiter{
+		cache:  cache,
+		limit:  limit,
+		window: window,
+		opts:   opts,
+	}, nil
+}
+
+// Allow reports whether one more request for key fits within the limit,
+// recording it if so
+func (r *RateLimiter) Allow(key string) (bool, error) {
+	return r.AllowN(key, 1)
+}
+
+// AllowN reports whether n more requests for key fit within the limit,
+// recording them if so. Rejected requests are not counted.
+func (r *RateLimiter) AllowN(key string, n int64) (bool, error) {
+	current, previous, weight := r.windows(key, r.cache.clock.Now())
+
+	// Windows live long enough to serve as the previous window next time
+	count, err := r.cache.IncrBy(current, n, 2*r.window)
+	if err != nil {
+		return false, err
+	}
+
+	if r.estimate(count, r.count(previous), weight) <= float64(r.limit) {
+		return true, nil
+	}
+
+	// Roll back so rejected requests do not consume the budget
+	if _, err := r.cache.IncrBy(current, -n, 2*r.window); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// Remaining returns how many more requests for key would currently be allowed
+func (r *RateLimiter) Remaining(key string) int64 {
+	current, previous, weight := r.windows(key, r.cache.clock.Now())
+
+	remaining := r.limit - int64(r.estimate(r.count(current), r.count(previous), weight))
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Reset clears the recorded requests for key
+func (r *RateLimiter) Reset(key string) error {
+	current, previous, _ := r.windows(key, r.cache.clock.Now())
+	if err := r.cache.Invalidate(current); err != nil {
+		return err
+	}
+	return r.cache.Invalidate(previous)
+}
+
+// windows returns the cache keys of the current and previous fixed windows
+// and the fraction of the previous window still inside the sliding window
+func (r *RateLimiter) windows(key string, now time.Time) (string, string, float64) {
+	index := now.UnixNano() / int64(r.window)
+	elapsed := now.UnixNano() - index*int64(r.window)
+	weight := 1 - float64(elapsed)/float64(r.window)
+
+	current := fmt.Sprintf("%s%s:%d", r.opts.KeyPrefix, key, index)
+	previous := fmt.Sprintf("%s%s:%d", r.opts.KeyPrefix, key, index-1)
+	return current, previous, weight
+}
+
+// estimate approximates the number of requests in the sliding window
+func (r *RateLimiter) estimate(current, previous int64, weight float64) float64 {
+	return float64(previous)*weight + float64(current)
+}
+
+// count returns the counter stored at key, or zero if it is missing. It
+// peeks so that checking the limiter is not counted as a hit or miss, fires
+// no hooks and never calls the cache's Loader.
+func (r *RateLimiter) count(key string) int64 {
+	value, found := r.cache.peek(key)
+	if !found {
+		return 0
+	}
+	n, _ := value.(int64)
+	return n
+}
@@ -0,0 +1,103 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	limiter, err := NewRateLimiter(cache, 3, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if allowed, err := limiter.Allow("client"); err != nil || !allowed {
+			t.Fatalf("Expected request %d to be allowed, got %v, %v", i, allowed, err)
+		}
+	}
+	if allowed, _ := limiter.Allow("client"); allowed {
+		t.Fatal("Expected request over the limit to be rejected")
+	}
+	if remaining := limiter.Remaining("client"); remaining != 0 {
+		t.Fatalf("Expected 0 remaining, got %d", remaining)
+	}
+
+	// Keys are limited independently
+	if allowed, _ := limiter.Allow("other"); !allowed {
+		t.Fatal("Expected a different key to have its own budget")
+	}
+
+	// Once the full window has slid past, the budget is restored
+	time.Sleep(400 * time.Millisecond)
+	if allowed, _ := limiter.Allow("client"); !allowed {
+		t.Fatal("Expected request to be allowed after the window passed")
+	}
+
+	if err := limiter.Reset("client"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if remaining := limiter.Remaining("client"); remaining != 3 {
+		t.Fatalf("Expected full budget after Reset, got %d", remaining)
+	}
+}
+
+func TestRateLimiterRejectedNotCounted(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+	limiter, _ := NewRateLimiter(cache, 2, time.Hour)
+
+	_, _ = limiter.AllowN("client", 2)
+	for i := 0; i < 5; i++ {
+		_, _ = limiter.Allow("client")
+	}
+
+	if remaining := limiter.Remaining("client"); remaining != 0 {
+		t.Fatalf("Expected 0 remaining, got %d", remaining)
+	}
+	if allowed, _ := limiter.AllowN("client", 3); allowed {
+		t.Fatal("Expected AllowN over the limit to be rejected")
+	}
+}
+
+func TestNewRateLimiterValidation(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	if _, err := NewRateLimiter(cache, 0, time.Second); err == nil {
+		t.Fatal("Expected error for zero limit")
+	}
+	if _, err := NewRateLimiter(cache, 1, 0); err == nil {
+		t.Fatal("Expected error for zero window")
+	}
+}
+
+func TestRateLimiterChecksHaveNoCacheSideEffects(t *testing.T) {
+	loads, lookups := 0, 0
+	hooks := &Hooks{}
+	hooks.AddOnHit(func(string, any) { lookups++ })
+	hooks.AddOnMiss(func(string) { lookups++ })
+	cache, _ := New(NewDefaultConfig().WithHooks(hooks).WithLoader(LoaderFunc(func(_ context.Context, key string) (any, error) {
+		loads++
+		return nil, errors.New("not found")
+	})))
+
+	limiter, err := NewRateLimiter(cache, 3, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create limiter: %v", err)
+	}
+	_, _ = limiter.Allow("client")
+	if remaining := limiter.Remaining("client"); remaining != 2 {
+		t.Fatalf("Expected 2 remaining, got %d", remaining)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits() != 0 || stats.Misses() != 0 || lookups != 0 {
+		t.Fatalf("Expected limiter checks not to count as lookups, got %d hits, %d misses and %d hook calls", stats.Hits(), stats.Misses(), lookups)
+	}
+	if loads != 0 {
+		t.Fatalf("Expected limiter checks not to call the Loader, got %d loads", loads)
+	}
+}
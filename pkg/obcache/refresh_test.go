@@ -0,0 +1,129 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitForCondition(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Condition not met before timeout")
+}
+
+func TestWrapRefreshAhead(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	var calls int64
+	fn := func(ctx context.Context, id int) (int, error) {
+		return int(atomic.AddInt64(&calls, 1)) * 100, nil
+	}
+
+	wrapped := Wrap(cache, fn, WithTTL(200*time.Millisecond), WithRefreshAhead(0.5))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if v, _ := wrapped(ctx, 1); v != 100 {
+		t.Fatalf("Expected first value 100, got %d", v)
+	}
+
+	// Still inside the fresh window: no refresh
+	if v, _ := wrapped(ctx, 1); v != 100 {
+		t.Fatalf("Expected cached value 100, got %d", v)
+	}
+	if atomic.LoadInt64(&calls) != 1 {
+		t.Fatalf("Expected 1 call before refresh window, got %d", calls)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+
+	// Hit inside the refresh window returns the stale value and reloads in the background,
+	// even though the caller's context is cancelled right after
+	if v, _ := wrapped(ctx, 1); v != 100 {
+		t.Fatalf("Expected cached value 100 during refresh, got %d", v)
+	}
+	cancel()
+
+	waitForCondition(t, time.Second, func() bool { return cache.Stats().Refreshes() == 1 })
+
+	if v, _ := wrapped(context.Background(), 1); v != 200 {
+		t.Fatalf("Expected refreshed value 200, got %d", v)
+	}
+}
+
+func TestWrapRefreshAheadFailure(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	var calls int64
+	fn := func(id int) (string, error) {
+		if atomic.AddInt64(&calls, 1) > 1 {
+			return "", errors.New("backend down")
+		}
+		return "value", nil
+	}
+
+	wrapped := Wrap(cache, fn, WithTTL(100*time.Millisecond), WithRefreshAhead(0.5))
+
+	if v, _ := wrapped(1); v != "value" {
+		t.Fatalf("Expected value, got %q", v)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	v, err := wrapped(1)
+	if err != nil || v != "value" {
+		t.Fatalf("Expected stale value without error, got %q, %v", v, err)
+	}
+
+	waitForCondition(t, time.Second, func() bool { return cache.Stats().RefreshFailures() == 1 })
+
+	if cache.Stats().Refreshes() != 0 {
+		t.Fatalf("Expected 0 successful refreshes, got %d", cache.Stats().Refreshes())
+	}
+}
+
+func TestWrapRefreshAheadCountsOncePerReload(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	var calls int64
+	release := make(chan struct{})
+	fn := func(id int) int {
+		if atomic.AddInt64(&calls, 1) > 1 {
+			<-release
+		}
+		return id
+	}
+
+	wrapped := Wrap(cache, fn, WithTTL(100*time.Millisecond), WithRefreshAhead(0.1))
+	wrapped(1)
+	time.Sleep(20 * time.Millisecond)
+
+	// Every hit in the window joins the same in-flight reload
+	for i := 0; i < 50; i++ {
+		wrapped(1)
+	}
+	close(release)
+
+	waitForCondition(t, time.Second, func() bool { return cache.Stats().Refreshes() == 1 })
+	time.Sleep(20 * time.Millisecond)
+	if n := cache.Stats().Refreshes(); n != 1 {
+		t.Fatalf("Expected one refresh for one reload, got %d", n)
+	}
+	if n := atomic.LoadInt64(&calls); n != 2 {
+		t.Fatalf("Expected a single background reload, got %d calls", n-1)
+	}
+}
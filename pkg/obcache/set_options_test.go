@@ -0,0 +1,145 @@
+package obcache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/eviction"
+)
+
+var setOptionEvictionTypes = []eviction.EvictionType{eviction.LRU, eviction.LFU, eviction.FIFO}
+
+func TestSetConditional(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	if err := cache.Set("key", "v1", time.Hour, WithOnlyIfPresent()); !errors.Is(err, ErrNotStored) {
+		t.Fatalf("Expected ErrNotStored for missing key, got %v", err)
+	}
+	if err := cache.Set("key", "v1", time.Hour, WithOnlyIfAbsent()); err != nil {
+		t.Fatalf("Expected IfAbsent write to succeed, got %v", err)
+	}
+	if err := cache.Set("key", "v2", time.Hour, WithOnlyIfAbsent()); !errors.Is(err, ErrNotStored) {
+		t.Fatalf("Expected ErrNotStored for existing key, got %v", err)
+	}
+	if err := cache.Set("key", "v3", time.Hour, WithOnlyIfPresent()); err != nil {
+		t.Fatalf("Expected IfPresent write to succeed, got %v", err)
+	}
+
+	if value, _ := cache.Get("key"); value != "v3" {
+		t.Fatalf("Expected v3, got %v", value)
+	}
+
+	// Expired entries count as absent
+	_ = cache.Set("short", "old", 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	if err := cache.Set("short", "new", time.Hour, WithOnlyIfAbsent()); err != nil {
+		t.Fatalf("Expected IfAbsent write over expired entry to succeed, got %v", err)
+	}
+}
+
+func TestSetNoEvict(t *testing.T) {
+	for _, evictionType := range setOptionEvictionTypes {
+		t.Run(string(evictionType), func(t *testing.T) {
+			cache, err := New(NewDefaultConfig().WithMaxEntries(2).WithEvictionType(evictionType))
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+
+			_ = cache.Set("pinned", "keep", time.Hour, WithNoEvict())
+			for i := 0; i < 10; i++ {
+				_ = cache.Set(fmt.Sprintf("key-%d", i), i, time.Hour)
+			}
+
+			if value, found := cache.Get("pinned"); !found || value != "keep" {
+				t.Fatalf("Expected pinned entry to survive eviction, got %v (found=%v)", value, found)
+			}
+			if cache.Len() != 3 {
+				t.Fatalf("Expected 2 evictable entries plus the pinned one, got %d", cache.Len())
+			}
+
+			if err := cache.Delete("pinned"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if cache.Has("pinned") {
+				t.Fatal("Expected pinned entry to be deletable")
+			}
+		})
+	}
+}
+
+func TestSetPriority(t *testing.T) {
+	for _, evictionType := range setOptionEvictionTypes {
+		t.Run(string(evictionType), func(t *testing.T) {
+			cache, err := New(NewDefaultConfig().WithMaxEntries(3).WithEvictionType(evictionType))
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+
+			_ = cache.Set("important", 1, time.Hour, WithPriority(10))
+			_ = cache.Set("low", 2, time.Hour, WithPriority(-1))
+			_ = cache.Set("normal", 3, time.Hour)
+			_ = cache.Set("new", 4, time.Hour)
+
+			if cache.Has("low") {
+				t.Fatal("Expected lowest-priority entry to be evicted first")
+			}
+
+			_ = cache.Set("newer", 5, time.Hour)
+			if !cache.Has("important") {
+				t.Fatal("Expected high-priority entry to outlive default-priority entries")
+			}
+		})
+	}
+}
+
+func TestSetCostBudget(t *testing.T) {
+	for _, evictionType := range setOptionEvictionTypes {
+		t.Run(string(evictionType), func(t *testing.T) {
+			cache, err := New(NewDefaultConfig().WithMaxCost(10).WithEvictionType(evictionType))
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+
+			_ = cache.Set("a", "a", time.Hour, WithCost(4))
+			_ = cache.Set("b", "b", time.Hour, WithCost(4))
+			if cache.Len() != 2 {
+				t.Fatalf("Expected 2 entries within budget, got %d", cache.Len())
+			}
+
+			_ = cache.Set("c", "c", time.Hour, WithCost(6))
+			if cache.Len() > 2 || !cache.Has("c") {
+				t.Fatalf("Expected older entries to be evicted to fit c, have %v", cache.Keys())
+			}
+			if cache.Has("a") && cache.Has("b") {
+				t.Fatal("Expected the budget to force at least one eviction")
+			}
+		})
+	}
+}
+
+func TestDeleteByTag(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	_ = cache.Set("user:1", "alice", time.Hour, WithTags("users", "tenant-a"))
+	_ = cache.Set("user:2", "bob", time.Hour, WithTags("users", "tenant-b"))
+	_ = cache.Set("order:1", "book", time.Hour, WithTags("orders", "tenant-a"))
+
+	removed, err := cache.DeleteByTag("tenant-a")
+	if err != nil {
+		t.Fatalf("DeleteByTag failed: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("Expected 2 entries removed, got %d", removed)
+	}
+	if !cache.Has("user:2") || cache.Has("user:1") || cache.Has("order:1") {
+		t.Fatalf("Unexpected keys after DeleteByTag: %v", cache.Keys())
+	}
+	if cache.Stats().Invalidations() != 2 {
+		t.Fatalf("Expected 2 invalidations, got %d", cache.Stats().Invalidations())
+	}
+}
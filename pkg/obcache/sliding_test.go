@@ -0,0 +1,77 @@
+package obcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetWithSlidingExpiration(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	if err := cache.Set("session", "data", 80*time.Millisecond, WithSliding(0)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cache.Set("fixed", "data", 80*time.Millisecond); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Keep accessing the sliding entry for longer than its idle TTL
+	for i := 0; i < 4; i++ {
+		time.Sleep(40 * time.Millisecond)
+		if _, found := cache.Get("session"); !found {
+			t.Fatalf("Expected sliding entry to survive access %d", i)
+		}
+	}
+
+	if _, found := cache.Get("fixed"); found {
+		t.Fatal("Expected fixed entry to expire")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, found := cache.Get("session"); found {
+		t.Fatal("Expected sliding entry to expire once idle")
+	}
+}
+
+func TestSetWithSlidingMaxLifetime(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	_ = cache.Set("session", "data", 60*time.Millisecond, WithSliding(100*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		time.Sleep(40 * time.Millisecond)
+		if _, found := cache.Get("session"); !found {
+			t.Fatalf("Expected sliding entry to survive access %d", i)
+		}
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if _, found := cache.Get("session"); found {
+		t.Fatal("Expected entry to expire at its max lifetime despite access")
+	}
+}
+
+func TestWrapWithSlidingExpiration(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	calls := 0
+	fn := func(id int) int {
+		calls++
+		return id
+	}
+
+	wrapped := Wrap(cache, fn, WithTTL(80*time.Millisecond), WithSlidingExpiration(0))
+
+	wrapped(1)
+	for i := 0; i < 4; i++ {
+		time.Sleep(40 * time.Millisecond)
+		wrapped(1)
+	}
+
+	if calls != 1 {
+		t.Fatalf("Expected sliding expiration to keep the result cached, got %d calls", calls)
+	}
+}
@@ -2,6 +2,8 @@ package obcache
 
 import (
 	"sync/atomic"
+
+	"github.com/vnykmshr/obcache-go/pkg/metrics"
 )
 
 // Stats holds cache performance statistics
@@ -23,6 +25,45 @@ type Stats struct {
 
 	// InFlight is the number of requests currently being processed (singleflight)
 	inFlight int64
+
+	// Refreshes is the number of successful refresh-ahead reloads
+	refreshes int64
+
+	// RefreshFailures is the number of refresh-ahead reloads that returned an error
+	refreshFailures int64
+
+	// Panics is the number of panics raised by wrapped functions and loaders
+	panics int64
+
+	// WriteFailures is the number of writes the configured Writer failed to apply
+	writeFailures int64
+
+	// WriteQueueDepth is the number of write-behind operations waiting to be flushed
+	writeQueueDepth int64
+
+	// evictionsByReason counts removed entries by EvictReason
+	evictionsByReason [numEvictReasons]int64
+
+	// errorsByOperation counts failed store operations, indexed like
+	// errorOperations
+	errorsByOperation [len(errorOperations)]int64
+
+	// circuitState is the current CircuitState of the Redis circuit breaker
+	circuitState int64
+
+	// circuitOpens is the number of times the circuit breaker opened
+	circuitOpens int64
+
+	// shortCircuits is the number of operations the open circuit breaker
+	// answered without contacting Redis
+	shortCircuits int64
+}
+
+// errorOperations are the operations whose store errors are counted
+var errorOperations = [...]metrics.Operation{
+	metrics.OperationGet,
+	metrics.OperationSet,
+	metrics.OperationDelete,
 }
 
 // Hits returns the number of cache hits
@@ -55,6 +96,90 @@ func (s *Stats) InFlight() int64 {
 	return atomic.LoadInt64(&s.inFlight)
 }
 
+// Refreshes returns the number of successful refresh-ahead reloads
+func (s *Stats) Refreshes() int64 {
+	return atomic.LoadInt64(&s.refreshes)
+}
+
+// RefreshFailures returns the number of failed refresh-ahead reloads
+func (s *Stats) RefreshFailures() int64 {
+	return atomic.LoadInt64(&s.refreshFailures)
+}
+
+// Panics returns the number of panics raised by wrapped functions and loaders
+func (s *Stats) Panics() int64 {
+	return atomic.LoadInt64(&s.panics)
+}
+
+// EvictionsFor returns the number of entries removed for reason
+func (s *Stats) EvictionsFor(reason EvictReason) int64 {
+	if reason < 0 || reason >= numEvictReasons {
+		return 0
+	}
+	return atomic.LoadInt64(&s.evictionsByReason[reason])
+}
+
+// EvictionsByReason returns the number of removed entries for each reason
+// that occurred, keyed by EvictReason.Label. Besides evictions it counts
+// entries that were replaced, deleted or cleared.
+func (s *Stats) EvictionsByReason() map[string]int64 {
+	counts := make(map[string]int64)
+	for reason := EvictReason(0); reason < numEvictReasons; reason++ {
+		if n := s.EvictionsFor(reason); n > 0 {
+			counts[reason.Label()] = n
+		}
+	}
+	return counts
+}
+
+// Errors returns the number of store operations that failed
+func (s *Stats) Errors() int64 {
+	var total int64
+	for i := range s.errorsByOperation {
+		total += atomic.LoadInt64(&s.errorsByOperation[i])
+	}
+	return total
+}
+
+// ErrorsByOperation returns the number of failed store operations for each
+// operation that failed, keyed by metrics.Operation
+func (s *Stats) ErrorsByOperation() map[string]int64 {
+	counts := make(map[string]int64)
+	for i, op := range errorOperations {
+		if n := atomic.LoadInt64(&s.errorsByOperation[i]); n > 0 {
+			counts[string(op)] = n
+		}
+	}
+	return counts
+}
+
+// CircuitState returns the current state of the Redis circuit breaker
+func (s *Stats) CircuitState() CircuitState {
+	return CircuitState(atomic.LoadInt64(&s.circuitState))
+}
+
+// CircuitOpens returns the number of times the circuit breaker opened
+func (s *Stats) CircuitOpens() int64 {
+	return atomic.LoadInt64(&s.circuitOpens)
+}
+
+// ShortCircuits returns the number of operations the open circuit breaker
+// answered without contacting Redis
+func (s *Stats) ShortCircuits() int64 {
+	return atomic.LoadInt64(&s.shortCircuits)
+}
+
+// WriteFailures returns the number of writes the configured Writer failed to
+// apply, after retries in write-behind mode
+func (s *Stats) WriteFailures() int64 {
+	return atomic.LoadInt64(&s.writeFailures)
+}
+
+// WriteQueueDepth returns the number of write-behind operations waiting to be flushed
+func (s *Stats) WriteQueueDepth() int64 {
+	return atomic.LoadInt64(&s.writeQueueDepth)
+}
+
 // HitRate returns the cache hit rate as a percentage (0-100)
 func (s *Stats) HitRate() float64 {
 	hits := s.Hits()
@@ -81,6 +206,18 @@ func (s *Stats) Reset() {
 	atomic.StoreInt64(&s.invalidations, 0)
 	atomic.StoreInt64(&s.keyCount, 0)
 	atomic.StoreInt64(&s.inFlight, 0)
+	atomic.StoreInt64(&s.refreshes, 0)
+	atomic.StoreInt64(&s.refreshFailures, 0)
+	atomic.StoreInt64(&s.panics, 0)
+	atomic.StoreInt64(&s.writeFailures, 0)
+	atomic.StoreInt64(&s.circuitOpens, 0)
+	atomic.StoreInt64(&s.shortCircuits, 0)
+	for i := range s.evictionsByReason {
+		atomic.StoreInt64(&s.evictionsByReason[i], 0)
+	}
+	for i := range s.errorsByOperation {
+		atomic.StoreInt64(&s.errorsByOperation[i], 0)
+	}
 }
 
 // Internal methods for updating stats (not exported)
@@ -97,6 +234,21 @@ func (s *Stats) incEvictions() {
 	atomic.AddInt64(&s.evictions, 1)
 }
 
+func (s *Stats) incEvictionsFor(reason EvictReason) {
+	if reason >= 0 && reason < numEvictReasons {
+		atomic.AddInt64(&s.evictionsByReason[reason], 1)
+	}
+}
+
+func (s *Stats) incErrors(op metrics.Operation) {
+	for i, known := range errorOperations {
+		if known == op {
+			atomic.AddInt64(&s.errorsByOperation[i], 1)
+			return
+		}
+	}
+}
+
 func (s *Stats) incInvalidations() {
 	atomic.AddInt64(&s.invalidations, 1)
 }
@@ -112,3 +264,35 @@ func (s *Stats) incInFlight() {
 func (s *Stats) decInFlight() {
 	atomic.AddInt64(&s.inFlight, -1)
 }
+
+func (s *Stats) incRefreshes() {
+	atomic.AddInt64(&s.refreshes, 1)
+}
+
+func (s *Stats) incRefreshFailures() {
+	atomic.AddInt64(&s.refreshFailures, 1)
+}
+
+func (s *Stats) incPanics() {
+	atomic.AddInt64(&s.panics, 1)
+}
+
+func (s *Stats) incWriteFailures() {
+	atomic.AddInt64(&s.writeFailures, 1)
+}
+
+func (s *Stats) setWriteQueueDepth(depth int64) {
+	atomic.StoreInt64(&s.writeQueueDepth, depth)
+}
+
+func (s *Stats) setCircuitState(state CircuitState) {
+	atomic.StoreInt64(&s.circuitState, int64(state))
+}
+
+func (s *Stats) incCircuitOpens() {
+	atomic.AddInt64(&s.circuitOpens, 1)
+}
+
+func (s *Stats) incShortCircuits() {
+	atomic.AddInt64(&s.shortCircuits, 1)
+}
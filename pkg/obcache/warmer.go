@@ -0,0 +1,316 @@
+package obcache
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/metrics"
+)
+
+// WarmLoader loads the value for a single key during cache warming
+type WarmLoader func(ctx context.Context, key string) (any, error)
+
+// KeySource yields the keys to warm. It is compatible with iter.Seq[string],
+// so range-over-func iterators can be passed directly.
+type KeySource = iter.Seq[string]
+
+// KeysFromSlice returns a KeySource that yields every key in the slice
+func KeysFromSlice(keys []string) KeySource {
+	return func(yield func(string) bool) {
+		for _, key := range keys {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// KeysFromChan returns a KeySource that yields keys until the channel is closed
+func KeysFromChan(keys <-chan string) KeySource {
+	return func(yield func(string) bool) {
+		for key := range keys {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// WarmProgress is a snapshot of a warming run
+type WarmProgress struct {
+	// Loaded is the number of keys loaded and stored in the cache
+	Loaded int64
+
+	// Failed is the number of keys whose loader or Set returned an error
+	Failed int64
+
+	// Skipped is the number of keys skipped because they were already cached
+	Skipped int64
+
+	// Elapsed is the time since the warming run started
+	Elapsed time.Duration
+}
+
+// Processed returns the total number of keys handled so far
+func (p WarmProgress) Processed() int64 {
+	return p.Loaded + p.Failed + p.Skipped
+}
+
+// WarmOptions holds configuration options for cache warming
+type WarmOptions struct {
+	// Concurrency is the maximum number of loaders running at once
+	// Default: 1
+	Concurrency int
+
+	// Rate limits how many keys are loaded per second (0 means unlimited)
+	Rate float64
+
+	// TTL is the TTL applied to warmed entries (0 uses the cache default)
+	TTL time.Duration
+
+	// SkipExisting skips keys that are already present in the cache
+	SkipExisting bool
+
+	// OnProgress is called after every processed key with a progress snapshot
+	OnProgress func(WarmProgress)
+}
+
+// WarmOption is a function that configures WarmOptions
+type WarmOption func(*WarmOptions)
+
+// WithWarmConcurrency sets the maximum number of concurrent loaders
+func WithWarmConcurrency(n int) WarmOption {
+	return func(opts *WarmOptions) {
+		opts.Concurrency = n
+	}
+}
+
+// WithWarmRate limits warming to the given number of keys per second
+func WithWarmRate(perSecond float64) WarmOption {
+	return func(opts *WarmOptions) {
+		opts.Rate = perSecond
+	}
+}
+
+// WithWarmTTL sets the TTL for warmed entries
+func WithWarmTTL(ttl time.Duration) WarmOption {
+	return func(opts *WarmOptions) {
+		opts.TTL = ttl
+	}
+}
+
+// WithWarmSkipExisting skips keys that are already cached
+func WithWarmSkipExisting() WarmOption {
+	return func(opts *WarmOptions) {
+		opts.SkipExisting = true
+	}
+}
+
+// WithWarmProgress sets a callback that receives progress snapshots
+func WithWarmProgress(fn func(WarmProgress)) WarmOption {
+	return func(opts *WarmOptions) {
+		opts.OnProgress = fn
+	}
+}
+
+// Warmer fills a cache from a key source before it takes traffic
+type Warmer struct {
+	cache  *Cache
+	loader WarmLoader
+	opts   *WarmOptions
+
+	// progress counters for the current run
+	loaded  int64
+	failed  int64
+	skipped int64
+	start   int64
+
+	progressMu sync.Mutex
+	running    int32
+	ready      int32
+	done       chan struct{}
+	doneOnce   sync.Once
+}
+
+// NewWarmer creates a Warmer that loads values with loader and stores them in cache
+func NewWarmer(cache *Cache, loader WarmLoader, options ...WarmOption) *Warmer {
+	opts := &WarmOptions{
+		Concurrency: 1,
+	}
+
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+
+	return &Warmer{
+		cache:  cache,
+		loader: loader,
+		opts:   opts,
+		done:   make(chan struct{}),
+	}
+}
+
+// Warm loads every key from keys into the cache with bounded parallelism.
+// Loader failures are counted in the returned progress rather than aborting
+// the run; the returned error is non-nil only if ctx is cancelled or another
+// run is still in progress (ErrWarmInProgress). The Warmer is marked ready
+// once a run completes without cancellation.
+func (w *Warmer) Warm(ctx context.Context, keys KeySource) (WarmProgress, error) {
+	if !atomic.CompareAndSwapInt32(&w.running, 0, 1) {
+		return w.Progress(), ErrWarmInProgress
+	}
+	defer atomic.StoreInt32(&w.running, 0)
+
+	atomic.StoreInt64(&w.loaded, 0)
+	atomic.StoreInt64(&w.failed, 0)
+	atomic.StoreInt64(&w.skipped, 0)
+	atomic.StoreInt64(&w.start, w.cache.clock.Now().UnixNano())
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+
+	for i := 0; i < w.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				w.warmKey(ctx, key)
+			}
+		}()
+	}
+
+	var tick <-chan time.Time
+	if w.opts.Rate > 0 {
+		ticker := w.cache.clock.NewTicker(time.Duration(float64(time.Second) / w.opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C()
+	}
+
+	// The first key goes out at once; the rate spaces out the ones after it
+	first := true
+	keys(func(key string) bool {
+		if tick != nil && !first {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		first = false
+
+		select {
+		case work <- key:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+
+	close(work)
+	wg.Wait()
+
+	progress := w.Progress()
+	if err := ctx.Err(); err != nil {
+		return progress, err
+	}
+
+	atomic.StoreInt32(&w.ready, 1)
+	w.doneOnce.Do(func() { close(w.done) })
+
+	return progress, nil
+}
+
+// Progress returns a snapshot of the current or most recent warming run
+func (w *Warmer) Progress() WarmProgress {
+	return WarmProgress{
+		Loaded:  atomic.LoadInt64(&w.loaded),
+		Failed:  atomic.LoadInt64(&w.failed),
+		Skipped: atomic.LoadInt64(&w.skipped),
+		Elapsed: w.cache.clock.Now().Sub(time.Unix(0, atomic.LoadInt64(&w.start))),
+	}
+}
+
+// Ready reports whether a warming run has completed
+func (w *Warmer) Ready() bool {
+	return atomic.LoadInt32(&w.ready) == 1
+}
+
+// Done returns a channel that is closed when the first warming run completes
+func (w *Warmer) Done() <-chan struct{} {
+	return w.done
+}
+
+// ReadinessHandler returns an HTTP handler suitable for a readiness probe.
+// It responds 503 Service Unavailable until warming completes and 200 OK
+// afterwards, with the current progress as a JSON body.
+func (w *Warmer) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+
+		status := http.StatusServiceUnavailable
+		if w.Ready() {
+			status = http.StatusOK
+		}
+		rw.WriteHeader(status)
+
+		progress := w.Progress()
+		_ = json.NewEncoder(rw).Encode(map[string]any{ //nolint:errcheck // Headers already written
+			"ready":   w.Ready(),
+			"loaded":  progress.Loaded,
+			"failed":  progress.Failed,
+			"skipped": progress.Skipped,
+		})
+	})
+}
+
+// warmKey loads and stores a single key, updating progress and metrics
+func (w *Warmer) warmKey(ctx context.Context, key string) {
+	if w.opts.SkipExisting && w.cache.Has(key) {
+		atomic.AddInt64(&w.skipped, 1)
+		w.recordWarm("skipped")
+		return
+	}
+
+	value, err := w.loader(ctx, key)
+	if err == nil {
+		// Warmed values come from the backing store, so they are not written
+		// back to it
+		err = w.cache.set(key, value, &setOptions{ttl: w.opts.TTL, jitter: w.cache.config.TTLJitter})
+	}
+
+	if err != nil {
+		atomic.AddInt64(&w.failed, 1)
+		w.recordWarm("error")
+	} else {
+		atomic.AddInt64(&w.loaded, 1)
+		w.recordWarm("loaded")
+	}
+}
+
+// recordWarm reports a processed key to the metrics exporter and progress callback
+func (w *Warmer) recordWarm(result string) {
+	if w.cache.metricsExporter != nil {
+		labels := make(metrics.Labels, len(w.cache.metricsLabels)+1)
+		for k, v := range w.cache.metricsLabels {
+			labels[k] = v
+		}
+		labels["result"] = result
+		_ = w.cache.metricsExporter.IncrementCounter(metrics.DefaultMetricNames().CacheWarmKeysTotal, labels) //nolint:errcheck // Metrics are best-effort
+	}
+
+	if w.opts.OnProgress != nil {
+		w.progressMu.Lock()
+		w.opts.OnProgress(w.Progress())
+		w.progressMu.Unlock()
+	}
+}
@@ -0,0 +1,202 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmerLoadsAllKeys(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	warmer := NewWarmer(cache, func(_ context.Context, key string) (any, error) {
+		return "value-" + key, nil
+	}, WithWarmConcurrency(4))
+
+	progress, err := warmer.Warm(context.Background(), KeysFromSlice(keys))
+	if err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if progress.Loaded != 50 {
+		t.Fatalf("Expected 50 loaded keys, got %d", progress.Loaded)
+	}
+
+	value, found := cache.Get("key-7")
+	if !found || value != "value-key-7" {
+		t.Fatalf("Expected warmed value, got %v (found=%v)", value, found)
+	}
+
+	if !warmer.Ready() {
+		t.Fatal("Expected warmer to be ready after completion")
+	}
+	select {
+	case <-warmer.Done():
+	default:
+		t.Fatal("Expected Done channel to be closed")
+	}
+}
+
+func TestWarmerConcurrencyLimit(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	var current, peak int64
+	warmer := NewWarmer(cache, func(_ context.Context, key string) (any, error) {
+		n := atomic.AddInt64(&current, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt64(&current, -1)
+		return key, nil
+	}, WithWarmConcurrency(3))
+
+	keys := make(chan string)
+	go func() {
+		for i := 0; i < 20; i++ {
+			keys <- fmt.Sprintf("key-%d", i)
+		}
+		close(keys)
+	}()
+
+	if _, err := warmer.Warm(context.Background(), KeysFromChan(keys)); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if peak > 3 {
+		t.Fatalf("Expected at most 3 concurrent loaders, saw %d", peak)
+	}
+}
+
+func TestWarmerFailuresAndSkips(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+	_ = cache.Set("existing", "cached", time.Hour)
+
+	var mu sync.Mutex
+	var snapshots []WarmProgress
+
+	warmer := NewWarmer(cache, func(_ context.Context, key string) (any, error) {
+		if key == "bad" {
+			return nil, errors.New("backend down")
+		}
+		return key, nil
+	}, WithWarmSkipExisting(), WithWarmProgress(func(p WarmProgress) {
+		mu.Lock()
+		snapshots = append(snapshots, p)
+		mu.Unlock()
+	}))
+
+	progress, err := warmer.Warm(context.Background(), KeysFromSlice([]string{"existing", "bad", "good"}))
+	if err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if progress.Loaded != 1 || progress.Failed != 1 || progress.Skipped != 1 {
+		t.Fatalf("Unexpected progress: %+v", progress)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("Expected 3 progress callbacks, got %d", len(snapshots))
+	}
+	if snapshots[2].Processed() != 3 {
+		t.Fatalf("Expected final snapshot to report 3 processed keys, got %d", snapshots[2].Processed())
+	}
+	if value, _ := cache.Get("existing"); value != "cached" {
+		t.Fatalf("Expected existing entry to be left alone, got %v", value)
+	}
+}
+
+func TestWarmerCancellationAndReadiness(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	warmer := NewWarmer(cache, func(_ context.Context, key string) (any, error) {
+		return key, nil
+	}, WithWarmRate(10))
+
+	handler := warmer.ReadinessHandler()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 before warming, got %d", rec.Code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	progress, err := warmer.Warm(ctx, KeysFromSlice(keys))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if progress.Loaded >= 100 {
+		t.Fatalf("Expected rate limit to stop warming early, loaded %d", progress.Loaded)
+	}
+	if warmer.Ready() {
+		t.Fatal("Expected warmer not to be ready after cancellation")
+	}
+
+	if _, err := warmer.Warm(context.Background(), KeysFromSlice(keys[:2])); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 after warming, got %d", rec.Code)
+	}
+}
+
+func TestWarmerRejectsOverlappingRuns(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	warmer := NewWarmer(cache, func(_ context.Context, key string) (any, error) {
+		if key == "slow" {
+			close(started)
+			<-release
+		}
+		return key, nil
+	})
+
+	done := make(chan WarmProgress)
+	go func() {
+		progress, _ := warmer.Warm(context.Background(), KeysFromSlice([]string{"slow", "a"}))
+		done <- progress
+	}()
+	<-started
+
+	if _, err := warmer.Warm(context.Background(), KeysFromSlice([]string{"b"})); !errors.Is(err, ErrWarmInProgress) {
+		t.Fatalf("Expected ErrWarmInProgress for an overlapping run, got %v", err)
+	}
+
+	close(release)
+	if progress := <-done; progress.Loaded != 2 {
+		t.Fatalf("Expected the first run to load its 2 keys undisturbed, got %+v", progress)
+	}
+	if cache.Has("b") {
+		t.Fatal("Expected the rejected run to load nothing")
+	}
+
+	// Sequential runs are still allowed
+	if _, err := warmer.Warm(context.Background(), KeysFromSlice([]string{"b"})); err != nil {
+		t.Fatalf("Expected a later run to succeed, got %v", err)
+	}
+}
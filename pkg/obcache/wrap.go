@@ -2,9 +2,15 @@ package obcache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
+	"slices"
 	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
 )
 
 // cachedError represents an error that has been cached
@@ -29,6 +35,69 @@ type WrapOptions struct {
 
 	// ErrorTTL is the TTL for cached errors (defaults to TTL if not set)
 	ErrorTTL time.Duration
+
+	// RefreshAhead is the fraction of an entry's TTL (between 0 and 1) after
+	// which a cache hit triggers an asynchronous reload of the entry.
+	// Zero disables refresh-ahead.
+	RefreshAhead float64
+
+	// TTLJitter is the maximum fraction of TTL randomly subtracted from each
+	// cached result (defaults to the cache's Config.TTLJitter)
+	TTLJitter float64
+
+	// EarlyExpirationBeta enables XFetch probabilistic early recomputation
+	// (defaults to the cache's Config.EarlyExpirationBeta)
+	EarlyExpirationBeta float64
+
+	// Sliding makes TTL an idle timeout that restarts on every cache hit
+	Sliding bool
+
+	// MaxLifetime caps the total lifetime of sliding entries (zero means no cap)
+	MaxLifetime time.Duration
+
+	// DistributedLeaseTTL enables deduplication of loads across processes
+	// sharing a Redis store: only the holder of a lease of this duration
+	// loads a missing key. Zero disables it; stores without lease support
+	// fall back to per-process deduplication.
+	DistributedLeaseTTL time.Duration
+
+	// LeasePollInterval is how often processes waiting on another lease
+	// holder check for its result
+	// Default: 50 milliseconds
+	LeasePollInterval time.Duration
+
+	// LoadTimeout bounds how long a load may take. The computation's
+	// context gets this deadline and callers stop waiting once it passes.
+	// Zero means no timeout.
+	LoadTimeout time.Duration
+
+	// DetachContext runs the shared computation with a context that is not
+	// cancelled when the caller that started it is, so one caller giving up
+	// does not fail the others waiting on the same key
+	DetachContext bool
+
+	// PanicAsError returns a panic in the loaded function as a *PanicError
+	// instead of re-raising it in every caller. Only applies to functions
+	// with an error result.
+	PanicAsError bool
+
+	// IgnoreArgs lists parameter positions (0-based, counting a leading
+	// context) left out of key generation, such as loggers or DB handles
+	IgnoreArgs []int
+
+	// Tags labels every cached result so a group of them, such as all
+	// results of one function, can be removed with DeleteByTag
+	Tags []string
+
+	// Namespace prefixes every key of the wrapped function so functions
+	// sharing a cache and argument types do not overwrite each other's
+	// results. Wrap defaults it to the function's package-qualified name,
+	// unless a custom key function is set with WithKeyFunc.
+	Namespace string
+
+	// customKeyFunc records that WithKeyFunc was used, which leaves the key
+	// space to the caller
+	customKeyFunc bool
 }
 
 // WrapOption is a function that configures WrapOptions
@@ -41,10 +110,24 @@ func WithTTL(ttl time.Duration) WrapOption {
 	}
 }
 
-// WithKeyFunc sets a custom key generation function for the wrapped function
+// WithKeyFunc sets a custom key generation function for the wrapped function.
+// Keys are used as returned, without the automatic function namespace;
+// combine with WithNamespace to prefix them.
 func WithKeyFunc(keyFunc KeyGenFunc) WrapOption {
 	return func(opts *WrapOptions) {
 		opts.KeyFunc = keyFunc
+		opts.customKeyFunc = true
+	}
+}
+
+// WithNamespace sets the namespace that prefixes the wrapped function's keys,
+// replacing the default derived from the function's name. Use it to give a
+// function a name that is stable across refactors, or to share results
+// between functions deliberately. Remove all results in a namespace with
+// Cache.InvalidateNamespace.
+func WithNamespace(namespace string) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.Namespace = namespace
 	}
 }
 
@@ -70,19 +153,136 @@ func WithErrorTTL(ttl time.Duration) WrapOption {
 	}
 }
 
+// WithRefreshAhead enables asynchronous refresh of entries that are accessed
+// after the given fraction of their TTL has elapsed. For example, 0.8 reloads
+// an entry in the background when it is hit during the last 20% of its TTL.
+func WithRefreshAhead(fraction float64) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.RefreshAhead = fraction
+	}
+}
+
+// WithTTLJitter randomises the TTL of cached results by up to fraction of the TTL
+func WithTTLJitter(fraction float64) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.TTLJitter = fraction
+	}
+}
+
+// WithEarlyExpiration enables XFetch probabilistic early recomputation with the
+// given beta. Results are recomputed ahead of expiry with a probability that
+// grows as expiry approaches and with the time the function took to compute.
+func WithEarlyExpiration(beta float64) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.EarlyExpirationBeta = beta
+	}
+}
+
+// WithSlidingExpiration enables sliding expiration for cached results: the TTL
+// restarts on every cache hit, up to maxLifetime in total when it is positive
+func WithSlidingExpiration(maxLifetime time.Duration) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.Sliding = true
+		opts.MaxLifetime = maxLifetime
+	}
+}
+
+// WithDistributedSingleflight deduplicates loads across every process that
+// shares the cache's Redis store. The first process to miss takes a lease
+// for leaseTTL and loads the value; others wait for it to appear in the
+// store. If the holder crashes, another process takes over once the lease
+// expires, so leaseTTL should exceed the expected load time.
+func WithDistributedSingleflight(leaseTTL time.Duration) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.DistributedLeaseTTL = leaseTTL
+	}
+}
+
+// WithLeasePollInterval sets how often processes waiting on a distributed
+// load check for its result
+func WithLeasePollInterval(interval time.Duration) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.LeasePollInterval = interval
+	}
+}
+
+// WithLoadTimeout bounds each load: the wrapped function's context carries
+// the deadline, and callers stop waiting with context.DeadlineExceeded once
+// it passes
+func WithLoadTimeout(timeout time.Duration) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.LoadTimeout = timeout
+	}
+}
+
+// WithDetachedContext detaches the shared computation from the cancellation
+// of the caller that started it. Each caller still stops waiting when its own
+// context is done, but the computation runs to completion (within
+// LoadTimeout) and caches its result for everyone else.
+func WithDetachedContext() WrapOption {
+	return func(opts *WrapOptions) {
+		opts.DetachContext = true
+	}
+}
+
+// WithPanicAsError turns a panic in the wrapped function into a *PanicError
+// returned to every caller sharing the call, instead of re-raising the panic
+// in each of them. Functions without an error result still panic.
+func WithPanicAsError() WrapOption {
+	return func(opts *WrapOptions) {
+		opts.PanicAsError = true
+	}
+}
+
+// WithIgnoreArgs leaves the parameters at the given positions out of key
+// generation, for arguments that do not affect the result such as loggers or
+// database handles. Positions are 0-based and count a leading context.
+// Ignoring a variadic parameter ignores all of its arguments.
+func WithIgnoreArgs(positions ...int) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.IgnoreArgs = append(opts.IgnoreArgs, positions...)
+	}
+}
+
+// WithResultTags labels every result cached for the wrapped function with
+// tags, so they can be removed together with DeleteByTag
+func WithResultTags(tags ...string) WrapOption {
+	return func(opts *WrapOptions) {
+		opts.Tags = append(opts.Tags, tags...)
+	}
+}
+
 // Wrap wraps any function with caching using Go generics
 // T must be a function type
 func Wrap[T any](cache *Cache, fn T, options ...WrapOption) T {
+	return wrapFunction(cache, fn, newWrapOptions(cache, options))
+}
+
+// newWrapOptions applies options on top of the cache's defaults
+func newWrapOptions(cache *Cache, options []WrapOption) *WrapOptions {
 	opts := &WrapOptions{
-		TTL:     cache.config.DefaultTTL,
-		KeyFunc: cache.getKeyGenFunc(),
+		TTL:                 cache.config.DefaultTTL,
+		KeyFunc:             cache.getKeyGenFunc(),
+		TTLJitter:           cache.config.TTLJitter,
+		EarlyExpirationBeta: cache.config.EarlyExpirationBeta,
+		LeasePollInterval:   50 * time.Millisecond,
 	}
 
 	for _, opt := range options {
 		opt(opts)
 	}
 
-	return wrapFunction(cache, fn, opts)
+	return opts
+}
+
+// TryWrap is like Wrap but returns an error instead of panicking when fn
+// cannot be wrapped (see ValidateWrappableFunction)
+func TryWrap[T any](cache *Cache, fn T, options ...WrapOption) (T, error) {
+	if err := ValidateWrappableFunction(fn); err != nil {
+		var zero T
+		return zero, fmt.Errorf("obcache.TryWrap: %w", err)
+	}
+	return Wrap(cache, fn, options...), nil
 }
 
 // wrapFunction performs the actual function wrapping using reflection
@@ -95,6 +295,10 @@ func wrapFunction[T any](cache *Cache, fn T, opts *WrapOptions) T {
 		panic("obcache.Wrap: argument must be a function")
 	}
 
+	if opts.Namespace == "" && !opts.customKeyFunc {
+		opts.Namespace = FuncNamespace(fn)
+	}
+
 	// Create the wrapper function
 	wrapper := reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
 		return executeWrappedFunction(cache, fnValue, fnType, opts, args)
@@ -105,47 +309,109 @@ func wrapFunction[T any](cache *Cache, fn T, opts *WrapOptions) T {
 
 // executeWrappedFunction handles the core wrapping logic
 func executeWrappedFunction(cache *Cache, fnValue reflect.Value, fnType reflect.Type, opts *WrapOptions, args []reflect.Value) []reflect.Value {
-	_, keyArgs := extractContextAndArgs(fnType, args)
-	key := opts.KeyFunc(keyArgs)
+	key := wrappedKey(opts, fnType, args)
 
 	// If caching is disabled, call original function directly
 	if opts.DisableCache {
-		return fnValue.Call(args)
+		return callFunction(fnValue, fnType, args)
 	}
 
 	hasErrorReturn := hasErrorReturn(fnType)
 
 	// Try to get from cache first
-	if cachedValue, found := cache.Get(key); found {
+	if cachedValue, cachedEntry, found := cache.lookup(context.Background(), key, opts.EarlyExpirationBeta); found {
+		if shouldRefreshAhead(opts, cachedValue, cachedEntry) {
+			refreshAhead(cache, fnValue, fnType, opts, detachContextArg(fnType, args), key, hasErrorReturn)
+		}
 		return convertCachedValue(cachedValue, fnType, hasErrorReturn)
 	}
 
 	return executeFunctionWithSingleflight(cache, fnValue, fnType, opts, args, key, hasErrorReturn)
 }
 
-// extractContextAndArgs extracts context and key args from function arguments
-func extractContextAndArgs(fnType reflect.Type, args []reflect.Value) (context.Context, []any) {
-	ctx := context.Background()
-	var keyArgs []any
+// namespaceSeparator ends the namespace part of a wrapped function's key
+const namespaceSeparator = "|"
 
-	// Detect context.Context as first parameter
-	if len(args) > 0 && fnType.In(0).String() == "context.Context" {
-		// First parameter is context.Context
-		ctx = args[0].Interface().(context.Context)
-		// Use remaining args for key generation
-		keyArgs = make([]any, len(args)-1)
-		for i := 1; i < len(args); i++ {
-			keyArgs[i-1] = args[i].Interface()
+// wrappedKey derives the cache key for a call to a wrapped function
+func wrappedKey(opts *WrapOptions, fnType reflect.Type, args []reflect.Value) string {
+	key := opts.KeyFunc(keyArgs(fnType, args, opts.IgnoreArgs))
+	if opts.Namespace == "" {
+		return key
+	}
+	return opts.Namespace + namespaceSeparator + key
+}
+
+// FuncNamespace returns the namespace Wrap uses by default for fn: its
+// package-qualified name as reported by runtime.FuncForPC, such as
+// "example.com/app/users.(*Repo).GetUser-fm". Anonymous functions are named
+// after the function defining them ("users.NewService.func1"). It returns
+// an empty string if fn is not a function.
+func FuncNamespace(fn any) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
+
+// contextType is the reflect.Type of context.Context
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// takesContext reports whether the function's first parameter is a context,
+// including interfaces that embed context.Context
+func takesContext(fnType reflect.Type) bool {
+	return fnType.NumIn() > 0 && fnType.In(0).Implements(contextType)
+}
+
+// contextArg returns the function's leading context argument, or Background
+// if it takes none or was passed nil
+func contextArg(fnType reflect.Type, args []reflect.Value) context.Context {
+	if len(args) > 0 && takesContext(fnType) {
+		if ctx, ok := args[0].Interface().(context.Context); ok && ctx != nil {
+			return ctx
 		}
-	} else {
-		// No context parameter, use all args for key generation
-		keyArgs = make([]any, len(args))
-		for i, arg := range args {
-			keyArgs[i] = arg.Interface()
+	}
+	return context.Background()
+}
+
+// keyArgs returns the arguments that make up the cache key: all of them
+// except a leading context and the ignored positions, with variadic
+// arguments expanded so f(1, 2) and f([]int{1, 2}...) share a key
+func keyArgs(fnType reflect.Type, args []reflect.Value, ignored []int) []any {
+	first := 0
+	if takesContext(fnType) {
+		first = 1
+	}
+
+	keyArgs := make([]any, 0, len(args))
+	for i := first; i < len(args); i++ {
+		if slices.Contains(ignored, i) {
+			continue
+		}
+		if fnType.IsVariadic() && i == len(args)-1 {
+			for j := 0; j < args[i].Len(); j++ {
+				keyArgs = append(keyArgs, args[i].Index(j).Interface())
+			}
+			continue
 		}
+		keyArgs = append(keyArgs, args[i].Interface())
 	}
 
-	return ctx, keyArgs
+	return keyArgs
+}
+
+// callFunction calls fn with args as received by a reflect.MakeFunc wrapper,
+// where variadic arguments arrive as a single slice
+func callFunction(fnValue reflect.Value, fnType reflect.Type, args []reflect.Value) []reflect.Value {
+	if fnType.IsVariadic() {
+		return fnValue.CallSlice(args)
+	}
+	return fnValue.Call(args)
 }
 
 // hasErrorReturn checks if function returns error as last parameter
@@ -156,38 +422,273 @@ func hasErrorReturn(fnType reflect.Type) bool {
 
 // executeFunctionWithSingleflight executes the function with singleflight pattern
 func executeFunctionWithSingleflight(cache *Cache, fnValue reflect.Value, fnType reflect.Type, opts *WrapOptions, args []reflect.Value, key string, hasErrorReturn bool) []reflect.Value {
-	// Use singleflight to prevent duplicate calls
+	ctx := contextArg(fnType, args)
+
+	// Functions without an error result cannot report cancellation, timeouts
+	// or recovered panics, so their callers always wait for the result
+	value, err := cache.load(ctx, key, opts, hasErrorReturn, wrappedLoadFunc(fnValue, fnType, args, hasErrorReturn))
+	if err != nil {
+		// Return the error in the function's expected format
+		return createErrorReturn(fnType, err)
+	}
+
+	// Convert the result back to the expected format
+	return convertComputedValue(value, fnType, hasErrorReturn)
+}
+
+// loadFunc computes a value using ctx and reports how long it took
+type loadFunc func(ctx context.Context) (any, time.Duration, error)
+
+// wrappedLoadFunc returns a loadFunc calling the wrapped function with args,
+// passing the load context in place of the caller's
+func wrappedLoadFunc(fnValue reflect.Value, fnType reflect.Type, args []reflect.Value, hasErrorReturn bool) loadFunc {
+	return func(ctx context.Context) (any, time.Duration, error) {
+		start := time.Now()
+		results := callFunction(fnValue, fnType, withContextArg(fnType, args, ctx))
+		computeTime := time.Since(start)
+		value, err := processResults(results, hasErrorReturn)
+		return value, computeTime, err
+	}
+}
+
+// GetOrLoad returns the cached value for key, calling loader to compute and
+// cache it on a miss. Concurrent misses for the same key share one loader
+// call; with WithDistributedSingleflight the sharing extends to every process
+// using the same Redis store. Options are those accepted by Wrap (KeyFunc
+// and WithoutCache do not apply).
+func (c *Cache) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context) (any, error), options ...WrapOption) (any, error) {
+	if c.closed.Load() {
+		return nil, ErrClosed
+	}
+
+	opts := newWrapOptions(c, options)
+
+	if value, _, found := c.lookup(ctx, key, opts.EarlyExpirationBeta); found {
+		return cachedResult(value)
+	}
+
+	return c.load(ctx, key, opts, true, func(ctx context.Context) (any, time.Duration, error) {
+		start := time.Now()
+		value, err := loader(ctx)
+		return value, time.Since(start), err
+	})
+}
+
+// load runs fn for key at most once across concurrent callers (and across
+// processes in distributed mode) and caches the result. When canFail is set
+// the caller stops waiting once ctx is done, and panics may be returned as
+// errors; the shared computation stores its result regardless, so later
+// callers still benefit from it.
+func (c *Cache) load(ctx context.Context, key string, opts *WrapOptions, canFail bool, fn loadFunc) (value any, err error) {
+	leases, distributed := c.store.(store.LeaseStore)
+	distributed = distributed && opts.DistributedLeaseTTL > 0 && !c.closed.Load()
+
 	compute := func() (any, error) {
-		results := fnValue.Call(args)
-		return processResults(results, hasErrorReturn)
+		defer c.countPanic()
+
+		loadCtx, cancel := loadContext(ctx, opts)
+		defer cancel()
+
+		if distributed {
+			return c.loadDistributed(loadCtx, leases, key, opts, canFail, fn)
+		}
+		return c.loadAndStore(loadCtx, key, opts, fn, 0)
+	}
+
+	waitCtx := context.Background()
+	if canFail {
+		waitCtx = ctx
+		if opts.LoadTimeout > 0 {
+			var cancel context.CancelFunc
+			waitCtx, cancel = context.WithTimeout(ctx, opts.LoadTimeout)
+			defer cancel()
+		}
+
+		if opts.PanicAsError {
+			defer func() {
+				if r := recover(); r != nil {
+					pe, ok := r.(*PanicError)
+					if !ok {
+						panic(r)
+					}
+					value, err = nil, pe
+				}
+			}()
+		}
 	}
 
 	// Execute with singleflight
-	cache.stats.incInFlight()
-	defer cache.stats.decInFlight()
+	c.stats.incInFlight()
+	defer c.stats.decInFlight()
+
+	// Contexts that can never be cancelled run the computation on the calling
+	// goroutine, as before contexts were honoured
+	if waitCtx.Done() == nil {
+		value, err, _ = c.sf.Do(key, compute)
+	} else {
+		value, err, _ = c.sf.DoContext(waitCtx, key, compute)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// countPanic records a panic unwinding through a load and lets it continue.
+// It must be deferred directly.
+func (c *Cache) countPanic() {
+	if r := recover(); r != nil {
+		c.stats.incPanics()
+		panic(r)
+	}
+}
+
+// loadContext derives the context a shared computation runs with
+func loadContext(ctx context.Context, opts *WrapOptions) (context.Context, context.CancelFunc) {
+	if opts.DetachContext {
+		ctx = context.WithoutCancel(ctx)
+	}
+	if opts.LoadTimeout > 0 {
+		return context.WithTimeout(ctx, opts.LoadTimeout)
+	}
+	return ctx, func() {}
+}
+
+// loadAndStore runs fn and caches its result (and its error, if error caching
+// is enabled), fenced on token if non-zero
+func (c *Cache) loadAndStore(ctx context.Context, key string, opts *WrapOptions, fn loadFunc, token int64) (any, error) {
+	value, computeTime, err := fn(ctx)
 
-	value, err, shared := cache.sf.Do(key, compute)
+	// Cancellation says nothing about the key, so it is never cached
+	if err != nil && (!opts.CacheErrors || isContextError(err)) {
+		return nil, err
+	}
 
+	ttl := opts.TTL
+	cached := value
 	if err != nil {
-		// Cache errors if enabled
-		if opts.CacheErrors && !shared {
-			errorTTL := opts.ErrorTTL
-			if errorTTL == 0 {
-				errorTTL = opts.TTL
+		cached = cachedError{Err: err}
+		computeTime = 0
+		if opts.ErrorTTL > 0 {
+			ttl = opts.ErrorTTL
+		}
+	}
+
+	so := wrappedSetOptions(ttl, opts, computeTime)
+	so.leaseToken = token
+	// A rejected fenced write means our lease expired and a newer holder
+	// owns the key; the value we loaded is still valid to return
+	_ = c.set(key, cached, so) //nolint:errcheck // Caching the result is best-effort
+
+	return value, err
+}
+
+// isContextError reports whether err stems from context cancellation or a deadline
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// cachedResult unpacks a cached value, turning cached errors back into errors
+func cachedResult(value any) (any, error) {
+	if ce, ok := value.(cachedError); ok {
+		return nil, ce.Err
+	}
+	return value, nil
+}
+
+// shouldRefreshAhead reports whether a cache hit falls inside the refresh-ahead window
+func shouldRefreshAhead(opts *WrapOptions, cachedValue any, e *entry.Entry) bool {
+	if opts.RefreshAhead <= 0 || opts.RefreshAhead >= 1 || e == nil || !e.HasExpiry() || e.IsSliding() {
+		return false
+	}
+	if _, isErr := cachedValue.(cachedError); isErr {
+		return false
+	}
+
+	lifetime := e.Expiry().Sub(e.CreatedAt)
+	if lifetime <= 0 {
+		return false
+	}
+
+	return float64(e.Age()) >= float64(lifetime)*opts.RefreshAhead
+}
+
+// refreshAhead reloads an entry in the background through the singleflight
+// group. Hits that find a reload already in flight join it, so the outcome
+// is counted by the reload itself, once, and nobody waits for the result.
+func refreshAhead(cache *Cache, fnValue reflect.Value, fnType reflect.Type, opts *WrapOptions, args []reflect.Value, key string, hasErrorReturn bool) {
+	cache.sf.DoChan(key, func() (any, error) {
+		refreshed := false
+		defer func() {
+			if refreshed {
+				cache.stats.incRefreshes()
+			} else {
+				cache.stats.incRefreshFailures()
 			}
-			cache.Set(key, cachedError{Err: err}, errorTTL)
+		}()
+		defer cache.countPanic()
+
+		start := time.Now()
+		results := callFunction(fnValue, fnType, args)
+		computeTime := time.Since(start)
+		value, err := processResults(results, hasErrorReturn)
+		if err != nil {
+			return nil, err
 		}
-		// Return the error in the function's expected format
-		return createErrorReturn(fnType, err)
+		if setErr := setWrappedResult(cache, key, value, opts.TTL, opts, computeTime); setErr != nil {
+			return nil, setErr
+		}
+		refreshed = true
+		return value, nil
+	})
+}
+
+// detachContextArg returns a copy of args whose leading context, if any, is
+// detached from the caller's cancellation so background work can outlive it
+func detachContextArg(fnType reflect.Type, args []reflect.Value) []reflect.Value {
+	return withContextArg(fnType, args, context.WithoutCancel(contextArg(fnType, args)))
+}
+
+// withContextArg returns args with the leading context, if the function takes
+// one, replaced by ctx. Parameters of a narrower type than context.Context
+// that ctx does not satisfy keep the caller's value.
+func withContextArg(fnType reflect.Type, args []reflect.Value, ctx context.Context) []reflect.Value {
+	if len(args) == 0 || !takesContext(fnType) {
+		return args
 	}
 
-	// Store in cache if this wasn't a shared call
-	if !shared {
-		cache.Set(key, value, opts.TTL)
+	var value reflect.Value
+	switch paramType := fnType.In(0); {
+	case paramType == contextType:
+		value = reflect.ValueOf(&ctx).Elem()
+	case reflect.TypeOf(ctx).AssignableTo(paramType):
+		value = reflect.ValueOf(ctx)
+	default:
+		return args
 	}
 
-	// Convert the result back to the expected format
-	return convertComputedValue(value, fnType, hasErrorReturn)
+	replaced := make([]reflect.Value, len(args))
+	copy(replaced, args)
+	replaced[0] = value
+	return replaced
+}
+
+// setWrappedResult stores a wrapped function result using the wrap options
+func setWrappedResult(cache *Cache, key string, value any, ttl time.Duration, opts *WrapOptions, computeTime time.Duration) error {
+	return cache.set(key, value, wrappedSetOptions(ttl, opts, computeTime))
+}
+
+// wrappedSetOptions builds the write parameters for a wrapped function result
+func wrappedSetOptions(ttl time.Duration, opts *WrapOptions, computeTime time.Duration) *setOptions {
+	return &setOptions{
+		ttl:         ttl,
+		jitter:      opts.TTLJitter,
+		computeTime: computeTime,
+		sliding:     opts.Sliding,
+		maxLifetime: opts.MaxLifetime,
+		tags:        opts.Tags,
+	}
 }
 
 // processResults processes function results for caching
@@ -372,15 +873,10 @@ func WrapFunc2WithError[T1, T2, R any](cache *Cache, fn func(T1, T2) (R, error),
 func ValidateWrappableFunction(fn any) error {
 	fnType := reflect.TypeOf(fn)
 
-	if fnType.Kind() != reflect.Func {
+	if fnType == nil || fnType.Kind() != reflect.Func {
 		return fmt.Errorf("not a function: %T", fn)
 	}
 
-	// Check if function is variadic (not currently supported)
-	if fnType.IsVariadic() {
-		return fmt.Errorf("variadic functions are not currently supported")
-	}
-
 	// Validate return types
 	numOut := fnType.NumOut()
 	if numOut == 0 {
@@ -0,0 +1,92 @@
+package obcache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWrapVariadic(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	calls := 0
+	join := func(sep string, parts ...string) string {
+		calls++
+		return strings.Join(parts, sep)
+	}
+	wrapped := Wrap(cache, join)
+
+	if v := wrapped(",", "a", "b"); v != "a,b" {
+		t.Fatalf("Expected a,b, got %q", v)
+	}
+	// Expanded and spread arguments share a key
+	if v := wrapped(",", []string{"a", "b"}...); v != "a,b" || calls != 1 {
+		t.Fatalf("Expected cached a,b from a spread slice, got %q after %d calls", v, calls)
+	}
+	if v := wrapped(",", "a", "b", "c"); v != "a,b,c" || calls != 2 {
+		t.Fatalf("Expected a,b,c from a new call, got %q after %d calls", v, calls)
+	}
+	if v := wrapped(","); v != "" || calls != 3 {
+		t.Fatalf("Expected empty result with no variadic args, got %q after %d calls", v, calls)
+	}
+}
+
+// requestContext is an interface that embeds context.Context
+type requestContext interface {
+	context.Context
+	RequestID() string
+}
+
+type testRequestContext struct {
+	context.Context
+	id string
+}
+
+func (c testRequestContext) RequestID() string { return c.id }
+
+func TestWrapEmbeddedContextInterface(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	calls := 0
+	fn := func(ctx requestContext, id int) (string, error) {
+		calls++
+		return ctx.RequestID(), nil
+	}
+	wrapped := Wrap(cache, fn)
+
+	first, err := wrapped(testRequestContext{context.Background(), "req-1"}, 1)
+	if err != nil || first != "req-1" {
+		t.Fatalf("Expected req-1, got %q, %v", first, err)
+	}
+
+	// The context is not part of the key, so a different request hits the cache
+	if v, _ := wrapped(testRequestContext{context.Background(), "req-2"}, 1); v != "req-1" || calls != 1 {
+		t.Fatalf("Expected cached req-1, got %q after %d calls", v, calls)
+	}
+
+	// The original context value is still passed through to the function
+	if v, _ := wrapped(testRequestContext{context.Background(), "req-3"}, 2); v != "req-3" {
+		t.Fatalf("Expected req-3 for a new key, got %q", v)
+	}
+}
+
+func TestWrapIgnoreArgs(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	calls := 0
+	fn := func(ctx context.Context, logger *slog.Logger, id int) (int, error) {
+		calls++
+		return id * 10, nil
+	}
+	wrapped := Wrap(cache, fn, WithIgnoreArgs(1))
+
+	_, _ = wrapped(context.Background(), slog.Default(), 1)
+	if v, _ := wrapped(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), 1); v != 10 || calls != 1 {
+		t.Fatalf("Expected the logger to be ignored for keying, got %d after %d calls", v, calls)
+	}
+	if v, _ := wrapped(context.Background(), slog.Default(), 2); v != 20 || calls != 2 {
+		t.Fatalf("Expected a different id to miss, got %d after %d calls", v, calls)
+	}
+}
@@ -0,0 +1,107 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowLoad returns id after delay unless its context is done first
+func slowLoad(calls *int64, delay time.Duration) func(ctx context.Context, id int) (int, error) {
+	return func(ctx context.Context, id int) (int, error) {
+		atomic.AddInt64(calls, 1)
+		select {
+		case <-time.After(delay):
+			return id, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+func TestWrapHonoursCallerCancellation(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	var calls int64
+	wrapped := Wrap(cache, slowLoad(&calls, 100*time.Millisecond), WithDetachedContext())
+
+	// The caller gives up early but the detached computation still completes
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := wrapped(ctx, 7); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 80*time.Millisecond {
+		t.Fatalf("Expected caller to return at its deadline, took %v", elapsed)
+	}
+
+	waitForCondition(t, time.Second, func() bool { return cache.Len() == 1 })
+
+	if v, err := wrapped(context.Background(), 7); err != nil || v != 7 {
+		t.Fatalf("Expected cached result 7, got %d, %v", v, err)
+	}
+	if n := atomic.LoadInt64(&calls); n != 1 {
+		t.Fatalf("Expected 1 call, got %d", n)
+	}
+}
+
+func TestWrapLoadTimeout(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	var calls int64
+	wrapped := Wrap(cache, slowLoad(&calls, time.Second), WithLoadTimeout(30*time.Millisecond), WithErrorCaching())
+
+	start := time.Now()
+	if _, err := wrapped(context.Background(), 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Expected load to time out, took %v", elapsed)
+	}
+
+	// Timeouts are not cached, even with error caching enabled
+	waitForCondition(t, time.Second, func() bool { return cache.sf.InFlight() == 0 })
+	_, _ = wrapped(context.Background(), 1)
+	if n := atomic.LoadInt64(&calls); n != 2 {
+		t.Fatalf("Expected timed-out load to be retried, got %d calls", n)
+	}
+}
+
+func TestWrapDetachedContextSharedWaiters(t *testing.T) {
+	for _, detach := range []bool{false, true} {
+		cache, _ := New(NewDefaultConfig())
+
+		var calls int64
+		var options []WrapOption
+		if detach {
+			options = append(options, WithDetachedContext())
+		}
+		wrapped := Wrap(cache, slowLoad(&calls, 80*time.Millisecond), options...)
+
+		// The first caller starts the computation and then cancels
+		leaderCtx, cancelLeader := context.WithCancel(context.Background())
+		go func() { _, _ = wrapped(leaderCtx, 1) }()
+		waitForCondition(t, time.Second, func() bool { return atomic.LoadInt64(&calls) == 1 })
+
+		// A second caller joins the in-flight computation
+		result := make(chan error, 1)
+		go func() {
+			_, err := wrapped(context.Background(), 1)
+			result <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancelLeader()
+
+		err := <-result
+		if detach && err != nil {
+			t.Fatalf("Expected detached computation to succeed for other waiters, got %v", err)
+		}
+		if !detach && !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected shared computation to fail with the leader's cancellation, got %v", err)
+		}
+	}
+}
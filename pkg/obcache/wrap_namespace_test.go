@@ -0,0 +1,121 @@
+package obcache
+
+import (
+	"strings"
+	"testing"
+)
+
+func namespaceDouble(n int) int { return n * 2 }
+
+func namespaceTriple(n int) int { return n * 3 }
+
+func TestWrapNamespacesByFunction(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	double := Wrap(cache, namespaceDouble)
+	triple := Wrap(cache, namespaceTriple)
+
+	// Same arguments, different functions: results must not be shared
+	if got := double(5); got != 10 {
+		t.Fatalf("Expected 10, got %d", got)
+	}
+	if got := triple(5); got != 15 {
+		t.Fatalf("Expected 15 from separate namespace, got %d", got)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("Expected 2 entries, got %d", cache.Len())
+	}
+
+	for _, key := range cache.Keys() {
+		if !strings.HasPrefix(key, FuncNamespace(namespaceDouble)+"|") && !strings.HasPrefix(key, FuncNamespace(namespaceTriple)+"|") {
+			t.Fatalf("Expected key to be namespaced by function, got %q", key)
+		}
+	}
+}
+
+func TestFuncNamespace(t *testing.T) {
+	name := FuncNamespace(namespaceDouble)
+	if !strings.HasSuffix(name, "obcache.namespaceDouble") {
+		t.Fatalf("Expected package-qualified function name, got %q", name)
+	}
+	if FuncNamespace(namespaceTriple) == name {
+		t.Fatal("Expected different functions to have different namespaces")
+	}
+	if FuncNamespace(42) != "" {
+		t.Fatal("Expected empty namespace for a non-function")
+	}
+}
+
+func TestWrapWithNamespace(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	calls := 0
+	loadA := Wrap(cache, func(n int) int { calls++; return n }, WithNamespace("shared"))
+	loadB := Wrap(cache, func(n int) int { calls++; return n }, WithNamespace("shared"))
+
+	loadA(1)
+	loadB(1)
+	if calls != 1 {
+		t.Fatalf("Expected functions sharing a namespace to share results, got %d calls", calls)
+	}
+	if !cache.Has("shared|" + DefaultKeyFunc([]any{1})) {
+		t.Fatalf("Expected key in explicit namespace, got %v", cache.Keys())
+	}
+
+	// An explicit namespace also prefixes custom keys
+	custom := Wrap(cache, func(n int) int { return n }, WithNamespace("custom"), WithKeyFunc(SimpleKeyFunc))
+	custom(3)
+	if !cache.Has("custom|3") {
+		t.Fatalf("Expected namespaced custom key, got %v", cache.Keys())
+	}
+}
+
+func TestInvalidateFunc(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	double := Wrap(cache, namespaceDouble)
+	triple := Wrap(cache, namespaceTriple)
+	for i := 0; i < 3; i++ {
+		double(i)
+		triple(i)
+	}
+
+	removed, err := cache.InvalidateFunc(namespaceDouble)
+	if err != nil {
+		t.Fatalf("InvalidateFunc failed: %v", err)
+	}
+	if removed != 3 {
+		t.Fatalf("Expected 3 entries removed, got %d", removed)
+	}
+	if cache.Len() != 3 {
+		t.Fatalf("Expected the other function's entries to remain, got %d", cache.Len())
+	}
+
+	removed, _ = cache.InvalidateNamespace(FuncNamespace(namespaceTriple))
+	if removed != 3 || cache.Len() != 0 {
+		t.Fatalf("Expected namespace to be emptied, removed %d, %d left", removed, cache.Len())
+	}
+
+	if _, err := cache.InvalidateFunc("not a function"); err == nil {
+		t.Fatal("Expected an error for a non-function")
+	}
+}
+
+func TestDeleteByPrefix(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	_ = cache.Put("user:1", "a")
+	_ = cache.Put("user:2", "b")
+	_ = cache.Put("order:1", "c")
+
+	removed, err := cache.DeleteByPrefix("user:")
+	if err != nil || removed != 2 {
+		t.Fatalf("Expected 2 entries removed, got %d, %v", removed, err)
+	}
+	if !cache.Has("order:1") {
+		t.Fatal("Expected non-matching entry to remain")
+	}
+	if cache.Stats().Invalidations() != 2 {
+		t.Fatalf("Expected 2 invalidations, got %d", cache.Stats().Invalidations())
+	}
+}
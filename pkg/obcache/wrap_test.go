@@ -342,6 +342,7 @@ func TestValidateWrappableFunction(t *testing.T) {
 		func(x int) int { return x * 2 },
 		func(x, y int) (int, error) { return x + y, nil },
 		func() (string, error) { const testValue = "test"; return testValue, nil },
+		func(x int, _ ...string) int { return x },
 	}
 
 	for i, fn := range validFuncs {
@@ -357,7 +358,6 @@ func TestValidateWrappableFunction(t *testing.T) {
 	}{
 		{"not a function", "non-function"},
 		{func() {}, "no return values"},
-		{func(x int, _ ...string) int { return x }, "variadic function"},
 		{func() (int, string) { return 1, "test" }, "multiple returns without error"},
 	}
 
@@ -621,3 +621,39 @@ func TestErrorCachingMultipleReturnValues(t *testing.T) {
 		t.Fatalf("Expected 2 calls, got %d", callCount)
 	}
 }
+
+func TestWrapResultTags(t *testing.T) {
+	cache, err := New(NewDefaultConfig())
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	callCount := 0
+	double := Wrap(cache, func(n int) int {
+		callCount++
+		return n * 2
+	}, WithResultTags("double"))
+	square := Wrap(cache, func(n int) int {
+		return n * n
+	}, WithKeyFunc(func(args []any) string { return fmt.Sprintf("square:%v", args[0]) }))
+
+	double(1)
+	double(2)
+	square(3)
+
+	removed, err := cache.DeleteByTag("double")
+	if err != nil {
+		t.Fatalf("DeleteByTag failed: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("Expected 2 tagged results removed, got %d", removed)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("Expected untagged result to remain, got %d entries", cache.Len())
+	}
+
+	double(1)
+	if callCount != 3 {
+		t.Fatalf("Expected recomputation after invalidation, got %d calls", callCount)
+	}
+}
@@ -0,0 +1,403 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/metrics"
+)
+
+// Loader loads the value of a key that is missing from the cache
+type Loader interface {
+	Load(ctx context.Context, key string) (any, error)
+}
+
+// LoaderFunc adapts an ordinary function to the Loader interface
+type LoaderFunc func(ctx context.Context, key string) (any, error)
+
+// Load calls f(ctx, key)
+func (f LoaderFunc) Load(ctx context.Context, key string) (any, error) {
+	return f(ctx, key)
+}
+
+// Writer applies cache writes to a backing store
+type Writer interface {
+	// Write stores value for key in the backing store
+	Write(ctx context.Context, key string, value any) error
+
+	// Delete removes key from the backing store
+	Delete(ctx context.Context, key string) error
+}
+
+// BatchWriter is a Writer that can apply several operations at once. In
+// WriteBehind mode each flushed batch is passed to WriteBatch instead of
+// calling Write and Delete once per operation.
+type BatchWriter interface {
+	Writer
+	WriteBatch(ctx context.Context, ops []WriteOp) error
+}
+
+// WriteOp is a write queued for the backing store
+type WriteOp struct {
+	Key    string
+	Value  any
+	Delete bool
+}
+
+// WriteMode determines when the configured Writer is called
+type WriteMode int
+
+const (
+	// WriteThrough calls the Writer synchronously from Set and Delete; its
+	// error is returned to the caller
+	WriteThrough WriteMode = iota
+
+	// WriteBehind queues writes and applies them in batches from a
+	// background goroutine, retrying failures. Close flushes the queue.
+	WriteBehind
+)
+
+// String returns the string representation of WriteMode
+func (m WriteMode) String() string {
+	switch m {
+	case WriteThrough:
+		return "write-through"
+	case WriteBehind:
+		return "write-behind"
+	default:
+		return "unknown"
+	}
+}
+
+// WriteBehindConfig configures the queue used in WriteBehind mode
+type WriteBehindConfig struct {
+	// BatchSize is the largest number of operations written at once. A batch
+	// is flushed as soon as this many operations are queued.
+	// Default: 100
+	BatchSize int
+
+	// FlushInterval is how often queued operations are flushed when fewer
+	// than BatchSize are waiting
+	// Default: 1 second
+	FlushInterval time.Duration
+
+	// QueueSize caps the number of queued operations. Writes beyond it fail
+	// with ErrWriteQueueFull.
+	// Default: 10000
+	QueueSize int
+
+	// MaxRetries is how many times a failed write is retried before it is
+	// dropped and reported as a write failure. 0 disables retries.
+	// Default: 3
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry; it doubles on each
+	// further retry
+	// Default: 100 milliseconds
+	RetryBackoff time.Duration
+}
+
+// NewDefaultWriteBehindConfig returns a WriteBehindConfig with sensible defaults
+func NewDefaultWriteBehindConfig() *WriteBehindConfig {
+	return &WriteBehindConfig{
+		BatchSize:     100,
+		FlushInterval: time.Second,
+		QueueSize:     10000,
+		MaxRetries:    3,
+		RetryBackoff:  100 * time.Millisecond,
+	}
+}
+
+// Fetch returns the value cached for key, loading it with Config.Loader on
+// a miss (read-through). Concurrent misses for the same key share one Load
+// call, and the loaded value is cached for DefaultTTL. Loader errors are
+// returned and not cached. Without a Loader, a miss returns ErrNoLoader.
+// Store errors are returned rather than treated as misses (see GetE).
+func (c *Cache) Fetch(ctx context.Context, key string) (any, error) {
+	start := time.Now()
+	defer func() {
+		c.recordCacheOperation(metrics.OperationGet, time.Since(start))
+	}()
+
+	value, _, err := c.lookupE(ctx, key, c.config.EarlyExpirationBeta)
+	if err == nil {
+		return cachedResult(value)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if c.config.Loader == nil {
+		return nil, ErrNoLoader
+	}
+	return c.readThrough(ctx, key)
+}
+
+// readThrough loads key with the configured Loader and caches the result
+func (c *Cache) readThrough(ctx context.Context, key string) (any, error) {
+	opts := newWrapOptions(c, nil)
+	return c.load(ctx, key, opts, true, func(ctx context.Context) (any, time.Duration, error) {
+		start := time.Now()
+		value, err := c.config.Loader.Load(ctx, key)
+		return value, time.Since(start), err
+	})
+}
+
+// writeBack passes a write made to the cache on to the configured Writer.
+// A value the Writer rejects, or that cannot be queued, is removed from the
+// cache again so the cache never serves data the backing store lacks.
+func (c *Cache) writeBack(op WriteOp) error {
+	if c.config.Writer == nil {
+		return nil
+	}
+
+	var err error
+	if c.writeBehind != nil {
+		err = c.writeBehind.enqueue(op)
+	} else {
+		err = applyWrite(context.Background(), c.config.Writer, op)
+	}
+	if err == nil {
+		return nil
+	}
+
+	c.writeFailed(op.Key, err)
+	if !op.Delete {
+		c.lock(func() {
+			_ = c.store.Delete(op.Key) //nolint:errcheck // The write error is what the caller needs
+			c.updateKeyCount()
+		})
+	}
+	return fmt.Errorf("obcache: failed to write %q to backing store: %w", op.Key, err)
+}
+
+// writeFailed records a write the backing store did not apply
+func (c *Cache) writeFailed(key string, err error) {
+	c.stats.incWriteFailures()
+	if c.hooks != nil {
+		c.hooks.invokeOnWriteError(key, err)
+	}
+	if c.metricsExporter != nil {
+		_ = c.metricsExporter.IncrementCounter(metrics.DefaultMetricNames().CacheWriteFailuresTotal, c.metricsLabels) //nolint:errcheck // Metrics are best-effort
+	}
+}
+
+// applyWrite applies a single operation through writer
+func applyWrite(ctx context.Context, writer Writer, op WriteOp) error {
+	if op.Delete {
+		return writer.Delete(ctx, op.Key)
+	}
+	return writer.Write(ctx, op.Key, op.Value)
+}
+
+// writeBehindQueue buffers writes and applies them in batches from a
+// background goroutine
+type writeBehindQueue struct {
+	cache  *Cache
+	writer Writer
+	config WriteBehindConfig
+
+	mu      sync.Mutex
+	pending []WriteOp
+	closed  bool
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newWriteBehindQueue starts a queue writing to writer. Unset fields of
+// config take their default values.
+func newWriteBehindQueue(cache *Cache, writer Writer, config *WriteBehindConfig) *writeBehindQueue {
+	defaults := NewDefaultWriteBehindConfig()
+	cfg := *defaults
+	if config != nil {
+		cfg = *config
+		if cfg.BatchSize <= 0 {
+			cfg.BatchSize = defaults.BatchSize
+		}
+		if cfg.FlushInterval <= 0 {
+			cfg.FlushInterval = defaults.FlushInterval
+		}
+		if cfg.QueueSize <= 0 {
+			cfg.QueueSize = defaults.QueueSize
+		}
+		cfg.MaxRetries = max(cfg.MaxRetries, 0)
+		cfg.RetryBackoff = max(cfg.RetryBackoff, 0)
+	}
+
+	q := &writeBehindQueue{
+		cache:  cache,
+		writer: writer,
+		config: cfg,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// enqueue adds op to the queue
+func (q *writeBehindQueue) enqueue(op WriteOp) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrWriterClosed
+	}
+	if len(q.pending) >= q.config.QueueSize {
+		q.mu.Unlock()
+		return ErrWriteQueueFull
+	}
+	q.pending = append(q.pending, op)
+	depth := len(q.pending)
+	q.reportDepth(depth)
+	q.mu.Unlock()
+
+	if depth >= q.config.BatchSize {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// close stops accepting writes and returns once every queued write has been
+// flushed
+func (q *writeBehindQueue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.stop)
+	}
+	q.mu.Unlock()
+	<-q.done
+}
+
+// run flushes the queue whenever a batch fills up or the flush interval
+// passes, and drains it on close
+func (q *writeBehindQueue) run() {
+	defer close(q.done)
+
+	ticker := q.cache.clock.NewTicker(q.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.wake:
+		case <-ticker.C():
+		case <-q.stop:
+			for q.flushBatch() {
+			}
+			return
+		}
+		for q.flushBatch() {
+		}
+	}
+}
+
+// flushBatch writes the oldest queued operations, up to BatchSize of them,
+// and reports whether there were any
+func (q *writeBehindQueue) flushBatch() bool {
+	q.mu.Lock()
+	n := min(len(q.pending), q.config.BatchSize)
+	if n == 0 {
+		q.mu.Unlock()
+		return false
+	}
+	batch := make([]WriteOp, n)
+	copy(batch, q.pending)
+	q.pending = q.pending[n:]
+	q.reportDepth(len(q.pending))
+	q.mu.Unlock()
+
+	q.write(coalesceWrites(batch))
+	return true
+}
+
+// write applies a batch, retrying failures and reporting the operations that
+// could not be applied
+func (q *writeBehindQueue) write(batch []WriteOp) {
+	if bw, ok := q.writer.(BatchWriter); ok {
+		err := q.retry(func(ctx context.Context) error {
+			return bw.WriteBatch(ctx, batch)
+		})
+		if err != nil {
+			for _, op := range batch {
+				q.cache.writeFailed(op.Key, err)
+			}
+		}
+		return
+	}
+
+	for _, op := range batch {
+		err := q.retry(func(ctx context.Context) error {
+			return applyWrite(ctx, q.writer, op)
+		})
+		if err != nil {
+			q.cache.writeFailed(op.Key, err)
+		}
+	}
+}
+
+// retry calls fn until it succeeds or MaxRetries retries have failed,
+// doubling the delay between attempts. Once the queue is closing, the
+// remaining attempts run without delay so Close is not held up.
+func (q *writeBehindQueue) retry(fn func(ctx context.Context) error) error {
+	backoff := q.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn(context.Background())
+		if err == nil || attempt >= q.config.MaxRetries {
+			return err
+		}
+		q.wait(backoff)
+		backoff *= 2
+	}
+}
+
+// wait blocks for d on the cache clock, or until the queue is closing
+func (q *writeBehindQueue) wait(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	ticker := q.cache.clock.NewTicker(d)
+	defer ticker.Stop()
+
+	select {
+	case <-ticker.C():
+	case <-q.stop:
+	}
+}
+
+// reportDepth publishes the number of queued operations. It is called with
+// q.mu held so reports are published in order.
+func (q *writeBehindQueue) reportDepth(depth int) {
+	q.cache.stats.setWriteQueueDepth(int64(depth))
+	if q.cache.metricsExporter != nil {
+		_ = q.cache.metricsExporter.SetGauge(metrics.DefaultMetricNames().CacheWriteQueueDepth, float64(depth), q.cache.metricsLabels) //nolint:errcheck // Metrics are best-effort
+	}
+}
+
+// coalesceWrites drops operations superseded by a later operation on the
+// same key within the batch
+func coalesceWrites(batch []WriteOp) []WriteOp {
+	last := make(map[string]int, len(batch))
+	for i, op := range batch {
+		last[op.Key] = i
+	}
+	if len(last) == len(batch) {
+		return batch
+	}
+
+	coalesced := make([]WriteOp, 0, len(last))
+	for i, op := range batch {
+		if last[op.Key] == i {
+			coalesced = append(coalesced, op)
+		}
+	}
+	return coalesced
+}
@@ -0,0 +1,399 @@
+package obcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingWriter is a Writer that records the operations it applies and
+// fails while failures is positive
+type recordingWriter struct {
+	mu       sync.Mutex
+	ops      []WriteOp
+	batches  int
+	failures int
+}
+
+func (w *recordingWriter) apply(op WriteOp) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("backing store unavailable")
+	}
+	w.ops = append(w.ops, op)
+	return nil
+}
+
+func (w *recordingWriter) Write(_ context.Context, key string, value any) error {
+	return w.apply(WriteOp{Key: key, Value: value})
+}
+
+func (w *recordingWriter) Delete(_ context.Context, key string) error {
+	return w.apply(WriteOp{Key: key, Delete: true})
+}
+
+func (w *recordingWriter) applied() []WriteOp {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]WriteOp(nil), w.ops...)
+}
+
+// batchWriter is a BatchWriter built on recordingWriter
+type batchWriter struct {
+	recordingWriter
+}
+
+func (w *batchWriter) WriteBatch(_ context.Context, ops []WriteOp) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		return errors.New("backing store unavailable")
+	}
+	w.batches++
+	w.ops = append(w.ops, ops...)
+	return nil
+}
+
+func TestReadThroughLoader(t *testing.T) {
+	var loads int32
+	config := NewDefaultConfig().WithLoader(LoaderFunc(func(_ context.Context, key string) (any, error) {
+		atomic.AddInt32(&loads, 1)
+		if key == "missing" {
+			return nil, errors.New("not found")
+		}
+		return "value-" + key, nil
+	}))
+	cache, err := New(config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	value, found := cache.Get("a")
+	if !found || value != "value-a" {
+		t.Fatalf("Expected loaded value, got %v (found=%v)", value, found)
+	}
+	value, err = cache.Fetch(context.Background(), "a")
+	if err != nil || value != "value-a" {
+		t.Fatalf("Expected cached value, got %v (err=%v)", value, err)
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("Expected 1 load, got %d", n)
+	}
+
+	if _, err := cache.Fetch(context.Background(), "missing"); err == nil {
+		t.Fatal("Expected loader error from Fetch")
+	}
+	if _, found := cache.Get("missing"); found {
+		t.Fatal("Expected failed load to report a miss")
+	}
+	if cache.Has("missing") {
+		t.Fatal("Expected loader errors not to be cached")
+	}
+}
+
+func TestFetchWithoutLoader(t *testing.T) {
+	cache, _ := New(NewDefaultConfig())
+
+	if _, err := cache.Fetch(context.Background(), "a"); !errors.Is(err, ErrNoLoader) {
+		t.Fatalf("Expected ErrNoLoader, got %v", err)
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	writer := &recordingWriter{}
+	cache, err := New(NewDefaultConfig().WithWriter(writer))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	if err := cache.Set("a", 1, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cache.Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	ops := writer.applied()
+	if len(ops) != 2 || ops[0] != (WriteOp{Key: "a", Value: 1}) || ops[1] != (WriteOp{Key: "a", Delete: true}) {
+		t.Fatalf("Unexpected writes: %+v", ops)
+	}
+}
+
+func TestWriteThroughFailure(t *testing.T) {
+	writer := &recordingWriter{failures: 1}
+	var hookKey string
+	hooks := &Hooks{}
+	hooks.AddOnWriteError(func(key string, _ error) {
+		hookKey = key
+	})
+	cache, _ := New(NewDefaultConfig().WithWriter(writer).WithHooks(hooks))
+
+	if err := cache.Set("a", 1, time.Minute); err == nil {
+		t.Fatal("Expected Set to return the writer error")
+	}
+	if cache.Has("a") {
+		t.Fatal("Expected rejected value to be removed from the cache")
+	}
+	if hookKey != "a" {
+		t.Fatalf("Expected OnWriteError hook for key a, got %q", hookKey)
+	}
+	if n := cache.Stats().WriteFailures(); n != 1 {
+		t.Fatalf("Expected 1 write failure, got %d", n)
+	}
+}
+
+func TestWriteThroughAtomicWrites(t *testing.T) {
+	writer := &recordingWriter{}
+	cache, _ := New(NewDefaultConfig().WithWriter(writer))
+
+	_ = cache.Set("a", 1, time.Minute)
+	_, version, _ := cache.GetWithVersion("a")
+	if swapped, err := cache.CompareAndSwap("a", version, 2); err != nil || !swapped {
+		t.Fatalf("CompareAndSwap failed: %v, %v", swapped, err)
+	}
+	if swapped, _ := cache.CompareAndSwap("a", version, 3); swapped {
+		t.Fatal("Expected a stale version not to swap")
+	}
+	if err := cache.Update("a", func(old any) (any, error) {
+		return old.(int) + 1, nil
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	ops := writer.applied()
+	if len(ops) != 3 || ops[1] != (WriteOp{Key: "a", Value: 2}) || ops[2] != (WriteOp{Key: "a", Value: 3}) {
+		t.Fatalf("Expected swapped and updated values to be written, got %+v", ops)
+	}
+
+	// A rejected write removes the value from the cache, as with Set
+	writer.failures = 1
+	if err := cache.Update("a", func(any) (any, error) { return 4, nil }); err == nil {
+		t.Fatal("Expected Update to return the writer error")
+	}
+	if cache.Has("a") {
+		t.Fatal("Expected rejected value to be removed from the cache")
+	}
+}
+
+func TestWriterSkipsInvalidations(t *testing.T) {
+	writer := &recordingWriter{}
+	cache, _ := New(NewDefaultConfig().WithWriter(writer))
+
+	wrapped, controller := WrapWithController(cache, func(id int) int { return id })
+	wrapped(1)
+	if err := controller.Invalidate(1); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+
+	limiter, _ := NewRateLimiter(cache, 1, time.Minute)
+	_, _ = limiter.Allow("client")
+	if err := limiter.Reset("client"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+
+	_ = cache.Set("a", 1, time.Minute)
+	if err := cache.Invalidate("a"); err != nil {
+		t.Fatalf("Invalidate failed: %v", err)
+	}
+	if cache.Has("a") {
+		t.Fatal("Expected Invalidate to remove the key from the cache")
+	}
+
+	ops := writer.applied()
+	if len(ops) != 1 || ops[0] != (WriteOp{Key: "a", Value: 1}) {
+		t.Fatalf("Expected only the explicit Set to reach the Writer, got %+v", ops)
+	}
+}
+
+func TestWriterSkipsLoadedValues(t *testing.T) {
+	writer := &recordingWriter{}
+	config := NewDefaultConfig().WithWriter(writer).WithLoader(LoaderFunc(func(context.Context, string) (any, error) {
+		return "loaded", nil
+	}))
+	cache, _ := New(config)
+
+	if _, found := cache.Get("a"); !found {
+		t.Fatal("Expected read-through hit")
+	}
+	if ops := writer.applied(); len(ops) != 0 {
+		t.Fatalf("Expected loaded values not to be written back, got %+v", ops)
+	}
+}
+
+func TestWriterSkipsWarmedValues(t *testing.T) {
+	writer := &recordingWriter{}
+	cache, _ := New(NewDefaultConfig().WithWriter(writer))
+
+	warmer := NewWarmer(cache, func(_ context.Context, key string) (any, error) {
+		return "warm-" + key, nil
+	})
+	progress, err := warmer.Warm(context.Background(), KeysFromSlice([]string{"a", "b"}))
+	if err != nil || progress.Loaded != 2 {
+		t.Fatalf("Expected 2 keys warmed, got %+v, %v", progress, err)
+	}
+	if value, found := cache.Get("a"); !found || value != "warm-a" {
+		t.Fatalf("Expected warmed value, got %v (found=%v)", value, found)
+	}
+	if ops := writer.applied(); len(ops) != 0 {
+		t.Fatalf("Expected warmed values not to be written back, got %+v", ops)
+	}
+}
+
+func TestWriteBehindFlushesOnClose(t *testing.T) {
+	writer := &recordingWriter{}
+	cache, err := New(NewDefaultConfig().WithWriteBehind(writer, &WriteBehindConfig{
+		BatchSize:     100,
+		FlushInterval: time.Hour,
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+
+	_ = cache.Set("a", 1, time.Minute)
+	_ = cache.Set("b", 2, time.Minute)
+	_ = cache.Set("a", 3, time.Minute)
+	_ = cache.Delete("b")
+
+	if n := cache.Stats().WriteQueueDepth(); n != 4 {
+		t.Fatalf("Expected 4 queued writes, got %d", n)
+	}
+	if ops := writer.applied(); len(ops) != 0 {
+		t.Fatalf("Expected no writes before flush, got %+v", ops)
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Superseded writes to the same key are coalesced within a batch
+	ops := writer.applied()
+	if len(ops) != 2 || ops[0] != (WriteOp{Key: "a", Value: 3}) || ops[1] != (WriteOp{Key: "b", Delete: true}) {
+		t.Fatalf("Unexpected writes: %+v", ops)
+	}
+	if n := cache.Stats().WriteQueueDepth(); n != 0 {
+		t.Fatalf("Expected empty queue after Close, got %d", n)
+	}
+
+	if err := cache.Set("c", 1, time.Minute); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestWriteBehindBatches(t *testing.T) {
+	writer := &batchWriter{}
+	cache, _ := New(NewDefaultConfig().WithWriteBehind(writer, &WriteBehindConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	}))
+	defer cache.Close()
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		_ = cache.Set(key, key, time.Minute)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(writer.applied()) < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected full batches to flush without waiting for the interval, got %+v", writer.applied())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	writer.mu.Lock()
+	batches := writer.batches
+	writer.mu.Unlock()
+	if batches != 2 {
+		t.Fatalf("Expected 2 batches, got %d", batches)
+	}
+}
+
+func TestWriteBehindRetries(t *testing.T) {
+	writer := &recordingWriter{failures: 2}
+	cache, _ := New(NewDefaultConfig().WithWriteBehind(writer, &WriteBehindConfig{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	}))
+
+	_ = cache.Set("a", 1, time.Minute)
+	_ = cache.Close()
+
+	if ops := writer.applied(); len(ops) != 1 {
+		t.Fatalf("Expected write to succeed after retries, got %+v", ops)
+	}
+	if n := cache.Stats().WriteFailures(); n != 0 {
+		t.Fatalf("Expected no write failures, got %d", n)
+	}
+}
+
+func TestWriteBehindGivesUp(t *testing.T) {
+	writer := &recordingWriter{failures: 10}
+	var failed int32
+	hooks := &Hooks{}
+	hooks.AddOnWriteError(func(string, error) {
+		atomic.AddInt32(&failed, 1)
+	})
+	cache, _ := New(NewDefaultConfig().WithHooks(hooks).WithWriteBehind(writer, &WriteBehindConfig{
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+	}))
+
+	_ = cache.Set("a", 1, time.Minute)
+	_ = cache.Close()
+
+	if n := cache.Stats().WriteFailures(); n != 1 {
+		t.Fatalf("Expected 1 write failure, got %d", n)
+	}
+	if n := atomic.LoadInt32(&failed); n != 1 {
+		t.Fatalf("Expected OnWriteError to be called once, got %d", n)
+	}
+}
+
+func TestWriteBehindCloseSkipsBackoff(t *testing.T) {
+	writer := &recordingWriter{failures: 2}
+	cache, _ := New(NewDefaultConfig().WithWriteBehind(writer, &WriteBehindConfig{
+		BatchSize:    1,
+		MaxRetries:   2,
+		RetryBackoff: time.Hour,
+	}))
+
+	_ = cache.Set("a", 1, time.Minute)
+	waitForCondition(t, time.Second, func() bool {
+		writer.mu.Lock()
+		defer writer.mu.Unlock()
+		return writer.failures == 1
+	})
+
+	start := time.Now()
+	_ = cache.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected Close to cut the retry backoff short, took %v", elapsed)
+	}
+	if ops := writer.applied(); len(ops) != 1 {
+		t.Fatalf("Expected write to succeed on the remaining retries, got %+v", ops)
+	}
+}
+
+func TestWriteBehindQueueFull(t *testing.T) {
+	writer := &recordingWriter{}
+	cache, _ := New(NewDefaultConfig().WithWriteBehind(writer, &WriteBehindConfig{
+		QueueSize:     1,
+		FlushInterval: time.Hour,
+	}))
+	defer cache.Close()
+
+	if err := cache.Set("a", 1, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := cache.Set("b", 2, time.Minute); !errors.Is(err, ErrWriteQueueFull) {
+		t.Fatalf("Expected ErrWriteQueueFull, got %v", err)
+	}
+	if cache.Has("b") {
+		t.Fatal("Expected value that could not be queued to be removed from the cache")
+	}
+}
@@ -0,0 +1,127 @@
+package obcachetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+// defaultStart is the time a FakeClock starts at when given the zero time
+var defaultStart = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// FakeClock is an obcache.Clock whose time only moves when Advance or Set is
+// called. Tickers created from it fire as the clock passes their tick times.
+// It is safe for concurrent use.
+type FakeClock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	tickers map[*fakeTicker]struct{}
+}
+
+// NewFakeClock returns a FakeClock set to start, or to a fixed date in 2000
+// if start is the zero time
+func NewFakeClock(start time.Time) *FakeClock {
+	if start.IsZero() {
+		start = defaultStart
+	}
+	c := &FakeClock{
+		now:     start,
+		tickers: make(map[*fakeTicker]struct{}),
+	}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the clock's current time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker returns a Ticker that ticks each time the clock passes another
+// multiple of d after now. Like time.Ticker, it holds at most one pending
+// tick and drops ticks its reader is not ready for. It panics if d is not
+// positive.
+func (c *FakeClock) NewTicker(d time.Duration) obcache.Ticker {
+	if d <= 0 {
+		panic("obcachetest: non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTicker{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   c.now.Add(d),
+	}
+	c.tickers[t] = struct{}{}
+	c.changed.Broadcast()
+	return t
+}
+
+// Advance moves the clock forward by d, firing the tickers it passes
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set moves the clock to t, firing the tickers it passes. Setting the clock
+// back in time fires no tickers.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(t)
+}
+
+// WaitForTickers blocks until at least n tickers are running. Caches start
+// their tickers from background goroutines, so tests wait for them before
+// advancing the clock to be sure the ticks are seen.
+func (c *FakeClock) WaitForTickers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.tickers) < n {
+		c.changed.Wait()
+	}
+}
+
+// setLocked moves the clock to t and fires due tickers, with c.mu held
+func (c *FakeClock) setLocked(t time.Time) {
+	c.now = t
+	for ticker := range c.tickers {
+		if ticker.next.After(t) {
+			continue
+		}
+		select {
+		case ticker.c <- ticker.next:
+		default:
+		}
+		missed := t.Sub(ticker.next) / ticker.period
+		ticker.next = ticker.next.Add((missed + 1) * ticker.period)
+	}
+}
+
+// fakeTicker is a Ticker driven by a FakeClock
+type fakeTicker struct {
+	clock  *FakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	delete(t.clock.tickers, t)
+	t.clock.changed.Broadcast()
+}
+
+var _ obcache.Clock = (*FakeClock)(nil)
@@ -0,0 +1,199 @@
+package obcachetest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+func TestFakeClockTickers(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	start := clock.Now()
+	ticker := clock.NewTicker(time.Minute)
+
+	clock.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected no tick before the interval has passed")
+	default:
+	}
+
+	// Passing several ticks at once leaves a single pending tick
+	clock.Advance(3 * time.Minute)
+	select {
+	case tick := <-ticker.C():
+		if want := start.Add(time.Minute); !tick.Equal(want) {
+			t.Fatalf("Expected tick at %v, got %v", want, tick)
+		}
+	default:
+		t.Fatal("Expected a tick after the interval passed")
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected missed ticks to be dropped")
+	default:
+	}
+
+	// The next tick is still aligned to the ticker's start
+	clock.Advance(time.Second)
+	select {
+	case tick := <-ticker.C():
+		if want := start.Add(4 * time.Minute); !tick.Equal(want) {
+			t.Fatalf("Expected tick at %v, got %v", want, tick)
+		}
+	default:
+		t.Fatal("Expected a tick at the next interval")
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected no ticks after Stop")
+	default:
+	}
+}
+
+func TestFakeClockExpiresEntries(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	cache, err := obcache.New(obcache.NewDefaultConfig().WithClock(clock))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	_ = cache.Set("key", "value", time.Minute)
+
+	clock.Advance(40 * time.Second)
+	if _, found := cache.Get("key"); !found {
+		t.Fatal("Expected entry before its TTL has passed")
+	}
+	if ttl, _ := cache.TTL("key"); ttl != 20*time.Second {
+		t.Fatalf("Expected 20s remaining, got %v", ttl)
+	}
+
+	clock.Advance(21 * time.Second)
+	if _, found := cache.Get("key"); found {
+		t.Fatal("Expected entry to expire once the clock passed its TTL")
+	}
+}
+
+func TestFakeClockDrivesCleanup(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	var evicted int32
+	hooks := &obcache.Hooks{}
+	hooks.AddOnEvict(func(string, any, obcache.EvictReason) {
+		atomic.AddInt32(&evicted, 1)
+	})
+	config := obcache.NewDefaultConfig().
+		WithClock(clock).
+		WithCleanupInterval(time.Minute).
+		WithHooks(hooks)
+	cache, err := obcache.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	_ = cache.Set("short", 1, 30*time.Second)
+	_ = cache.Set("long", 2, time.Hour)
+
+	clock.WaitForTickers(1)
+	clock.Advance(time.Minute)
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&evicted) < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the cleanup tick to remove the expired entry")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if cache.Has("short") || !cache.Has("long") {
+		t.Fatal("Expected only the expired entry to be cleaned up")
+	}
+}
+
+func TestFakeClockDrivesWarmerRate(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	cache, err := obcache.New(obcache.NewDefaultConfig().WithClock(clock))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	var loaded int64
+	warmer := obcache.NewWarmer(cache, func(_ context.Context, key string) (any, error) {
+		atomic.AddInt64(&loaded, 1)
+		return key, nil
+	}, obcache.WithWarmRate(1))
+
+	done := make(chan obcache.WarmProgress)
+	go func() {
+		progress, _ := warmer.Warm(context.Background(), obcache.KeysFromSlice([]string{"a", "b", "c"}))
+		done <- progress
+	}()
+
+	// The first key is loaded without waiting for a tick
+	clock.WaitForTickers(2)
+	waitForCondition(t, time.Second, func() bool { return atomic.LoadInt64(&loaded) == 1 })
+
+	clock.Advance(time.Second)
+	waitForCondition(t, time.Second, func() bool { return atomic.LoadInt64(&loaded) == 2 })
+	clock.Advance(time.Second)
+
+	progress := <-done
+	if progress.Loaded != 3 {
+		t.Fatalf("Expected 3 loaded keys, got %d", progress.Loaded)
+	}
+	if progress.Elapsed != 2*time.Second {
+		t.Fatalf("Expected elapsed time by the fake clock, got %v", progress.Elapsed)
+	}
+}
+
+// flakyWriter fails until it has been called failures times
+type flakyWriter struct {
+	failures int32
+	written  int32
+}
+
+func (w *flakyWriter) Write(context.Context, string, any) error {
+	if atomic.AddInt32(&w.failures, -1) >= 0 {
+		return errors.New("backing store unavailable")
+	}
+	atomic.AddInt32(&w.written, 1)
+	return nil
+}
+
+func (w *flakyWriter) Delete(context.Context, string) error {
+	return nil
+}
+
+func TestFakeClockDrivesWriteBehindBackoff(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	writer := &flakyWriter{failures: 1}
+	cache, err := obcache.New(obcache.NewDefaultConfig().WithClock(clock).WithWriteBehind(writer, &obcache.WriteBehindConfig{
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		MaxRetries:    1,
+		RetryBackoff:  time.Minute,
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer cache.Close()
+
+	_ = cache.Set("a", 1, time.Hour)
+
+	// Cleanup, flush and backoff tickers
+	clock.WaitForTickers(3)
+	if n := atomic.LoadInt32(&writer.written); n != 0 {
+		t.Fatalf("Expected the retry to wait for the backoff, got %d writes", n)
+	}
+
+	clock.Advance(time.Minute)
+	waitForCondition(t, time.Second, func() bool { return atomic.LoadInt32(&writer.written) == 1 })
+}
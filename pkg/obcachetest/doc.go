@@ -0,0 +1,24 @@
+// Package obcachetest provides helpers for testing code that uses obcache.
+//
+// Recorder captures the hits, misses, evictions and invalidations a cache
+// reports, so tests can assert that a call was served from the cache or that
+// a key was invalidated:
+//
+//	cache, recorder := obcachetest.NewRecordingCache(t, nil)
+//	service.Lookup(cache, "user:1")
+//	recorder.AssertHit(t, "user:1")
+//
+// FakeClock replaces the system clock so tests can expire entries and fire
+// cleanup and reporting tickers deterministically instead of sleeping:
+//
+//	clock := obcachetest.NewFakeClock(time.Time{})
+//	cache, _ := obcache.New(obcache.NewDefaultConfig().WithClock(clock))
+//
+//	cache.Set("key", "value", time.Minute)
+//	clock.Advance(2 * time.Minute)
+//	_, found := cache.Get("key") // found == false
+//
+// FaultStore wraps a store to inject latency, errors and corrupted values,
+// and MiniRedis is an in-process Redis stand-in for testing the Redis store
+// without a server.
+package obcachetest
@@ -0,0 +1,216 @@
+package obcachetest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/internal/entry"
+	"github.com/vnykmshr/obcache-go/internal/store"
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+// FaultStore wraps a store and injects faults into its operations: added
+// latency, failing operations and corrupted values. Create the inner store
+// with obcache.NewStore and hand the FaultStore to the cache with
+// Config.WithStore:
+//
+//	inner, _ := obcache.NewStore(config)
+//	faults := obcachetest.NewFaultStore(inner)
+//	cache, _ := obcache.New(config.WithStore(faults))
+//	faults.SetError(errors.New("connection refused"))
+//
+// Faults can be changed at any time and apply to the operations that follow.
+// FaultStore is safe for concurrent use if the inner store is.
+type FaultStore struct {
+	inner obcache.Store
+
+	mu      sync.Mutex
+	latency time.Duration
+	err     error
+	corrupt func(value any) any
+}
+
+// NewFaultStore wraps inner without injecting any faults yet
+func NewFaultStore(inner obcache.Store) *FaultStore {
+	return &FaultStore{inner: inner}
+}
+
+// SetLatency delays every read and write by d (0 removes the delay)
+func (s *FaultStore) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetError makes operations fail with err, as an unreachable backend would
+// (nil restores normal operation). Reads fail with ErrBackendUnavailable
+// wrapping err.
+func (s *FaultStore) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// SetCorruption makes reads return entries whose value is fn applied to the
+// stored value (nil stops corrupting). For compressed entries the value is
+// the compressed bytes. The stored entries are not modified.
+func (s *FaultStore) SetCorruption(fn func(value any) any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.corrupt = fn
+}
+
+// Reset removes all injected faults
+func (s *FaultStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = 0
+	s.err = nil
+	s.corrupt = nil
+}
+
+// fault waits out the injected latency and returns the injected error
+func (s *FaultStore) fault() error {
+	s.mu.Lock()
+	latency, err := s.latency, s.err
+	s.mu.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	return err
+}
+
+// Get retrieves an entry by key, subject to the injected faults
+func (s *FaultStore) Get(key string) (*entry.Entry, bool) {
+	e, err := s.GetE(key)
+	return e, err == nil
+}
+
+// GetE retrieves an entry by key, subject to the injected faults. An
+// injected error is returned wrapped in obcache.ErrBackendUnavailable.
+func (s *FaultStore) GetE(key string) (*entry.Entry, error) {
+	if err := s.fault(); err != nil {
+		return nil, fmt.Errorf("%w: %w", store.ErrBackendUnavailable, err)
+	}
+
+	e, err := store.GetE(s.inner, key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	corrupt := s.corrupt
+	s.mu.Unlock()
+	if corrupt == nil {
+		return e, nil
+	}
+	return corrupted(e, corrupt(e.Value)), nil
+}
+
+// corrupted returns a copy of e holding value
+func corrupted(e *entry.Entry, value any) *entry.Entry {
+	c := entry.New(value, 0)
+	c.Inherit(e)
+	c.Version = e.Version
+	if e.IsCompressed {
+		c.SetCompressionInfo(e.CompressorName, e.OriginalSize, e.CompressedSize)
+	}
+	return c
+}
+
+// Set stores an entry unless a fault is injected
+func (s *FaultStore) Set(key string, e *entry.Entry, opts ...store.SetOption) error {
+	if err := s.fault(); err != nil {
+		return err
+	}
+	return s.inner.Set(key, e, opts...)
+}
+
+// Delete removes an entry unless a fault is injected
+func (s *FaultStore) Delete(key string) error {
+	if err := s.fault(); err != nil {
+		return err
+	}
+	return s.inner.Delete(key)
+}
+
+// Keys returns the keys of the inner store
+func (s *FaultStore) Keys() []string {
+	return s.inner.Keys()
+}
+
+// Len returns the number of entries in the inner store
+func (s *FaultStore) Len() int {
+	return s.inner.Len()
+}
+
+// Clear removes all entries unless a fault is injected
+func (s *FaultStore) Clear() error {
+	if err := s.fault(); err != nil {
+		return err
+	}
+	return s.inner.Clear()
+}
+
+// Close closes the inner store
+func (s *FaultStore) Close() error {
+	return s.inner.Close()
+}
+
+// Update atomically updates an entry unless a fault is injected
+func (s *FaultStore) Update(key string, fn store.UpdateFunc) error {
+	if err := s.fault(); err != nil {
+		return err
+	}
+	atomicStore, ok := s.inner.(store.AtomicStore)
+	if !ok {
+		return fmt.Errorf("store %T does not support atomic updates", s.inner)
+	}
+	return atomicStore.Update(key, fn)
+}
+
+// IncrBy increments a counter unless a fault is injected
+func (s *FaultStore) IncrBy(key string, delta int64, ttl time.Duration) (int64, error) {
+	if err := s.fault(); err != nil {
+		return 0, err
+	}
+	counterStore, ok := s.inner.(store.CounterStore)
+	if !ok {
+		return 0, fmt.Errorf("store %T does not support counters", s.inner)
+	}
+	return counterStore.IncrBy(key, delta, ttl)
+}
+
+// Cleanup removes expired entries from the inner store
+func (s *FaultStore) Cleanup() int {
+	if ttlStore, ok := s.inner.(store.TTLStore); ok {
+		return ttlStore.Cleanup()
+	}
+	return 0
+}
+
+// SetCleanupCallback forwards to the inner store
+func (s *FaultStore) SetCleanupCallback(callback store.EvictCallback) {
+	if ttlStore, ok := s.inner.(store.TTLStore); ok {
+		ttlStore.SetCleanupCallback(callback)
+	}
+}
+
+// SetRemovalCallback forwards to the inner store
+func (s *FaultStore) SetRemovalCallback(callback store.RemovalCallback) {
+	if removalStore, ok := s.inner.(store.RemovalStore); ok {
+		removalStore.SetRemovalCallback(callback)
+	}
+}
+
+// Ensure FaultStore forwards the capabilities the cache relies on
+var (
+	_ store.Store        = (*FaultStore)(nil)
+	_ store.TTLStore     = (*FaultStore)(nil)
+	_ store.RemovalStore = (*FaultStore)(nil)
+	_ store.ErrorStore   = (*FaultStore)(nil)
+	_ store.AtomicStore  = (*FaultStore)(nil)
+	_ store.CounterStore = (*FaultStore)(nil)
+)
@@ -0,0 +1,78 @@
+package obcachetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+func newFaultCache(t *testing.T) (*obcache.Cache, *FaultStore, *Recorder) {
+	t.Helper()
+	config := obcache.NewDefaultConfig()
+	inner, err := obcache.NewStore(config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	faults := NewFaultStore(inner)
+	cache, recorder := NewRecordingCache(t, config.WithStore(faults))
+	return cache, faults, recorder
+}
+
+func TestFaultStoreErrors(t *testing.T) {
+	cache, faults, recorder := newFaultCache(t)
+	_ = cache.Set("a", 1, time.Minute)
+
+	unavailable := errors.New("backend unavailable")
+	faults.SetError(unavailable)
+
+	if err := cache.Set("b", 2, time.Minute); !errors.Is(err, unavailable) {
+		t.Fatalf("Expected injected error from Set, got %v", err)
+	}
+	if _, err := cache.IncrBy("n", 1, time.Minute); !errors.Is(err, unavailable) {
+		t.Fatalf("Expected injected error from IncrBy, got %v", err)
+	}
+	if _, err := cache.GetE("a"); !errors.Is(err, obcache.ErrBackendUnavailable) || !errors.Is(err, unavailable) {
+		t.Fatalf("Expected injected error from GetE, got %v", err)
+	}
+	if _, found := cache.Get("a"); found {
+		t.Fatal("Expected Get to find nothing while the store fails")
+	}
+	recorder.AssertError(t, "a", obcache.ErrBackendUnavailable)
+	recorder.AssertCount(t, Miss, "a", 0)
+
+	faults.Reset()
+	if _, found := cache.Get("a"); !found {
+		t.Fatal("Expected stored entry once the fault is removed")
+	}
+}
+
+func TestFaultStoreLatency(t *testing.T) {
+	cache, faults, _ := newFaultCache(t)
+	_ = cache.Set("a", 1, time.Minute)
+
+	faults.SetLatency(20 * time.Millisecond)
+	start := time.Now()
+	cache.Get("a")
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("Expected the injected latency, took %v", elapsed)
+	}
+}
+
+func TestFaultStoreCorruption(t *testing.T) {
+	cache, faults, _ := newFaultCache(t)
+	_ = cache.Set("a", "value", time.Minute)
+
+	faults.SetCorruption(func(any) any {
+		return "garbage"
+	})
+	if value, _ := cache.Get("a"); value != "garbage" {
+		t.Fatalf("Expected corrupted value, got %v", value)
+	}
+
+	faults.SetCorruption(nil)
+	if value, _ := cache.Get("a"); value != "value" {
+		t.Fatalf("Expected the stored value to be intact, got %v", value)
+	}
+}
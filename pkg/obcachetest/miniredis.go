@@ -0,0 +1,1010 @@
+package obcachetest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+// MiniRedis is a miniature in-process Redis server speaking the Redis
+// protocol on a local TCP port, so caches backed by the Redis store can be
+// tested without a Redis server:
+//
+//	server := obcachetest.NewMiniRedis(t)
+//	cache, _ := obcache.New(obcache.NewRedisConfig(server.Addr()))
+//
+// It implements the commands the Redis store uses: strings with expiry
+// (GET, SET, SETEX, GETEX, DEL, EXISTS, KEYS, TTL, PTTL), counters (INCRBY),
+// optimistic transactions (WATCH, MULTI, EXEC) and pub/sub (SUBSCRIBE,
+// PSUBSCRIBE, PUBLISH) with keyspace notifications for del, expired and
+// evicted events, enabled with CONFIG SET notify-keyspace-events. Lua is not
+// available: EVAL and EVALSHA run Go emulations of the scripts the Redis
+// store uses for distributed load deduplication and reject any other
+// script. There is a single database, numbered 0.
+//
+// Keys expire by the configured clock, which lets a FakeClock drive Redis
+// expiry as well as the cache's. Expired keys are removed when they are next
+// accessed or when ExpireKeys is called.
+type MiniRedis struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	clock    obcache.Clock
+	data     map[string]miniValue
+	versions map[string]uint64
+	version  uint64
+	conns    map[net.Conn]struct{}
+	closed   bool
+
+	// lastToken is the last lease token handed out, so that tokens grow
+	// even while a fake clock stands still
+	lastToken int64
+
+	// notifyFlags is the notify-keyspace-events setting
+	notifyFlags string
+	subscribers map[*miniSubscriber]struct{}
+
+	wg sync.WaitGroup
+}
+
+// miniValue is a stored string with its expiry (zero means none)
+type miniValue struct {
+	data    string
+	expires time.Time
+}
+
+// NewMiniRedis starts a MiniRedis on a free local port. It is closed when
+// the test ends.
+func NewMiniRedis(t testing.TB) *MiniRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start MiniRedis: %v", err)
+	}
+
+	m := &MiniRedis{
+		listener: listener,
+		clock:    obcache.SystemClock(),
+		data:     make(map[string]miniValue),
+		versions: make(map[string]uint64),
+		conns:    make(map[net.Conn]struct{}),
+
+		subscribers: make(map[*miniSubscriber]struct{}),
+	}
+	m.wg.Add(1)
+	go m.accept()
+	t.Cleanup(m.Close)
+	return m
+}
+
+// Addr returns the host:port the server listens on
+func (m *MiniRedis) Addr() string {
+	return m.listener.Addr().String()
+}
+
+// SetClock sets the clock keys expire by (the system clock if c is nil)
+func (m *MiniRedis) SetClock(c obcache.Clock) {
+	if c == nil {
+		c = obcache.SystemClock()
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = c
+}
+
+// Get returns the string stored at key, for inspecting what a cache wrote
+func (m *MiniRedis) Get(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, found := m.lookup(key)
+	return value.data, found
+}
+
+// Set stores value at key without expiry, for seeding or corrupting data
+func (m *MiniRedis) Set(key, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(key, miniValue{data: value})
+}
+
+// Keys returns the keys currently stored, sorted
+func (m *MiniRedis) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.keys("*")
+}
+
+// TTL returns the time left before key expires, or 0 if it has no expiry
+// or does not exist
+func (m *MiniRedis) TTL(key string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, found := m.lookup(key)
+	if !found || value.expires.IsZero() {
+		return 0
+	}
+	return value.expires.Sub(m.clock.Now())
+}
+
+// ExpireKeys removes the keys whose expiry has passed, as Redis does in the
+// background, and returns how many it removed
+func (m *MiniRedis) ExpireKeys() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for key := range m.data {
+		if _, found := m.lookup(key); !found {
+			n++
+		}
+	}
+	return n
+}
+
+// Evict removes key as Redis does when it runs out of memory, publishing an
+// evicted notification. It reports whether key existed.
+func (m *MiniRedis) Evict(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, found := m.lookup(key); !found {
+		return false
+	}
+	m.remove(key)
+	m.notify("evicted", key)
+	return true
+}
+
+// FlushAll removes every key
+func (m *MiniRedis) FlushAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key := range m.data {
+		m.remove(key)
+	}
+}
+
+// Close stops the server and disconnects its clients
+func (m *MiniRedis) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	_ = m.listener.Close() //nolint:errcheck // Shutting down
+	for conn := range m.conns {
+		_ = conn.Close() //nolint:errcheck // Shutting down
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// accept serves connections until the listener is closed
+func (m *MiniRedis) accept() {
+	defer m.wg.Done()
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			_ = conn.Close() //nolint:errcheck // Shutting down
+			return
+		}
+		m.conns[conn] = struct{}{}
+		m.wg.Add(1)
+		m.mu.Unlock()
+
+		go m.serve(conn)
+	}
+}
+
+// serve answers the commands sent on conn until it is closed
+func (m *MiniRedis) serve(conn net.Conn) {
+	defer m.wg.Done()
+	defer func() {
+		m.mu.Lock()
+		delete(m.conns, conn)
+		m.mu.Unlock()
+		_ = conn.Close() //nolint:errcheck // Connection is done
+	}()
+
+	r := bufio.NewReader(conn)
+	session := &miniSession{out: &miniWriter{w: bufio.NewWriter(conn)}}
+	defer m.unsubscribeAll(session)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		// Pipelined commands are answered together
+		if err := session.out.write(m.dispatch(session, args), r.Buffered() == 0); err != nil {
+			return
+		}
+	}
+}
+
+// miniSession is the transaction and subscription state of one connection
+type miniSession struct {
+	out *miniWriter
+
+	watched map[string]uint64
+	multi   bool
+	queued  [][]string
+
+	// sub holds the subscriptions once the connection subscribed
+	sub *miniSubscriber
+}
+
+// miniWriter serialises the replies and published messages sent on a
+// connection
+type miniWriter struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+// write sends reply, flushing the connection if flush is set
+func (w *miniWriter) write(reply miniReply, flush bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	reply.write(w.w)
+	if !flush {
+		return nil
+	}
+	return w.w.Flush()
+}
+
+// dispatch runs a command in the context of session
+func (m *MiniRedis) dispatch(session *miniSession, args []string) miniReply {
+	name := strings.ToUpper(args[0])
+
+	switch name {
+	case "MULTI":
+		if session.multi {
+			return errorReply("ERR MULTI calls can not be nested")
+		}
+		session.multi = true
+		session.queued = nil
+		return okReply
+	case "EXEC":
+		if !session.multi {
+			return errorReply("ERR EXEC without MULTI")
+		}
+		return m.exec(session)
+	case "DISCARD":
+		if !session.multi {
+			return errorReply("ERR DISCARD without MULTI")
+		}
+		session.multi = false
+		session.queued = nil
+		session.watched = nil
+		return okReply
+	case "WATCH":
+		if session.multi {
+			return errorReply("ERR WATCH inside MULTI is not allowed")
+		}
+		if len(args) < 2 {
+			return wrongArgs(name)
+		}
+		m.watch(session, args[1:])
+		return okReply
+	case "UNWATCH":
+		session.watched = nil
+		return okReply
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		if len(args) < 2 {
+			return wrongArgs(name)
+		}
+		return m.subscribe(session, name == "PSUBSCRIBE", args[1:])
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		return m.unsubscribe(session, name == "PUNSUBSCRIBE", args[1:])
+	case "PING":
+		if session.sub != nil {
+			message := ""
+			if len(args) > 1 {
+				message = args[1]
+			}
+			return arrayReply{bulkReply("pong"), bulkReply(message)}
+		}
+	}
+
+	if session.multi {
+		session.queued = append(session.queued, args)
+		return simpleReply("QUEUED")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.run(name, args[1:])
+}
+
+// watch records the versions of keys for a later EXEC
+func (m *MiniRedis) watch(session *miniSession, keys []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if session.watched == nil {
+		session.watched = make(map[string]uint64)
+	}
+	for _, key := range keys {
+		m.lookup(key) // Expiring a key counts as a change
+		session.watched[key] = m.versions[key]
+	}
+}
+
+// exec runs the queued commands atomically unless a watched key changed
+func (m *MiniRedis) exec(session *miniSession) miniReply {
+	queued, watched := session.queued, session.watched
+	session.multi = false
+	session.queued = nil
+	session.watched = nil
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, version := range watched {
+		m.lookup(key)
+		if m.versions[key] != version {
+			return nullArrayReply{}
+		}
+	}
+
+	replies := make(arrayReply, len(queued))
+	for i, args := range queued {
+		replies[i] = m.run(strings.ToUpper(args[0]), args[1:])
+	}
+	return replies
+}
+
+// miniSubscriber is the set of channels and patterns a connection listens
+// to, and the queue of messages published to it
+type miniSubscriber struct {
+	channels map[string]bool
+	patterns map[string]bool
+	messages chan miniReply
+}
+
+// count returns the number of subscriptions
+func (s *miniSubscriber) count() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+// subscribe implements SUBSCRIBE and PSUBSCRIBE. The first subscription
+// starts a goroutine delivering published messages to the connection.
+func (m *MiniRedis) subscribe(session *miniSession, patterns bool, names []string) miniReply {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := session.sub
+	if sub == nil {
+		sub = &miniSubscriber{
+			channels: make(map[string]bool),
+			patterns: make(map[string]bool),
+			messages: make(chan miniReply, 1024),
+		}
+		session.sub = sub
+		m.subscribers[sub] = struct{}{}
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for msg := range sub.messages {
+				_ = session.out.write(msg, true) //nolint:errcheck // The reader notices a broken connection
+			}
+		}()
+	}
+
+	kind, set := "subscribe", sub.channels
+	if patterns {
+		kind, set = "psubscribe", sub.patterns
+	}
+	replies := make(multiReply, len(names))
+	for i, name := range names {
+		set[name] = true
+		replies[i] = arrayReply{bulkReply(kind), bulkReply(name), intReply(sub.count())}
+	}
+	return replies
+}
+
+// unsubscribe implements UNSUBSCRIBE and PUNSUBSCRIBE; without names it
+// removes every subscription of the kind
+func (m *MiniRedis) unsubscribe(session *miniSession, patterns bool, names []string) miniReply {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kind := "unsubscribe"
+	if patterns {
+		kind = "punsubscribe"
+	}
+	sub := session.sub
+	if sub == nil {
+		return arrayReply{bulkReply(kind), nullReply{}, intReply(0)}
+	}
+
+	set := sub.channels
+	if patterns {
+		set = sub.patterns
+	}
+	if len(names) == 0 {
+		for name := range set {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return arrayReply{bulkReply(kind), nullReply{}, intReply(sub.count())}
+	}
+
+	replies := make(multiReply, len(names))
+	for i, name := range names {
+		delete(set, name)
+		replies[i] = arrayReply{bulkReply(kind), bulkReply(name), intReply(sub.count())}
+	}
+	return replies
+}
+
+// unsubscribeAll drops the subscriptions of a closed connection
+func (m *MiniRedis) unsubscribeAll(session *miniSession) {
+	if session.sub == nil {
+		return
+	}
+	m.mu.Lock()
+	delete(m.subscribers, session.sub)
+	m.mu.Unlock()
+	close(session.sub.messages)
+}
+
+// publish delivers message to the subscribers of channel with m.mu held and
+// returns how many received it. Subscribers that fall behind miss messages.
+func (m *MiniRedis) publish(channel, message string) int {
+	n := 0
+	for sub := range m.subscribers {
+		var msgs []miniReply
+		if sub.channels[channel] {
+			msgs = append(msgs, arrayReply{bulkReply("message"), bulkReply(channel), bulkReply(message)})
+		}
+		for pattern := range sub.patterns {
+			if matchGlob(pattern, channel) {
+				msgs = append(msgs, arrayReply{bulkReply("pmessage"), bulkReply(pattern), bulkReply(channel), bulkReply(message)})
+			}
+		}
+		for _, msg := range msgs {
+			select {
+			case sub.messages <- msg:
+				n++
+			default:
+			}
+		}
+	}
+	return n
+}
+
+// notifyClasses maps the keyspace events MiniRedis publishes to the
+// notify-keyspace-events class that enables them
+var notifyClasses = map[string]byte{
+	"del":     'g',
+	"expired": 'x',
+	"evicted": 'e',
+}
+
+// notify publishes a keyspace notification for event on key with m.mu held,
+// if notify-keyspace-events enables it
+func (m *MiniRedis) notify(event, key string) {
+	flags := m.notifyFlags
+	class := notifyClasses[event]
+	if !strings.ContainsRune(flags, rune(class)) && !strings.ContainsRune(flags, 'A') {
+		return
+	}
+	if strings.ContainsRune(flags, 'E') {
+		m.publish("__keyevent@0__:"+event, key)
+	}
+	if strings.ContainsRune(flags, 'K') {
+		m.publish("__keyspace@0__:"+key, event)
+	}
+}
+
+// config implements CONFIG GET and CONFIG SET for notify-keyspace-events
+func (m *MiniRedis) config(args []string) miniReply {
+	if len(args) < 2 {
+		return wrongArgs("CONFIG")
+	}
+
+	const notifyParam = "notify-keyspace-events"
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if !matchGlob(strings.ToLower(args[1]), notifyParam) {
+			return arrayReply{}
+		}
+		return arrayReply{bulkReply(notifyParam), bulkReply(m.notifyFlags)}
+	case "SET":
+		if len(args) != 3 || !strings.EqualFold(args[1], notifyParam) {
+			return errorReply(fmt.Sprintf("ERR Unknown option or number of arguments for CONFIG SET - '%s'", args[1]))
+		}
+		m.notifyFlags = args[2]
+		return okReply
+	default:
+		return errorReply(fmt.Sprintf("ERR unknown subcommand '%s'", args[0]))
+	}
+}
+
+// run executes a data command with m.mu held
+func (m *MiniRedis) run(name string, args []string) miniReply {
+	switch name {
+	case "PING":
+		if len(args) > 0 {
+			return bulkReply(args[0])
+		}
+		return simpleReply("PONG")
+	case "SELECT", "CLIENT", "AUTH":
+		return okReply
+	case "GET":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		if value, found := m.lookup(args[0]); found {
+			return bulkReply(value.data)
+		}
+		return nullReply{}
+	case "SET":
+		return m.set(args)
+	case "SETEX":
+		if len(args) != 3 {
+			return wrongArgs(name)
+		}
+		ttl, err := parseExpiry(args[1], time.Second)
+		if err != nil {
+			return errorReply("ERR invalid expire time in 'setex' command")
+		}
+		m.store(args[0], miniValue{data: args[2], expires: m.clock.Now().Add(ttl)})
+		return okReply
+	case "GETEX":
+		return m.getex(args)
+	case "DEL", "UNLINK":
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		removed := 0
+		for _, key := range args {
+			if _, found := m.lookup(key); found {
+				m.remove(key)
+				m.notify("del", key)
+				removed++
+			}
+		}
+		return intReply(removed)
+	case "PUBLISH":
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		return intReply(m.publish(args[0], args[1]))
+	case "CONFIG":
+		return m.config(args)
+	case "EXISTS":
+		if len(args) == 0 {
+			return wrongArgs(name)
+		}
+		n := 0
+		for _, key := range args {
+			if _, found := m.lookup(key); found {
+				n++
+			}
+		}
+		return intReply(n)
+	case "KEYS":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		keys := m.keys(args[0])
+		replies := make(arrayReply, len(keys))
+		for i, key := range keys {
+			replies[i] = bulkReply(key)
+		}
+		return replies
+	case "DBSIZE":
+		return intReply(len(m.keys("*")))
+	case "TTL", "PTTL":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+		value, found := m.lookup(args[0])
+		switch {
+		case !found:
+			return intReply(-2)
+		case value.expires.IsZero():
+			return intReply(-1)
+		}
+		remaining := value.expires.Sub(m.clock.Now())
+		if name == "TTL" {
+			return intReply(int64((remaining + time.Second - 1) / time.Second))
+		}
+		return intReply(int64((remaining + time.Millisecond - 1) / time.Millisecond))
+	case "INCR", "DECR", "INCRBY", "DECRBY":
+		return m.incr(name, args)
+	case "FLUSHDB", "FLUSHALL":
+		for key := range m.data {
+			m.remove(key)
+		}
+		return okReply
+	case "EVAL", "EVALSHA":
+		return m.eval(name, args)
+	default:
+		return errorReply(fmt.Sprintf("ERR unknown command '%s'", strings.ToLower(name)))
+	}
+}
+
+// set implements SET key value [NX|XX] [EX seconds|PX milliseconds|KEEPTTL] [GET]
+func (m *MiniRedis) set(args []string) miniReply {
+	if len(args) < 2 {
+		return wrongArgs("SET")
+	}
+	key, data := args[0], args[1]
+
+	var nx, xx, keepTTL, get bool
+	var ttl time.Duration
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "GET":
+			get = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return errorReply("ERR syntax error")
+			}
+			unit := time.Second
+			if strings.EqualFold(args[i], "PX") {
+				unit = time.Millisecond
+			}
+			var err error
+			if ttl, err = parseExpiry(args[i+1], unit); err != nil {
+				return errorReply("ERR invalid expire time in 'set' command")
+			}
+			i++
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+	if nx && xx {
+		return errorReply("ERR syntax error")
+	}
+
+	existing, found := m.lookup(key)
+	var previous miniReply = nullReply{}
+	if get && found {
+		previous = bulkReply(existing.data)
+	}
+	if (nx && found) || (xx && !found) {
+		return previous
+	}
+
+	value := miniValue{data: data}
+	switch {
+	case ttl > 0:
+		value.expires = m.clock.Now().Add(ttl)
+	case keepTTL:
+		value.expires = existing.expires
+	}
+	m.store(key, value)
+
+	if get {
+		return previous
+	}
+	return okReply
+}
+
+// getex implements GETEX key [EX seconds|PX milliseconds|PERSIST]
+func (m *MiniRedis) getex(args []string) miniReply {
+	if len(args) == 0 {
+		return wrongArgs("GETEX")
+	}
+	value, found := m.lookup(args[0])
+	if !found {
+		return nullReply{}
+	}
+
+	switch {
+	case len(args) == 1:
+	case len(args) == 2 && strings.EqualFold(args[1], "PERSIST"):
+		value.expires = time.Time{}
+		m.data[args[0]] = value
+	case len(args) == 3 && (strings.EqualFold(args[1], "EX") || strings.EqualFold(args[1], "PX")):
+		unit := time.Second
+		if strings.EqualFold(args[1], "PX") {
+			unit = time.Millisecond
+		}
+		ttl, err := parseExpiry(args[2], unit)
+		if err != nil {
+			return errorReply("ERR invalid expire time in 'getex' command")
+		}
+		value.expires = m.clock.Now().Add(ttl)
+		m.data[args[0]] = value
+	default:
+		return errorReply("ERR syntax error")
+	}
+	return bulkReply(value.data)
+}
+
+// incr implements INCR, DECR, INCRBY and DECRBY
+func (m *MiniRedis) incr(name string, args []string) miniReply {
+	delta := int64(1)
+	switch name {
+	case "INCR", "DECR":
+		if len(args) != 1 {
+			return wrongArgs(name)
+		}
+	default:
+		if len(args) != 2 {
+			return wrongArgs(name)
+		}
+		var err error
+		if delta, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return errorReply("ERR value is not an integer or out of range")
+		}
+	}
+	if name == "DECR" || name == "DECRBY" {
+		if delta == math.MinInt64 {
+			return errorReply("ERR decrement would overflow")
+		}
+		delta = -delta
+	}
+
+	value, _ := m.lookup(args[0])
+	current := int64(0)
+	if value.data != "" {
+		var err error
+		if current, err = strconv.ParseInt(value.data, 10, 64); err != nil {
+			return errorReply("ERR value is not an integer or out of range")
+		}
+	}
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return errorReply("ERR increment or decrement would overflow")
+	}
+
+	result := current + delta
+	value.data = strconv.FormatInt(result, 10)
+	m.store(args[0], value)
+	return intReply(result)
+}
+
+// lookup returns the live value at key, expiring it if its time has come
+func (m *MiniRedis) lookup(key string) (miniValue, bool) {
+	value, found := m.data[key]
+	if !found {
+		return miniValue{}, false
+	}
+	if !value.expires.IsZero() && !m.clock.Now().Before(value.expires) {
+		m.remove(key)
+		m.notify("expired", key)
+		return miniValue{}, false
+	}
+	return value, true
+}
+
+// store writes value at key
+func (m *MiniRedis) store(key string, value miniValue) {
+	m.data[key] = value
+	m.touch(key)
+}
+
+// remove deletes key
+func (m *MiniRedis) remove(key string) {
+	delete(m.data, key)
+	m.touch(key)
+}
+
+// touch marks key as changed for transactions watching it
+func (m *MiniRedis) touch(key string) {
+	m.version++
+	m.versions[key] = m.version
+}
+
+// keys returns the sorted live keys matching the glob pattern
+func (m *MiniRedis) keys(pattern string) []string {
+	var keys []string
+	for key := range m.data {
+		if _, found := m.lookup(key); found && matchGlob(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// parseExpiry parses a positive expire time in unit
+func parseExpiry(s string, unit time.Duration) (time.Duration, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, errors.New("invalid expire time")
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// matchGlob reports whether s matches a Redis glob pattern supporting *, ?,
+// [...] classes (with ^ negation and a-z ranges) and \ escapes
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if pattern == "" {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if s == "" {
+				return false
+			}
+		case '[':
+			if s == "" {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				return false
+			}
+			if !matchClass(pattern[1:end+1], s[0]) {
+				return false
+			}
+			pattern = pattern[end+2:]
+			s = s[1:]
+			continue
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if s == "" || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return s == ""
+}
+
+// matchClass reports whether c is in the character class body of a [...]
+// pattern
+func matchClass(class string, c byte) bool {
+	negate := strings.HasPrefix(class, "^")
+	if negate {
+		class = class[1:]
+	}
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] <= c && c <= class[i+2] {
+				return !negate
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			return !negate
+		}
+	}
+	return negate
+}
+
+// readCommand reads one command, as an array of bulk strings or an inline
+// command
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid multibulk length %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(header, "$") {
+			return nil, fmt.Errorf("expected bulk string, got %q", header)
+		}
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid bulk length %q", header)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// readLine reads a CRLF-terminated line without the terminator
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// miniReply is a reply in the Redis protocol (RESP2)
+type miniReply interface {
+	write(w *bufio.Writer)
+}
+
+type (
+	simpleReply    string
+	errorReply     string
+	intReply       int64
+	bulkReply      string
+	nullReply      struct{}
+	arrayReply     []miniReply
+	nullArrayReply struct{}
+
+	// multiReply is several replies sent for one command, as SUBSCRIBE does
+	multiReply []miniReply
+)
+
+var okReply = simpleReply("OK")
+
+// wrongArgs is the error for a command called with the wrong arity
+func wrongArgs(name string) miniReply {
+	return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+}
+
+func (r simpleReply) write(w *bufio.Writer) { fmt.Fprintf(w, "+%s\r\n", string(r)) }
+func (r errorReply) write(w *bufio.Writer)  { fmt.Fprintf(w, "-%s\r\n", string(r)) }
+func (r intReply) write(w *bufio.Writer)    { fmt.Fprintf(w, ":%d\r\n", int64(r)) }
+func (r bulkReply) write(w *bufio.Writer)   { fmt.Fprintf(w, "$%d\r\n%s\r\n", len(r), string(r)) }
+func (nullReply) write(w *bufio.Writer)     { _, _ = w.WriteString("$-1\r\n") }
+func (nullArrayReply) write(w *bufio.Writer) {
+	_, _ = w.WriteString("*-1\r\n")
+}
+
+func (r arrayReply) write(w *bufio.Writer) {
+	fmt.Fprintf(w, "*%d\r\n", len(r))
+	for _, reply := range r {
+		reply.write(w)
+	}
+}
+
+func (r multiReply) write(w *bufio.Writer) {
+	for _, reply := range r {
+		reply.write(w)
+	}
+}
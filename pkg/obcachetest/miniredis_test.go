@@ -0,0 +1,360 @@
+package obcachetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+func newMiniRedisCache(t *testing.T, server *MiniRedis, clock obcache.Clock) *obcache.Cache {
+	t.Helper()
+	cache, err := obcache.New(obcache.NewRedisConfig(server.Addr()).WithClock(clock))
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cache.Close()
+	})
+	return cache
+}
+
+func TestMiniRedisBacksRedisStore(t *testing.T) {
+	server := NewMiniRedis(t)
+	cache := newMiniRedisCache(t, server, nil)
+
+	if err := cache.Set("a", "value", time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if value, found := cache.Get("a"); !found || value != "value" {
+		t.Fatalf("Expected value from MiniRedis, got %v (found=%v)", value, found)
+	}
+	if _, found := server.Get("obcache:a"); !found {
+		t.Fatalf("Expected entry under the key prefix, got keys %v", server.Keys())
+	}
+	if ttl := server.TTL("obcache:a"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("Expected the entry TTL to reach the server, got %v", ttl)
+	}
+
+	if added, err := cache.SetNX("a", "other", time.Minute); err != nil || added {
+		t.Fatalf("Expected SetNX on an existing key to do nothing, got %v (err=%v)", added, err)
+	}
+	if err := cache.Update("a", func(old any) (any, error) {
+		return old.(string) + "!", nil
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if value, _ := cache.Get("a"); value != "value!" {
+		t.Fatalf("Expected updated value, got %v", value)
+	}
+
+	if n, err := cache.IncrBy("counter", 5, time.Minute); err != nil || n != 5 {
+		t.Fatalf("Expected counter 5, got %d (err=%v)", n, err)
+	}
+	if n, err := cache.Incr("counter", time.Minute); err != nil || n != 6 {
+		t.Fatalf("Expected counter 6, got %d (err=%v)", n, err)
+	}
+	if _, err := cache.IncrBy("a", 1, time.Minute); err == nil {
+		t.Fatal("Expected incrementing a non-integer to fail")
+	}
+
+	if len(cache.Keys()) != 2 {
+		t.Fatalf("Expected 2 keys, got %v", cache.Keys())
+	}
+	if err := cache.Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if cache.Has("a") {
+		t.Fatal("Expected deleted key to be gone")
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Fatalf("Expected Clear to empty the server, got %v", keys)
+	}
+}
+
+func TestMiniRedisCloseKeepsSharedKeys(t *testing.T) {
+	server := NewMiniRedis(t)
+	first := newMiniRedisCache(t, server, nil)
+	second := newMiniRedisCache(t, server, nil)
+
+	if err := first.Set("a", "value", time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Shutting down one process must not wipe the cache for the others
+	if value, found := second.Get("a"); !found || value != "value" {
+		t.Fatalf("Expected the key to survive Close, got %v (found=%v)", value, found)
+	}
+}
+
+func TestMiniRedisReadsDoNotClobberUpdates(t *testing.T) {
+	server := NewMiniRedis(t)
+	reader := newMiniRedisCache(t, server, nil)
+	writer := newMiniRedisCache(t, server, nil)
+
+	// Values round-trip through JSON, so numbers come back as float64
+	if err := writer.Set("n", 0.0, time.Minute); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Another process keeps reading the key while this one updates it
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				reader.Get("n")
+			}
+		}
+	}()
+
+	const updates = 100
+	for i := 0; i < updates; i++ {
+		if err := writer.Update("n", func(old any) (any, error) {
+			return old.(float64) + 1, nil
+		}); err != nil {
+			t.Fatalf("Update %d failed: %v", i, err)
+		}
+	}
+	close(stop)
+	<-done
+
+	if value, _ := writer.Get("n"); value != float64(updates) {
+		t.Fatalf("Expected %d after concurrent reads, got %v", updates, value)
+	}
+}
+
+func TestMiniRedisAtomicWritesOnSlidingEntries(t *testing.T) {
+	server := NewMiniRedis(t)
+	cache := newMiniRedisCache(t, server, nil)
+
+	if err := cache.Set("session", "a", 200*time.Millisecond, obcache.WithSliding(0)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// Reading slides the entry past the expiry stored when it was written
+	time.Sleep(150 * time.Millisecond)
+	_, version, found := cache.GetWithVersion("session")
+	if !found {
+		t.Fatal("Expected sliding entry to be live")
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if swapped, err := cache.CompareAndSwap("session", version, "b"); err != nil || !swapped {
+		t.Fatalf("Expected CompareAndSwap on a slid entry to succeed, got %v, %v", swapped, err)
+	}
+	if err := cache.Update("session", func(old any) (any, error) {
+		if old != "b" {
+			return nil, fmt.Errorf("expected current value b, got %v", old)
+		}
+		return "c", nil
+	}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	if value, found := cache.Get("session"); !found || value != "c" {
+		t.Fatalf("Expected updated value c, got %v (found=%v)", value, found)
+	}
+	if ttl := server.TTL("obcache:session"); ttl <= 0 || ttl > 200*time.Millisecond {
+		t.Fatalf("Expected the idle timeout to be kept, got TTL %v", ttl)
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if _, found := cache.Get("session"); found {
+		t.Fatal("Expected updated entry to keep sliding expiration and expire once idle")
+	}
+}
+
+func TestMiniRedisDistributedSingleflight(t *testing.T) {
+	server := NewMiniRedis(t)
+	caches := []*obcache.Cache{
+		newMiniRedisCache(t, server, nil),
+		newMiniRedisCache(t, server, nil),
+	}
+
+	var loads int64
+	loader := func(context.Context) (any, error) {
+		atomic.AddInt64(&loads, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "report", nil
+	}
+
+	// Processes missing the same key concurrently share one load through
+	// the lease scripts
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		cache := caches[i%len(caches)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrLoad(context.Background(), "report", loader,
+				obcache.WithDistributedSingleflight(time.Second),
+				obcache.WithLeasePollInterval(5*time.Millisecond))
+			if err != nil || value != "report" {
+				t.Errorf("Expected the shared value, got %v (err=%v)", value, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt64(&loads); n != 1 {
+		t.Fatalf("Expected one load across processes, got %d", n)
+	}
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "obcache:report" {
+		t.Fatalf("Expected the lease to be released, got keys %v", keys)
+	}
+}
+
+func TestMiniRedisExpiresByClock(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	server := NewMiniRedis(t)
+	server.SetClock(clock)
+	cache := newMiniRedisCache(t, server, clock)
+
+	_ = cache.Set("a", 1, time.Minute)
+
+	clock.Advance(30 * time.Second)
+	if _, found := cache.Get("a"); !found {
+		t.Fatal("Expected entry before its TTL has passed")
+	}
+
+	clock.Advance(31 * time.Second)
+	if _, found := server.Get("obcache:a"); found {
+		t.Fatal("Expected MiniRedis to expire the key by the fake clock")
+	}
+	if _, found := cache.Get("a"); found {
+		t.Fatal("Expected entry to have expired")
+	}
+}
+
+func TestMiniRedisPubSub(t *testing.T) {
+	server := NewMiniRedis(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	ctx := context.Background()
+	pubsub := client.PSubscribe(ctx, "news.*")
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		t.Fatalf("PSubscribe failed: %v", err)
+	}
+
+	if n, err := client.Publish(ctx, "news.sport", "goal").Result(); err != nil || n != 1 {
+		t.Fatalf("Expected one receiver, got %d (err=%v)", n, err)
+	}
+	if n, _ := client.Publish(ctx, "weather", "rain").Result(); n != 0 {
+		t.Fatalf("Expected no receivers for an unmatched channel, got %d", n)
+	}
+
+	select {
+	case msg := <-pubsub.Channel():
+		if msg.Channel != "news.sport" || msg.Pattern != "news.*" || msg.Payload != "goal" {
+			t.Fatalf("Unexpected message %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the published message")
+	}
+}
+
+func TestMiniRedisKeyspaceNotifications(t *testing.T) {
+	clock := NewFakeClock(time.Time{})
+	server := NewMiniRedis(t)
+	server.SetClock(clock)
+
+	// Another client of the shared Redis
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	if err := client.ConfigSet(ctx, "notify-keyspace-events", "Exeg").Err(); err != nil {
+		t.Fatalf("CONFIG SET failed: %v", err)
+	}
+
+	config := obcache.NewRedisConfig(server.Addr()).WithClock(clock)
+	config.Redis.KeyspaceNotifications = true
+	cache, recorder := NewRecordingCache(t, config)
+
+	_ = cache.Set("expiring", 1, time.Minute)
+	_ = cache.Set("evicted", 2, time.Hour)
+	_ = cache.Set("own", 3, time.Hour)
+	_ = cache.Set("external", 4, time.Hour)
+
+	if err := cache.Delete("own"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := client.Del(ctx, "obcache:external").Err(); err != nil {
+		t.Fatalf("DEL failed: %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if n := server.ExpireKeys(); n != 1 {
+		t.Fatalf("Expected one key to expire, got %d", n)
+	}
+	server.Evict("obcache:evicted")
+
+	// Notifications arrive in order, so once the eviction is reported the
+	// others have been too
+	waitForCondition(t, 2*time.Second, func() bool { return recorder.Count(Evict, "evicted") > 0 })
+
+	recorder.AssertInvalidated(t, "external")
+	recorder.AssertCount(t, Invalidate, "own", 1)
+	recorder.AssertEvictedFor(t, "expiring", obcache.EvictReasonTTL)
+	recorder.AssertEvictedFor(t, "evicted", obcache.EvictReasonCapacity)
+
+	stats := cache.Stats()
+	if stats.Invalidations() != 2 {
+		t.Fatalf("Expected 2 invalidations, got %d", stats.Invalidations())
+	}
+	if n := stats.EvictionsFor(obcache.EvictReasonDeletedExternally); n != 1 {
+		t.Fatalf("Expected 1 external delete, got %d", n)
+	}
+}
+
+func waitForCondition(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("Condition not met before timeout")
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "anything", true},
+		{"obcache:*", "obcache:key", true},
+		{"obcache:*", "other:key", false},
+		{"k?y", "key", true},
+		{"k?y", "ky", false},
+		{"k[ae]y", "kay", true},
+		{"k[^ae]y", "kay", false},
+		{"k[a-f]y", "key", true},
+		{`a\*`, "a*", true},
+		{`a\*`, "ab", false},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.s); got != tt.want {
+			t.Fatalf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
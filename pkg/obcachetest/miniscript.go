@@ -0,0 +1,121 @@
+package obcachetest
+
+import (
+	"crypto/sha1" //nolint:gosec // Redis identifies scripts by SHA1
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	redisstore "github.com/vnykmshr/obcache-go/internal/store/redis"
+)
+
+// miniScript runs a Lua script of the Redis store in Go with m.mu held
+type miniScript func(m *MiniRedis, keys, args []string) miniReply
+
+// miniScripts holds the emulated scripts, keyed by SHA1
+var miniScripts = func() map[string]miniScript {
+	byName := map[string]miniScript{
+		"acquireLease": (*MiniRedis).acquireLease,
+		"releaseLease": (*MiniRedis).releaseLease,
+		"fencedSet":    (*MiniRedis).fencedSet,
+	}
+	scripts := make(map[string]miniScript, len(byName))
+	for name, sha := range redisstore.ScriptHashes() {
+		if script, ok := byName[name]; ok {
+			scripts[sha] = script
+		}
+	}
+	return scripts
+}()
+
+// eval implements EVAL script numkeys [key ...] [arg ...] and EVALSHA for
+// the scripts MiniRedis emulates
+func (m *MiniRedis) eval(name string, args []string) miniReply {
+	if len(args) < 2 {
+		return wrongArgs(name)
+	}
+
+	sha := strings.ToLower(args[0])
+	if name == "EVAL" {
+		sum := sha1.Sum([]byte(args[0])) //nolint:gosec // Redis identifies scripts by SHA1
+		sha = hex.EncodeToString(sum[:])
+	}
+	script, ok := miniScripts[sha]
+	if !ok {
+		if name == "EVALSHA" {
+			return errorReply("NOSCRIPT No matching script. Please use EVAL.")
+		}
+		return errorReply("ERR MiniRedis only runs the scripts of the Redis store")
+	}
+
+	numKeys, err := strconv.Atoi(args[1])
+	if err != nil || numKeys < 0 {
+		return errorReply("ERR Number of keys can't be negative")
+	}
+	if numKeys > len(args)-2 {
+		return errorReply("ERR Number of keys can't be greater than number of args")
+	}
+	return script(m, args[2:2+numKeys], args[2+numKeys:])
+}
+
+// acquireLease sets KEYS[1] to a new fencing token for ARGV[1] milliseconds
+// unless it exists, returning the token or nil
+func (m *MiniRedis) acquireLease(keys, args []string) miniReply {
+	if len(keys) != 1 || len(args) != 1 {
+		return errorReply("ERR wrong number of arguments for the lease script")
+	}
+	ttl, err := parseExpiry(args[0], time.Millisecond)
+	if err != nil {
+		return errorReply("ERR invalid expire time in 'set' command")
+	}
+	if _, found := m.lookup(keys[0]); found {
+		return nullReply{}
+	}
+
+	// Like the script, use the server time in microseconds as the token
+	now := m.clock.Now()
+	token := max(now.UnixMicro(), m.lastToken+1)
+	m.lastToken = token
+
+	data := strconv.FormatInt(token, 10)
+	m.store(keys[0], miniValue{data: data, expires: now.Add(ttl)})
+	return bulkReply(data)
+}
+
+// releaseLease deletes KEYS[1] if it holds the token ARGV[1], returning the
+// number of keys deleted
+func (m *MiniRedis) releaseLease(keys, args []string) miniReply {
+	if len(keys) != 1 || len(args) != 1 {
+		return errorReply("ERR wrong number of arguments for the lease script")
+	}
+	if value, found := m.lookup(keys[0]); !found || value.data != args[0] {
+		return intReply(0)
+	}
+	m.remove(keys[0])
+	m.notify("del", keys[0])
+	return intReply(1)
+}
+
+// fencedSet sets KEYS[1] to ARGV[2], expiring after ARGV[3] milliseconds if
+// positive, only while the lease KEYS[2] holds the token ARGV[1]. It returns
+// 1 if the value was written and 0 otherwise.
+func (m *MiniRedis) fencedSet(keys, args []string) miniReply {
+	if len(keys) != 2 || len(args) != 3 {
+		return errorReply("ERR wrong number of arguments for the fenced set script")
+	}
+	if lease, found := m.lookup(keys[1]); !found || lease.data != args[0] {
+		return intReply(0)
+	}
+	ttl, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return errorReply("ERR value is not an integer or out of range")
+	}
+
+	value := miniValue{data: args[1]}
+	if ttl > 0 {
+		value.expires = m.clock.Now().Add(time.Duration(ttl) * time.Millisecond)
+	}
+	m.store(keys[0], value)
+	return intReply(1)
+}
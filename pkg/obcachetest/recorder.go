@@ -0,0 +1,258 @@
+package obcachetest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+// EventKind identifies what a cache reported through its hooks
+type EventKind int
+
+const (
+	// Hit is a lookup that found the key
+	Hit EventKind = iota
+
+	// Miss is a lookup that did not find the key
+	Miss
+
+	// Evict is an entry removed to make room or because it expired
+	Evict
+
+	// Invalidate is an entry removed with Delete or Invalidate
+	Invalidate
+
+	// WriteError is a write the configured Writer failed to apply
+	WriteError
+
+	// Error is a store operation that failed
+	Error
+)
+
+// String returns the string representation of EventKind
+func (k EventKind) String() string {
+	switch k {
+	case Hit:
+		return "hit"
+	case Miss:
+		return "miss"
+	case Evict:
+		return "evict"
+	case Invalidate:
+		return "invalidate"
+	case WriteError:
+		return "write error"
+	case Error:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a single hook invocation recorded by a Recorder
+type Event struct {
+	Kind EventKind
+	Key  string
+
+	// Reason is why the entry was evicted (Evict events only)
+	Reason obcache.EvictReason
+
+	// Err is the error (WriteError and Error events only)
+	Err error
+}
+
+// Recorder records the hits, misses, evictions, invalidations and errors a
+// cache reports through its hooks, and asserts on them per key.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewRecorder returns an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// NewRecordingCache creates a cache from config (NewDefaultConfig if nil)
+// whose hooks also feed a new Recorder. The cache is closed when the test
+// ends.
+func NewRecordingCache(t testing.TB, config *obcache.Config) (*obcache.Cache, *Recorder) {
+	t.Helper()
+	if config == nil {
+		config = obcache.NewDefaultConfig()
+	}
+	if config.Hooks == nil {
+		config.Hooks = &obcache.Hooks{}
+	}
+
+	recorder := NewRecorder()
+	recorder.Attach(config.Hooks)
+
+	cache, err := obcache.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = cache.Close() //nolint:errcheck // Best-effort cleanup
+	})
+	return cache, recorder
+}
+
+// Hooks returns a new hook set that records into r
+func (r *Recorder) Hooks() *obcache.Hooks {
+	hooks := &obcache.Hooks{}
+	r.Attach(hooks)
+	return hooks
+}
+
+// Attach adds hooks recording into r to an existing hook set
+func (r *Recorder) Attach(hooks *obcache.Hooks) {
+	hooks.AddOnHit(func(key string, _ any) {
+		r.record(Event{Kind: Hit, Key: key})
+	})
+	hooks.AddOnMiss(func(key string) {
+		r.record(Event{Kind: Miss, Key: key})
+	})
+	hooks.AddOnEvict(func(key string, _ any, reason obcache.EvictReason) {
+		r.record(Event{Kind: Evict, Key: key, Reason: reason})
+	})
+	hooks.AddOnInvalidate(func(key string) {
+		r.record(Event{Kind: Invalidate, Key: key})
+	})
+	hooks.AddOnWriteError(func(key string, err error) {
+		r.record(Event{Kind: WriteError, Key: key, Err: err})
+	})
+	hooks.AddOnError(func(key string, err error) {
+		r.record(Event{Kind: Error, Key: key, Err: err})
+	})
+}
+
+// record appends an event
+func (r *Recorder) record(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns the recorded events in the order they happened
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// EventsFor returns the events recorded for key
+func (r *Recorder) EventsFor(key string) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []Event
+	for _, event := range r.events {
+		if event.Key == key {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Count returns how many events of kind were recorded for key
+func (r *Recorder) Count(kind EventKind, key string) int {
+	n := 0
+	for _, event := range r.EventsFor(key) {
+		if event.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset discards the recorded events
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// AssertHit fails the test unless key was looked up and found at least once
+func (r *Recorder) AssertHit(t testing.TB, key string) {
+	t.Helper()
+	r.assertSeen(t, Hit, key)
+}
+
+// AssertMiss fails the test unless key was looked up and missed at least once
+func (r *Recorder) AssertMiss(t testing.TB, key string) {
+	t.Helper()
+	r.assertSeen(t, Miss, key)
+}
+
+// AssertEvicted fails the test unless key was evicted at least once
+func (r *Recorder) AssertEvicted(t testing.TB, key string) {
+	t.Helper()
+	r.assertSeen(t, Evict, key)
+}
+
+// AssertEvictedFor fails the test unless key was evicted for reason
+func (r *Recorder) AssertEvictedFor(t testing.TB, key string, reason obcache.EvictReason) {
+	t.Helper()
+	for _, event := range r.EventsFor(key) {
+		if event.Kind == Evict && event.Reason == reason {
+			return
+		}
+	}
+	t.Fatalf("Expected %q to be evicted for %s, got events %s", key, reason, r.describe(key))
+}
+
+// AssertNotEvicted fails the test if key was evicted
+func (r *Recorder) AssertNotEvicted(t testing.TB, key string) {
+	t.Helper()
+	r.AssertCount(t, Evict, key, 0)
+}
+
+// AssertInvalidated fails the test unless key was invalidated at least once
+func (r *Recorder) AssertInvalidated(t testing.TB, key string) {
+	t.Helper()
+	r.assertSeen(t, Invalidate, key)
+}
+
+// AssertError fails the test unless an operation on key failed with an
+// error matching target (see errors.Is)
+func (r *Recorder) AssertError(t testing.TB, key string, target error) {
+	t.Helper()
+	for _, event := range r.EventsFor(key) {
+		if event.Kind == Error && errors.Is(event.Err, target) {
+			return
+		}
+	}
+	t.Fatalf("Expected an error matching %v for %q, got events %s", target, key, r.describe(key))
+}
+
+// AssertCount fails the test unless exactly n events of kind were recorded
+// for key
+func (r *Recorder) AssertCount(t testing.TB, kind EventKind, key string, n int) {
+	t.Helper()
+	if got := r.Count(kind, key); got != n {
+		t.Fatalf("Expected %d %s events for %q, got %d: %s", n, kind, key, got, r.describe(key))
+	}
+}
+
+// assertSeen fails the test unless an event of kind was recorded for key
+func (r *Recorder) assertSeen(t testing.TB, kind EventKind, key string) {
+	t.Helper()
+	if r.Count(kind, key) == 0 {
+		t.Fatalf("Expected a %s event for %q, got events %s", kind, key, r.describe(key))
+	}
+}
+
+// describe lists the kinds of the events recorded for key
+func (r *Recorder) describe(key string) string {
+	events := r.EventsFor(key)
+	kinds := make([]string, len(events))
+	for i, event := range events {
+		kinds[i] = event.Kind.String()
+	}
+	return fmt.Sprint(kinds)
+}
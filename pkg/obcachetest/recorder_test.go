@@ -0,0 +1,58 @@
+package obcachetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vnykmshr/obcache-go/pkg/obcache"
+)
+
+func TestRecorder(t *testing.T) {
+	cache, recorder := NewRecordingCache(t, obcache.NewDefaultConfig().WithMaxEntries(2))
+
+	_ = cache.Set("a", 1, time.Minute)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("missing")
+
+	recorder.AssertHit(t, "a")
+	recorder.AssertCount(t, Hit, "a", 2)
+	recorder.AssertMiss(t, "missing")
+	recorder.AssertCount(t, Miss, "a", 0)
+
+	// Overflowing the capacity evicts the least recently used key
+	_ = cache.Set("b", 2, time.Minute)
+	_ = cache.Set("c", 3, time.Minute)
+	recorder.AssertEvictedFor(t, "a", obcache.EvictReasonCapacity)
+	recorder.AssertNotEvicted(t, "b")
+
+	_ = cache.Delete("b")
+	recorder.AssertInvalidated(t, "b")
+	recorder.AssertNotEvicted(t, "b")
+
+	if events := recorder.EventsFor("b"); len(events) != 1 || events[0].Kind != Invalidate {
+		t.Fatalf("Expected a single invalidation for b, got %+v", events)
+	}
+
+	recorder.Reset()
+	if events := recorder.Events(); len(events) != 0 {
+		t.Fatalf("Expected no events after Reset, got %+v", events)
+	}
+}
+
+func TestRecorderAttachKeepsExistingHooks(t *testing.T) {
+	hits := 0
+	hooks := &obcache.Hooks{}
+	hooks.AddOnHit(func(string, any) {
+		hits++
+	})
+	cache, recorder := NewRecordingCache(t, obcache.NewDefaultConfig().WithHooks(hooks))
+
+	_ = cache.Set("a", 1, time.Minute)
+	cache.Get("a")
+
+	recorder.AssertHit(t, "a")
+	if hits != 1 {
+		t.Fatalf("Expected the existing hook to keep running, got %d calls", hits)
+	}
+}